@@ -3,9 +3,9 @@ package app
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
-	"reflect"
 	"sync"
 	"syscall"
 	"time"
@@ -51,15 +51,22 @@ type (
 		Config C
 		self   Application[C]
 		*Runtime
-		ready       chan void
-		readyWg     sync.WaitGroup
-		stopTimeout time.Duration
+		signals       map[SignalGroup]Signals
+		signalHandler func(Signal)
+		ready         chan void
+		readyWg       sync.WaitGroup
+		lifecycle     Lifecycle
+		stopTimeout   time.Duration
 	}
 
+	// Service is a long running application component.
+	// Run must call ready.Done() exactly once when service is ready
+	// to serve (eg: listener is bound), App.Ready is closed after
+	// all enabled services signaled readiness.
 	Service interface {
 		Name() string
 		Enabled() bool
-		Run(context.Context, *sync.WaitGroup) error
+		Run(ctx context.Context, ready *sync.WaitGroup) error
 		Signal(os.Signal)
 		Close() error
 	}
@@ -70,17 +77,17 @@ const (
 	DefaultStopTimeout = 10 * time.Second
 )
 
+var (
+	ErrStopTimeout = errors.New("timed out waiting all components to stop, forcing exit")
+)
+
 func (a *App[C]) Configure(path string) (C, error) {
 	log.Ctx(a.Runtime.Super).
 		Info().
 		Str("config", path).
 		Msg("loading config")
 
-	var c C
-	typ := reflect.TypeOf((*C)(nil)).Elem()
-	if typ.Kind() == reflect.Pointer {
-		c = reflect.New(typ.Elem()).Interface().(C)
-	}
+	c := newConfig[C]()
 	err := c.FromFile(path)
 	if err != nil {
 		return c, errors.Wrapf(err, "failed to load config from %q", path)
@@ -89,13 +96,27 @@ func (a *App[C]) Configure(path string) (C, error) {
 	return c, nil
 }
 
-func (*App[C]) Signals(sgids ...SignalGroup) Signals {
+// ConfigureFromReader is like Configure, but loads config from r, see ConfigFromReader.
+func (a *App[C]) ConfigureFromReader(r io.Reader, name string) (C, error) {
+	c, err := ConfigFromReader[C](r, name)
+	if err != nil {
+		return c, err
+	}
+	a.Config = c
+	return c, nil
+}
+
+func (a *App[C]) Signals(sgids ...SignalGroup) Signals {
 	if len(sgids) == 0 {
 		sgids = SignalGroups
 	}
 
 	var sigs Signals
 	for _, sgid := range sgids {
+		if groupSigs, ok := a.signals[sgid]; ok {
+			sigs = append(sigs, groupSigs...)
+			continue
+		}
 		switch sgid {
 		case SignalGroupStop:
 			sigs = append(sigs, syscall.SIGINT, syscall.SIGTERM)
@@ -106,6 +127,19 @@ func (*App[C]) Signals(sgids ...SignalGroup) Signals {
 	return sigs
 }
 
+// SetSignals overrides signals which belong to the group.
+func (a *App[C]) SetSignals(sgid SignalGroup, sigs ...Signal) {
+	if a.signals == nil {
+		a.signals = map[SignalGroup]Signals{}
+	}
+	a.signals[sgid] = sigs
+}
+
+// SetSignalHandler sets callback for signals of SignalGroupCustom.
+func (a *App[C]) SetSignalHandler(fn func(Signal)) {
+	a.signalHandler = fn
+}
+
 func (*App[C]) Flags() Flags {
 	return Flags{
 		&PathFlag{
@@ -146,6 +180,11 @@ func (a *App[C]) Ready() <-chan void {
 	return a.ready
 }
 
+// Lifecycle returns broadcaster of application lifecycle events.
+func (a *App[C]) Lifecycle() *Lifecycle {
+	return &a.lifecycle
+}
+
 func (a *App[C]) Init(r *Runtime) {
 	r.Cli.Flags = a.self.Flags()
 	r.Cli.Commands = a.self.Commands()
@@ -173,22 +212,13 @@ watchdog:
 			log.Error().
 				Err(err).
 				Msg("supervisor has been shutdown, exiting")
+			a.lifecycle.emit(LifecycleStopped)
 			os.Exit(1)
 		case sig := <-sigCh:
-			log.Info().
-				Str("signal", sig.String()).
-				Msg("received signal")
-			switch sgids[sig] {
-			case SignalGroupNotify:
-				a.self.Notify(sig)
-			case SignalGroupStop:
+			if a.handleSignal(sig, sgids) {
 				log.Warn().Msg("shutting down supervisor")
 				a.Runtime.Super.Cancel(nil)
 				break watchdog
-			default:
-				log.Warn().
-					Str("signal", sig.String()).
-					Msg("unsupported signal, ignoring")
 			}
 		}
 	}
@@ -196,27 +226,77 @@ watchdog:
 		Str("timeout", a.stopTimeout.String()).
 		Msg("shutting down...")
 
-wait:
-	for {
-		select {
-		case err := <-exit:
-			log.Error().
-				Err(err).
-				Msg("supervisor got error, exiting")
-			os.Exit(1)
-		case sig := <-sigCh:
-			log.Warn().
-				Msgf("received signal: %v, forcing exit", sig)
-			os.Exit(1)
-		case <-time.After(a.stopTimeout):
-			log.Fatal().
-				Err(errors.Errorf("timed out waiting all components to stop, forcing exit")).
-				Msg("exiting")
-			break wait
+	err := a.stop(exit, sigCh)
+	if errors.Is(err, ErrStopTimeout) {
+		log.Fatal().
+			Err(err).
+			Msg("exiting")
+	}
+	os.Exit(1)
+}
+
+// handleSignal routes signal to the action of its group.
+// Returns true if signal requests application to stop.
+func (a *App[C]) handleSignal(sig Signal, sgids SignalGroupIndex) bool {
+	log.Info().
+		Str("signal", sig.String()).
+		Msg("received signal")
+	switch sgids[sig] {
+	case SignalGroupNotify:
+		a.self.Notify(sig)
+	case SignalGroupStop:
+		return true
+	case SignalGroupCustom:
+		if a.signalHandler != nil {
+			a.signalHandler(sig)
+			return false
 		}
+		fallthrough
+	default:
+		log.Warn().
+			Str("signal", sig.String()).
+			Msg("unsupported signal, ignoring")
 	}
+	return false
+}
 
-	log.Warn().Msg("exiting")
+// stop waits for components to stop emitting stopping and stopped lifecycle events.
+func (a *App[C]) stop(exit <-chan error, sigCh <-chan os.Signal) error {
+	a.lifecycle.emit(LifecycleStopping)
+	defer a.lifecycle.emit(LifecycleStopped)
+	return a.awaitStop(exit, sigCh)
+}
+
+// awaitStop waits for supervisor to stop no longer than stopTimeout.
+// Returns ErrStopTimeout if components did not stop in time.
+func (a *App[C]) awaitStop(exit <-chan error, sigCh <-chan os.Signal) error {
+	select {
+	case err := <-exit:
+		log.Error().
+			Err(err).
+			Msg("supervisor got error, exiting")
+		return err
+	case sig := <-sigCh:
+		log.Warn().
+			Msgf("received signal: %v, forcing exit", sig)
+		return errors.Errorf("received signal: %v", sig)
+	case <-time.After(a.stopTimeout):
+		return ErrStopTimeout
+	}
+}
+
+// StopTimeout returns duration watchdog waits for components to stop before forcing exit.
+func (a *App[C]) StopTimeout() time.Duration {
+	return a.stopTimeout
+}
+
+// SetStopTimeout sets duration watchdog waits for components to stop before forcing exit.
+func (a *App[C]) SetStopTimeout(timeout time.Duration) error {
+	if timeout <= 0 {
+		return errors.Errorf("stop timeout must be positive, got %s", timeout)
+	}
+	a.stopTimeout = timeout
+	return nil
 }
 
 func (a *App[C]) PreRun(ctx *cli.Context) error {
@@ -271,12 +351,10 @@ func (a *App[C]) runService(srv Service) error {
 	return srv.Run(ctx, &a.readyWg)
 }
 
-func (a *App[C]) Run(ctx *cli.Context) error {
-	a.Super.Run(func(ctx context.Context) error {
-		a.Watcher.Run(ctx)
-		return nil
-	})
-
+// runServices starts enabled services, Ready channel is closed
+// when every started service signaled readiness.
+func (a *App[C]) runServices() {
+	a.lifecycle.emit(LifecycleStarted)
 	for _, srv := range a.self.Services() {
 		if !srv.Enabled() {
 			continue
@@ -289,16 +367,26 @@ func (a *App[C]) Run(ctx *cli.Context) error {
 		})
 	}
 
+	go func() {
+		a.readyWg.Wait()
+		a.lifecycle.emit(LifecycleReady)
+		close(a.ready)
+	}()
+}
+
+func (a *App[C]) Run(ctx *cli.Context) error {
+	a.Super.Run(func(ctx context.Context) error {
+		a.Watcher.Run(ctx)
+		return nil
+	})
+
+	a.runServices()
 	a.self.Watchdog(ctx)
 
 	return nil
 }
 
 func (a *App[C]) Exec(args []string) error {
-	go func() {
-		a.readyWg.Wait()
-		close(a.ready)
-	}()
 	return a.Runtime.Run(args)
 }
 
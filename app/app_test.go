@@ -0,0 +1,167 @@
+package app
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.tatikoma.dev/corpix/atlas/supervisor"
+)
+
+type (
+	testConfig struct{}
+
+	testApp struct {
+		*App[*testConfig]
+		services Services
+	}
+
+	testService struct {
+		run  func(ctx context.Context, ready *sync.WaitGroup) error
+		name string
+	}
+)
+
+func (*testConfig) FromFile(string) error { return nil }
+
+func (a *testApp) Services() Services { return a.services }
+
+func (s *testService) Name() string   { return s.name }
+func (*testService) Enabled() bool    { return true }
+func (*testService) Signal(os.Signal) {}
+func (*testService) Close() error     { return nil }
+func (s *testService) Run(ctx context.Context, ready *sync.WaitGroup) error {
+	return s.run(ctx, ready)
+}
+
+func newTestApp(t *testing.T, services ...Service) *testApp {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Runtime{Super: supervisor.New(ctx)}
+	a := &testApp{services: services}
+	a.App = New[*testConfig](r, a)
+	t.Cleanup(func() {
+		cancel()
+		_ = r.Super.Wait(context.Background())
+	})
+	return a
+}
+
+func TestAppReady(t *testing.T) {
+	delay := 100 * time.Millisecond
+	signal := make(chan void)
+	a := newTestApp(t,
+		&testService{
+			name: "instant",
+			run: func(ctx context.Context, ready *sync.WaitGroup) error {
+				ready.Done()
+				<-ctx.Done()
+				return nil
+			},
+		},
+		&testService{
+			name: "delayed",
+			run: func(ctx context.Context, ready *sync.WaitGroup) error {
+				<-signal
+				ready.Done()
+				<-ctx.Done()
+				return nil
+			},
+		},
+	)
+
+	a.runServices()
+
+	select {
+	case <-a.Ready():
+		t.Fatal("app is ready before delayed service signaled readiness")
+	case <-time.After(delay):
+	}
+
+	close(signal)
+	select {
+	case <-a.Ready():
+	case <-time.After(delay):
+		t.Fatal("app is not ready after all services signaled readiness")
+	}
+}
+
+func TestAppStopTimeout(t *testing.T) {
+	drain := 200 * time.Millisecond
+	stop := func(t *testing.T, timeout time.Duration) error {
+		a := newTestApp(t, &testService{
+			name: "slow",
+			run: func(ctx context.Context, ready *sync.WaitGroup) error {
+				ready.Done()
+				<-ctx.Done()
+				time.Sleep(drain)
+				return nil
+			},
+		})
+		require.NoError(t, a.SetStopTimeout(timeout))
+		a.runServices()
+		<-a.Ready()
+
+		exit := make(chan error, 1)
+		go func() {
+			exit <- a.Super.Wait(context.Background())
+		}()
+		a.Super.Cancel(nil)
+
+		return a.awaitStop(exit, nil)
+	}
+
+	t.Run("forces exit after timeout", func(t *testing.T) {
+		assert.ErrorIs(t, stop(t, drain/4), ErrStopTimeout)
+	})
+
+	t.Run("lets slow service finish", func(t *testing.T) {
+		assert.NotErrorIs(t, stop(t, drain*4), ErrStopTimeout)
+	})
+
+	t.Run("rejects non positive timeout", func(t *testing.T) {
+		a := newTestApp(t)
+		assert.Error(t, a.SetStopTimeout(0))
+		assert.Equal(t, DefaultStopTimeout, a.StopTimeout())
+	})
+}
+
+func TestAppSignals(t *testing.T) {
+	a := newTestApp(t)
+
+	received := make(chan Signal, 1)
+	a.SetSignals(SignalGroupCustom, syscall.SIGHUP)
+	a.SetSignalHandler(func(sig Signal) {
+		received <- sig
+	})
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, a.Signals()...)
+	defer signal.Stop(sigCh)
+
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+
+	var sig Signal
+	select {
+	case sig = <-sigCh:
+	case <-time.After(time.Second):
+		t.Fatal("signal was not delivered")
+	}
+	assert.False(t, a.handleSignal(sig, GroupSignals(a)), "custom signal should not stop application")
+
+	select {
+	case got := <-received:
+		assert.Equal(t, Signal(syscall.SIGHUP), got)
+	default:
+		t.Fatal("custom signal handler was not called")
+	}
+
+	assert.True(t, a.handleSignal(syscall.SIGTERM, GroupSignals(a)), "stop signal should stop application")
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"git.tatikoma.dev/corpix/atlas/errors"
+)
+
+// readerConfig may be implemented by Config to be loaded without filesystem,
+// configs which do not implement it are loaded through temporary file.
+type readerConfig interface {
+	FromReader(r io.Reader) error
+}
+
+func newConfig[C Config]() C {
+	var c C
+	typ := reflect.TypeOf((*C)(nil)).Elem()
+	if typ.Kind() == reflect.Pointer {
+		c = reflect.New(typ.Elem()).Interface().(C)
+	}
+	return c
+}
+
+// ConfigFromReader loads config of type C from r, name is a file name content originates from.
+// Configs implementing FromReader decode r directly, otherwise r is passed to FromFile
+// through temporary file which keeps extension of name, so format detection still works.
+func ConfigFromReader[C Config](r io.Reader, name string) (C, error) {
+	c := newConfig[C]()
+	if rc, ok := any(c).(readerConfig); ok {
+		err := rc.FromReader(r)
+		if err != nil {
+			return c, errors.Wrap(err, "failed to load config")
+		}
+		return c, nil
+	}
+
+	f, err := os.CreateTemp("", "config-*"+filepath.Ext(name))
+	if err != nil {
+		return c, errors.Wrap(err, "failed to create temporary config file")
+	}
+	defer os.Remove(f.Name())
+
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return c, errors.Wrap(err, "failed to write temporary config file")
+	}
+
+	err = c.FromFile(f.Name())
+	if err != nil {
+		return c, errors.Wrap(err, "failed to load config")
+	}
+	return c, nil
+}
+
+// ConfigFromBytes loads config of type C from buf, name is used as in ConfigFromReader.
+func ConfigFromBytes[C Config](buf []byte, name string) (C, error) {
+	return ConfigFromReader[C](bytes.NewReader(buf), name)
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	testFileConfig struct {
+		Name string `json:"name"`
+		ext  string
+	}
+	testReaderConfig struct {
+		testFileConfig
+		fromReader bool
+	}
+)
+
+func (c *testFileConfig) FromFile(path string) error {
+	c.ext = filepath.Ext(path)
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, c)
+}
+
+func (c *testReaderConfig) FromReader(r io.Reader) error {
+	c.fromReader = true
+	return json.NewDecoder(r).Decode(&c.testFileConfig)
+}
+
+func TestConfigFromBytes(t *testing.T) {
+	buf := []byte(`{"name":"atlas"}`)
+
+	t.Run("reader config", func(t *testing.T) {
+		c, err := ConfigFromBytes[*testReaderConfig](buf, "config.json")
+		require.NoError(t, err)
+		assert.True(t, c.fromReader)
+		assert.Equal(t, "atlas", c.Name)
+	})
+
+	t.Run("file config", func(t *testing.T) {
+		c, err := ConfigFromBytes[*testFileConfig](buf, "config.json")
+		require.NoError(t, err)
+		assert.Equal(t, "atlas", c.Name)
+		assert.Equal(t, ".json", c.ext)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := ConfigFromBytes[*testFileConfig]([]byte(`{`), "config.json")
+		assert.Error(t, err)
+	})
+
+	t.Run("app", func(t *testing.T) {
+		a := New[*testFileConfig](&Runtime{}, nil)
+		c, err := a.ConfigureFromReader(strings.NewReader(`{"name":"app"}`), "config.json")
+		require.NoError(t, err)
+		assert.Same(t, c, a.Config)
+		assert.Equal(t, "app", c.Name)
+	})
+}
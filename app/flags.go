@@ -8,6 +8,7 @@ type (
 	Flag             = cli.Flag
 	GenericFlag      = cli.GenericFlag
 	StringFlag       = cli.StringFlag
+	StringSliceFlag  = cli.StringSliceFlag
 	PathFlag         = cli.PathFlag
 	DurationFlag     = cli.DurationFlag
 	BoolFlag         = cli.BoolFlag
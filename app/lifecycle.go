@@ -0,0 +1,75 @@
+package app
+
+import (
+	"sync"
+
+	"git.tatikoma.dev/corpix/atlas/log"
+)
+
+type (
+	LifecycleEvent uint8
+
+	// Lifecycle broadcasts application lifecycle events to subscribers.
+	// It follows rpc.Stream semantics: delivery never blocks, events are
+	// dropped for subscribers which queue is full.
+	// rpc.Stream itself can not be used here, rpc depends on app.
+	Lifecycle struct {
+		subscriptions map[chan<- LifecycleEvent]void
+		mu            sync.Mutex
+	}
+)
+
+const (
+	LifecycleStarted LifecycleEvent = iota + 1
+	LifecycleReady
+	LifecycleStopping
+	LifecycleStopped
+)
+
+func (e LifecycleEvent) String() string {
+	switch e {
+	case LifecycleStarted:
+		return "started"
+	case LifecycleReady:
+		return "ready"
+	case LifecycleStopping:
+		return "stopping"
+	case LifecycleStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+func (l *Lifecycle) Subscribe(ch chan<- LifecycleEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.subscriptions == nil {
+		l.subscriptions = map[chan<- LifecycleEvent]void{}
+	}
+	l.subscriptions[ch] = void{}
+}
+
+func (l *Lifecycle) Unsubscribe(ch chan<- LifecycleEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.subscriptions, ch)
+}
+
+func (l *Lifecycle) emit(ev LifecycleEvent) {
+	log.Debug().
+		Str("event", ev.String()).
+		Msg("lifecycle event")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for ch := range l.subscriptions {
+		select {
+		case ch <- ev:
+		default:
+			log.Warn().
+				Str("event", ev.String()).
+				Msg("failed to deliver lifecycle event, subscriber queue is full")
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppLifecycle(t *testing.T) {
+	a := newTestApp(t, &testService{
+		name: "service",
+		run: func(ctx context.Context, ready *sync.WaitGroup) error {
+			ready.Done()
+			<-ctx.Done()
+			return nil
+		},
+	})
+
+	events := make(chan LifecycleEvent, 8)
+	a.Lifecycle().Subscribe(events)
+
+	a.runServices()
+	<-a.Ready()
+
+	exit := make(chan error, 1)
+	go func() {
+		exit <- a.Super.Wait(context.Background())
+	}()
+	a.Super.Cancel(nil)
+	_ = a.stop(exit, nil)
+
+	a.Lifecycle().Unsubscribe(events)
+	close(events)
+
+	var got []LifecycleEvent
+	for ev := range events {
+		got = append(got, ev)
+	}
+	assert.Equal(t, []LifecycleEvent{LifecycleStarted, LifecycleReady, LifecycleStopping, LifecycleStopped}, got)
+}
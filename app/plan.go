@@ -0,0 +1,180 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"git.tatikoma.dev/corpix/atlas/errors"
+	"git.tatikoma.dev/corpix/atlas/plan"
+)
+
+const (
+	FlagPlanType     = "type"
+	FlagPlanCurrent  = "current"
+	FlagPlanNext     = "next"
+	FlagPlanSummary  = "summary"
+	FlagPlanDiff     = "diff"
+	FlagPlanGraphviz = "graphviz"
+)
+
+type (
+	// PlanSpecType builds plan from JSON encoded states of concrete spec type,
+	// each state is a list of specs.
+	PlanSpecType interface {
+		Render(w io.Writer, current, next []byte, format PlanFormat) error
+	}
+	// PlanSpecTypes is a registry of spec types available for PlanCommand, keyed by name.
+	PlanSpecTypes map[string]PlanSpecType
+
+	PlanFormat struct {
+		Summary  bool
+		Diff     bool
+		Graphviz bool
+	}
+
+	planSpecType[T plan.Spec[K, T], K comparable, O plan.Ops[O]] struct {
+		ops      O
+		resolver plan.Resolver[T, K, O]
+	}
+	planNoopResolver[T plan.Spec[K, T], K comparable, O plan.Ops[O]] void
+)
+
+// NewPlanSpecType wraps concrete spec type to be registered in PlanSpecTypes,
+// resolver is used to render graph and may be nil if specs have no dependencies.
+func NewPlanSpecType[T plan.Spec[K, T], K comparable, O plan.Ops[O]](ops O, resolver plan.Resolver[T, K, O]) PlanSpecType {
+	if resolver == nil {
+		resolver = planNoopResolver[T, K, O]{}
+	}
+	return planSpecType[T, K, O]{ops: ops, resolver: resolver}
+}
+
+func (planNoopResolver[T, K, O]) Requests(O, T) []T { return nil }
+func (planNoopResolver[T, K, O]) Provides(O, T) []T { return nil }
+
+func (st planSpecType[T, K, O]) decode(name string, buf []byte) ([]T, error) {
+	var specs []T
+	err := json.Unmarshal(buf, &specs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode %s state", name)
+	}
+	return specs, nil
+}
+
+func (st planSpecType[T, K, O]) Render(w io.Writer, current, next []byte, format PlanFormat) error {
+	currentSpecs, err := st.decode(FlagPlanCurrent, current)
+	if err != nil {
+		return err
+	}
+	nextSpecs, err := st.decode(FlagPlanNext, next)
+	if err != nil {
+		return err
+	}
+	p := plan.New(st.ops, currentSpecs, nextSpecs)
+
+	if format.Summary {
+		changes, stat := p.Stat()
+		_, err = fmt.Fprintf(w, "changes: %d %s\ntasks: %s\n", changes, stat, p)
+		if err != nil {
+			return err
+		}
+	}
+	if format.Diff {
+		_, err = io.WriteString(w, p.Diff())
+		if err != nil {
+			return err
+		}
+	}
+	if format.Graphviz {
+		dot, err := p.Graphviz(st.resolver)
+		if err != nil {
+			return errors.Wrap(err, "failed to build plan graph")
+		}
+		_, err = io.WriteString(w, dot)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PlanCommand renders plan between "current" and "next" states loaded from JSON files.
+// Summary is printed when no output format is requested.
+func PlanCommand(types PlanSpecTypes) *Command {
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	typeFlag := &StringFlag{
+		Name:  FlagPlanType,
+		Usage: "spec type, one of: " + strings.Join(names, ", "),
+	}
+	if len(names) == 1 {
+		typeFlag.Value = names[0]
+	} else {
+		typeFlag.Required = true
+	}
+
+	return &Command{
+		Name:  "plan",
+		Usage: "render plan between current and next states",
+		Flags: Flags{
+			typeFlag,
+			&PathFlag{
+				Name:     FlagPlanCurrent,
+				Usage:    "current state JSON file path",
+				Required: true,
+			},
+			&PathFlag{
+				Name:     FlagPlanNext,
+				Usage:    "next state JSON file path",
+				Required: true,
+			},
+			&BoolFlag{
+				Name:  FlagPlanSummary,
+				Usage: "print plan summary",
+			},
+			&BoolFlag{
+				Name:  FlagPlanDiff,
+				Usage: "print plan diff",
+			},
+			&BoolFlag{
+				Name:  FlagPlanGraphviz,
+				Usage: "print plan dependency graph in DOT format",
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			name := ctx.String(FlagPlanType)
+			st, ok := types[name]
+			if !ok {
+				return errors.Errorf("unknown spec type %q, expected one of: %s", name, strings.Join(names, ", "))
+			}
+
+			current, err := os.ReadFile(ctx.Path(FlagPlanCurrent))
+			if err != nil {
+				return errors.Wrap(err, "failed to read current state")
+			}
+			next, err := os.ReadFile(ctx.Path(FlagPlanNext))
+			if err != nil {
+				return errors.Wrap(err, "failed to read next state")
+			}
+
+			format := PlanFormat{
+				Summary:  ctx.Bool(FlagPlanSummary),
+				Diff:     ctx.Bool(FlagPlanDiff),
+				Graphviz: ctx.Bool(FlagPlanGraphviz),
+			}
+			if format == (PlanFormat{}) {
+				format.Summary = true
+			}
+			return st.Render(ctx.App.Writer, current, next, format)
+		},
+	}
+}
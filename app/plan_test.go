@@ -0,0 +1,67 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+)
+
+type (
+	testPlanOps  string
+	testPlanSpec struct {
+		ID   string
+		Size int
+	}
+)
+
+func (testPlanOps) Read() testPlanOps   { return "read" }
+func (testPlanOps) Create() testPlanOps { return "create" }
+func (testPlanOps) Update() testPlanOps { return "update" }
+func (testPlanOps) Delete() testPlanOps { return "delete" }
+func (o testPlanOps) All() []testPlanOps {
+	return []testPlanOps{o.Read(), o.Create(), o.Update(), o.Delete()}
+}
+
+func (s testPlanSpec) String() string                { return s.ID }
+func (s testPlanSpec) Identify() string              { return s.ID }
+func (s testPlanSpec) Equal(other testPlanSpec) bool { return s == other }
+func (testPlanSpec) Weight() int64                   { return 0 }
+
+func TestPlanCommand(t *testing.T) {
+	dir := t.TempDir()
+	current := filepath.Join(dir, "current.json")
+	next := filepath.Join(dir, "next.json")
+	require.NoError(t, os.WriteFile(current, []byte(`[{"ID":"a","Size":1},{"ID":"b","Size":2}]`), 0o600))
+	require.NoError(t, os.WriteFile(next, []byte(`[{"ID":"a","Size":1},{"ID":"b","Size":3},{"ID":"c","Size":4}]`), 0o600))
+
+	run := func(t *testing.T, args ...string) string {
+		t.Helper()
+		var out bytes.Buffer
+		cmd := PlanCommand(PlanSpecTypes{
+			"test": NewPlanSpecType[testPlanSpec, string](testPlanOps(""), nil),
+		})
+		a := &cli.App{Writer: &out, Commands: Commands{cmd}}
+		require.NoError(t, a.Run(append([]string{"app", "plan", "--current", current, "--next", next}, args...)))
+		return out.String()
+	}
+
+	t.Run("summary", func(t *testing.T) {
+		assert.True(t, strings.HasPrefix(run(t), "changes: 2 [create:1,read:1,update:1]\n"))
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		out := run(t, "--diff")
+		assert.Contains(t, out, "-  Size: (int) 2")
+		assert.Contains(t, out, "+  Size: (int) 3")
+	})
+
+	t.Run("graphviz", func(t *testing.T) {
+		assert.True(t, strings.HasPrefix(run(t, "--graphviz"), "digraph plan {\n"))
+	})
+}
@@ -0,0 +1,147 @@
+package app
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"git.tatikoma.dev/corpix/atlas/errors"
+)
+
+type (
+	// ListenerService adapts server which accepts connections from net.Listener
+	// (http.Server, grpc.Server, rpc.Gateway) to the Service interface.
+	ListenerService struct {
+		listener    net.Listener
+		serve       func(net.Listener) error
+		shutdown    func(context.Context) error
+		done        chan void
+		name        string
+		addr        string
+		stopTimeout time.Duration
+		started     bool
+		closeOnce   sync.Once
+		mu          sync.Mutex
+	}
+)
+
+// NewListenerService creates a service which listens on tcp addr and
+// passes listener to serve, shutdown is called to gracefully stop serving on Close.
+// Service is disabled if addr is empty.
+func NewListenerService(
+	name, addr string,
+	serve func(net.Listener) error,
+	shutdown func(context.Context) error,
+) *ListenerService {
+	return &ListenerService{
+		name:        name,
+		addr:        addr,
+		serve:       serve,
+		shutdown:    shutdown,
+		done:        make(chan void),
+		stopTimeout: DefaultStopTimeout,
+	}
+}
+
+func (s *ListenerService) Name() string {
+	return s.name
+}
+
+func (s *ListenerService) Enabled() bool {
+	return s.addr != ""
+}
+
+// Addr returns address service is listening on, it is nil until service is running.
+func (s *ListenerService) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// StopTimeout returns duration Close waits for server to stop.
+func (s *ListenerService) StopTimeout() time.Duration {
+	return s.stopTimeout
+}
+
+// SetStopTimeout sets duration Close waits for server to stop,
+// usually it is App.StopTimeout so services fit into app watchdog.
+func (s *ListenerService) SetStopTimeout(timeout time.Duration) error {
+	if timeout <= 0 {
+		return errors.Errorf("stop timeout must be positive, got %s", timeout)
+	}
+	s.stopTimeout = timeout
+	return nil
+}
+
+func (s *ListenerService) Run(ctx context.Context, ready *sync.WaitGroup) error {
+	s.mu.Lock()
+	s.started = true
+	s.mu.Unlock()
+
+	l, err := (&net.ListenConfig{}).Listen(ctx, "tcp", s.addr)
+	if err != nil {
+		ready.Done()
+		close(s.done)
+		return errors.Wrapf(err, "failed to listen on %q", s.addr)
+	}
+
+	s.mu.Lock()
+	s.listener = l
+	s.mu.Unlock()
+	ready.Done()
+
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(s.done)
+		err := s.serve(l)
+		if errors.Is(err, http.ErrServerClosed) || errors.Is(err, net.ErrClosed) {
+			err = nil
+		}
+		errCh <- err
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-errCh:
+		return err
+	}
+}
+
+func (*ListenerService) Signal(os.Signal) {}
+
+// Close gracefully stops the server, it waits no longer than StopTimeout.
+// Close returns immediately if service was never run.
+func (s *ListenerService) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		started := s.started
+		s.mu.Unlock()
+		if !started {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.stopTimeout)
+		defer cancel()
+
+		err = s.shutdown(ctx)
+		if err != nil {
+			return
+		}
+		select {
+		case <-s.done:
+		case <-ctx.Done():
+			err = errors.Wrapf(ctx.Err(), "timed out waiting %q to stop", s.name)
+		}
+	})
+	return err
+}
+
+var _ Service = new(ListenerService)
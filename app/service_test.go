@@ -0,0 +1,73 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenerService(t *testing.T) {
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = io.WriteString(w, "ok")
+		}),
+		ReadHeaderTimeout: time.Second,
+	}
+	srv := NewListenerService("http", "127.0.0.1:0", server.Serve, server.Shutdown)
+	require.True(t, srv.Enabled())
+	require.False(t, NewListenerService("http", "", server.Serve, server.Shutdown).Enabled())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var ready sync.WaitGroup
+	ready.Add(1)
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- srv.Run(ctx, &ready)
+	}()
+	ready.Wait()
+	require.NotNil(t, srv.Addr())
+
+	res, err := http.Get("http://" + srv.Addr().String())
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	assert.Equal(t, "ok", string(body))
+
+	cancel()
+	select {
+	case err := <-runErr:
+		assert.NoError(t, err)
+	case <-time.After(DefaultStopTimeout):
+		t.Fatal("service did not stop")
+	}
+
+	started := time.Now()
+	assert.NoError(t, srv.Close())
+	assert.Less(t, time.Since(started), DefaultStopTimeout)
+	assert.NoError(t, srv.Close())
+}
+
+func TestListenerServiceStopTimeout(t *testing.T) {
+	srv := NewListenerService("http", "127.0.0.1:0",
+		func(net.Listener) error { return nil },
+		func(context.Context) error { return nil },
+	)
+	assert.Equal(t, DefaultStopTimeout, srv.StopTimeout())
+	require.Error(t, srv.SetStopTimeout(0))
+	require.NoError(t, srv.SetStopTimeout(time.Second))
+	assert.Equal(t, time.Second, srv.StopTimeout())
+
+	started := time.Now()
+	assert.NoError(t, srv.Close())
+	assert.Less(t, time.Since(started), 100*time.Millisecond)
+}
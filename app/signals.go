@@ -14,12 +14,15 @@ type (
 const (
 	SignalGroupStop   SignalGroup = 0
 	SignalGroupNotify             = iota
+	// SignalGroupCustom signals are routed to App signal handler, group is empty by default.
+	SignalGroupCustom SignalGroup = iota
 )
 
 var (
 	SignalGroups = []SignalGroup{
 		SignalGroupStop,
 		SignalGroupNotify,
+		SignalGroupCustom,
 	}
 )
 
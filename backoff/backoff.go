@@ -0,0 +1,54 @@
+package backoff
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"google.golang.org/grpc/backoff"
+)
+
+// Config is the same type gRPC uses for connection backoff,
+// so single configuration may be shared between them.
+type Config = backoff.Config
+
+var DefaultConfig = Config{
+	BaseDelay:  1 * time.Second,
+	Multiplier: 1.5,
+	Jitter:     0.2,
+	MaxDelay:   10 * time.Second,
+}
+
+// Backoff computes exponentially growing delays with jitter for consecutive attempts.
+// Delay never exceeds MaxDelay*(1+Jitter). It is not safe for concurrent use.
+type Backoff struct {
+	cfg     Config
+	attempt int
+}
+
+// Next returns delay before the next attempt.
+func (b *Backoff) Next() time.Duration {
+	delay := float64(b.cfg.BaseDelay)
+	if b.attempt > 0 {
+		delay *= math.Pow(b.cfg.Multiplier, float64(b.attempt))
+	}
+	delay = min(delay, float64(b.cfg.MaxDelay))
+	delay *= 1 + b.cfg.Jitter*(rand.Float64()*2-1)
+	b.attempt++
+
+	return time.Duration(max(delay, 0))
+}
+
+// Attempt returns number of delays returned since creation or last Reset.
+func (b *Backoff) Attempt() int {
+	return b.attempt
+}
+
+// Reset starts delays over from BaseDelay, it should be called after successful attempt.
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
+
+func New(cfg Config) *Backoff {
+	return &Backoff{cfg: cfg}
+}
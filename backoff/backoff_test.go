@@ -0,0 +1,54 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	t.Run("grows up to max delay", func(t *testing.T) {
+		b := New(Config{
+			BaseDelay:  time.Second,
+			Multiplier: 2,
+			MaxDelay:   10 * time.Second,
+		})
+		var delays []time.Duration
+		for range 6 {
+			delays = append(delays, b.Next())
+		}
+		assert.Equal(t, []time.Duration{
+			1 * time.Second,
+			2 * time.Second,
+			4 * time.Second,
+			8 * time.Second,
+			10 * time.Second,
+			10 * time.Second,
+		}, delays)
+		assert.Equal(t, 6, b.Attempt())
+	})
+
+	t.Run("jitter stays in range", func(t *testing.T) {
+		cfg := DefaultConfig
+		b := New(cfg)
+		for range 100 {
+			delay := b.Next()
+			assert.GreaterOrEqual(t, delay, time.Duration(float64(cfg.BaseDelay)*(1-cfg.Jitter)))
+			assert.LessOrEqual(t, delay, time.Duration(float64(cfg.MaxDelay)*(1+cfg.Jitter)))
+		}
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		b := New(Config{
+			BaseDelay:  time.Second,
+			Multiplier: 2,
+			MaxDelay:   10 * time.Second,
+		})
+		b.Next()
+		b.Next()
+		b.Reset()
+		assert.Equal(t, 0, b.Attempt())
+		assert.Equal(t, time.Second, b.Next())
+	})
+}
@@ -51,6 +51,11 @@ func JSON(value any) string {
 	return string(encoded)
 }
 
+// Lines returns dump of x split into lines, each line keeps trailing newline.
+func Lines(x any) []string {
+	return difflib.SplitLines(dumper.Sdump(x))
+}
+
 func Diff(a, b any, opts ...DiffOption) {
 	fmt.Println(Sdiff(a, b, opts...))
 }
@@ -62,8 +67,8 @@ func Sdiff(a, b any, opts ...DiffOption) string {
 	for _, fn := range opts {
 		fn(&params)
 	}
-	params.A = difflib.SplitLines(dumper.Sdump(a))
-	params.B = difflib.SplitLines(dumper.Sdump(b))
+	params.A = Lines(a)
+	params.B = Lines(b)
 	diff, _ := difflib.GetUnifiedDiffString(params)
 
 	return diff
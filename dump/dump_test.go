@@ -44,3 +44,18 @@ func Test(t *testing.T) {
 		``,
 	)
 }
+
+func TestLines(t *testing.T) {
+	assert.Equal(
+		t,
+		[]string{
+			"(map[string]int) (len=3) {\n",
+			"  (string) (len=1) \"a\": (int) 1,\n",
+			"  (string) (len=1) \"b\": (int) 2,\n",
+			"  (string) (len=1) \"c\": (int) 3\n",
+			"}\n",
+			"\n",
+		},
+		Lines(map[string]int{"c": 3, "a": 1, "b": 2}),
+	)
+}
@@ -1,9 +1,12 @@
 package log
 
 import (
+	"bytes"
 	"context"
+	"io"
 	stdlog "log"
 	"os"
+	"sync"
 
 	console "github.com/mattn/go-isatty"
 	"github.com/rs/zerolog"
@@ -14,10 +17,33 @@ type (
 	Logger  = zerolog.Logger
 	Context = zerolog.Context
 	Event   = *zerolog.Event
+
+	// ColorMode controls colorization of console output.
+	ColorMode int
+)
+
+const (
+	// ColorAuto enables colors if output is a terminal.
+	ColorAuto ColorMode = iota
+	ColorAlways
+	ColorNever
 )
 
+// NoColorEnv disables colors in any mode when set to non-empty value, see https://no-color.org.
+const NoColorEnv = "NO_COLOR"
+
 var DefaultLogger *Logger
 
+var (
+	mu     sync.Mutex
+	base   zerolog.Logger
+	output io.Writer = os.Stderr
+	color            = ColorAuto
+
+	caller     bool
+	callerSkip int
+)
+
 var (
 	DebugLevel = zerolog.DebugLevel
 	InfoLevel  = zerolog.InfoLevel
@@ -45,18 +71,62 @@ var (
 )
 
 func init() {
-	log.Logger = log.Logger.Output(zerolog.ConsoleWriter{
-		Out:     os.Stderr,
-		NoColor: !console.IsTerminal(os.Stderr.Fd()),
-	})
+	base = log.Logger
+	configure()
 
 	zerolog.DefaultContextLogger = &log.Logger
 	DefaultLogger = &log.Logger
 
 	stdlog.SetFlags(0)
+}
+
+func configure() {
+	l := base
+	if caller {
+		l = l.With().CallerWithSkipFrameCount(zerolog.CallerSkipFrameCount + callerSkip).Logger()
+	}
+	log.Logger = l.Output(zerolog.ConsoleWriter{
+		Out:     output,
+		NoColor: !colorize(color, output),
+	})
 	stdlog.SetOutput(log.Logger)
 }
 
+func colorize(mode ColorMode, w io.Writer) bool {
+	if os.Getenv(NoColorEnv) != "" {
+		return false
+	}
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	f, ok := w.(*os.File)
+	return ok && console.IsTerminal(f.Fd())
+}
+
+// SetColor sets colorization mode of the default logger output.
+func SetColor(mode ColorMode) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	color = mode
+	configure()
+}
+
+// SetCaller toggles reporting of the source file and line in the default logger,
+// skip is a number of additional stack frames to skip, use it when logging from helpers
+// so caller of the helper is reported.
+func SetCaller(enabled bool, skip int) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	caller = enabled
+	callerSkip = skip
+	configure()
+}
+
 func With() Context {
 	return log.Logger.With()
 }
@@ -68,3 +138,52 @@ func WithContext(ctx context.Context) context.Context {
 func Ctx(ctx context.Context) *Logger {
 	return zerolog.Ctx(ctx)
 }
+
+// WithTestWriter redirects default logger output to w with colors disabled,
+// returned function restores previous output.
+func WithTestWriter(w io.Writer) (restore func()) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	prevOutput, prevColor := output, color
+	output, color = w, ColorNever
+	configure()
+
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		output, color = prevOutput, prevColor
+		configure()
+	}
+}
+
+// Buffer is a bytes.Buffer safe for concurrent writes, used to capture log output.
+type Buffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *Buffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *Buffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
+
+// Capture redirects default logger output into returned buffer, see WithTestWriter.
+func Capture() (*Buffer, func()) {
+	buf := &Buffer{}
+	return buf, WithTestWriter(buf)
+}
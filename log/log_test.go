@@ -2,6 +2,9 @@ package log
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/rs/zerolog/log"
@@ -21,3 +24,67 @@ func TestLogger(t *testing.T) {
 	ctxLogger = Ctx(ctxEmpty)
 	assert.Equal(t, &log.Logger, ctxLogger)
 }
+
+func captureOutput(t *testing.T) *Buffer {
+	buf, restore := Capture()
+	t.Cleanup(func() {
+		SetCaller(false, 0)
+		restore()
+	})
+	return buf
+}
+
+func TestSetColor(t *testing.T) {
+	buf := captureOutput(t)
+
+	capture := func(mode ColorMode) string {
+		buf.Reset()
+		SetColor(mode)
+		Info().Str("key", "value").Msg("hello")
+		return buf.String()
+	}
+
+	assert.Contains(t, capture(ColorAlways), "\x1b[")
+	assert.NotContains(t, capture(ColorNever), "\x1b[")
+	assert.NotContains(t, capture(ColorAuto), "\x1b[")
+
+	t.Setenv(NoColorEnv, "1")
+	assert.NotContains(t, capture(ColorAuto), "\x1b[")
+	assert.NotContains(t, capture(ColorAlways), "\x1b[")
+}
+
+func TestSetCaller(t *testing.T) {
+	buf := captureOutput(t)
+
+	logHelper := func() {
+		Info().Msg("helper")
+	}
+
+	SetCaller(true, 0)
+	Info().Msg("hello")
+	assert.Contains(t, buf.String(), "log_test.go:")
+
+	buf.Reset()
+	SetCaller(true, 1)
+	logHelper()
+	_, file, line, _ := runtime.Caller(0)
+	assert.Contains(t, buf.String(), fmt.Sprintf("%s:%d", filepath.Base(file), line-1))
+
+	buf.Reset()
+	SetCaller(false, 0)
+	Info().Msg("hello")
+	assert.NotContains(t, buf.String(), "log_test.go:")
+}
+
+func TestCapture(t *testing.T) {
+	buf, restore := Capture()
+	Info().Str("key", "value").Msg("captured")
+	Printf("from %s", "printf")
+	restore()
+	Info().Msg("not captured")
+
+	assert.Contains(t, buf.String(), "INF captured key=value")
+	assert.Contains(t, buf.String(), "from printf")
+	assert.NotContains(t, buf.String(), "not captured")
+	assert.NotContains(t, buf.String(), "\x1b[")
+}
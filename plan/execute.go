@@ -0,0 +1,179 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// DefaultConcurrency is a number of tasks executed in parallel for operations without explicit limit.
+const DefaultConcurrency = 1
+
+type (
+	// Executor applies single task, it is called once all task dependencies are applied.
+	Executor[T Spec[K, T], K comparable, O Ops[O]] func(ctx context.Context, task *Task[T, K, O]) error
+
+	// Progress receives execution events, callbacks are invoked from a single goroutine
+	// which schedules tasks, so they should not block and need no synchronization.
+	Progress[T Spec[K, T], K comparable, O Ops[O]] struct {
+		OnStart    func(task *Task[T, K, O])
+		OnComplete func(task *Task[T, K, O], err error)
+		OnProgress func(done int, total int)
+	}
+
+	ExecuteOption[O comparable]  func(*executeOptions[O])
+	executeOptions[O comparable] struct {
+		concurrency   int
+		opConcurrency map[O]int
+		progress      any
+	}
+
+	executeResult struct {
+		err  error
+		task int
+	}
+)
+
+// WithConcurrency sets limit of tasks executed in parallel for operations without explicit limit.
+func WithConcurrency[O comparable](n int) ExecuteOption[O] {
+	return func(opts *executeOptions[O]) {
+		opts.concurrency = n
+	}
+}
+
+// WithOpConcurrency sets limit of tasks of operation op executed in parallel.
+func WithOpConcurrency[O comparable](op O, n int) ExecuteOption[O] {
+	return func(opts *executeOptions[O]) {
+		opts.opConcurrency[op] = n
+	}
+}
+
+// WithProgress sets callbacks notified about execution of tasks.
+func WithProgress[T Spec[K, T], K comparable, O Ops[O]](p Progress[T, K, O]) ExecuteOption[O] {
+	return func(opts *executeOptions[O]) {
+		opts.progress = p
+	}
+}
+
+func (p Progress[T, K, O]) start(task *Task[T, K, O]) {
+	if p.OnStart != nil {
+		p.OnStart(task)
+	}
+}
+
+func (p Progress[T, K, O]) complete(task *Task[T, K, O], err error, done int, total int) {
+	if p.OnComplete != nil {
+		p.OnComplete(task, err)
+	}
+	if p.OnProgress != nil {
+		p.OnProgress(done, total)
+	}
+}
+
+func (o executeOptions[O]) limit(op O) int {
+	n, ok := o.opConcurrency[op]
+	if !ok {
+		n = o.concurrency
+	}
+	return max(n, 1)
+}
+
+// Execute runs tasks of the graph with fn honoring dependencies between them,
+// number of tasks of each operation running in parallel is limited by options.
+// First error cancels context passed to the running tasks and stops execution.
+func (g *Graph[T, K, O]) Execute(ctx context.Context, fn Executor[T, K, O], opts ...ExecuteOption[O]) error {
+	options := executeOptions[O]{
+		concurrency:   DefaultConcurrency,
+		opConcurrency: map[O]int{},
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	progress, _ := options.progress.(Progress[T, K, O])
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		indegree = make([]int, len(g.tasks))
+		running  = map[O]int{}
+		results  = make(chan executeResult)
+		ready    []int
+		inflight int
+		done     int
+		err      error
+	)
+	copy(indegree, g.indegree)
+	for i := range g.tasks {
+		if indegree[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	for done < len(g.tasks) {
+		if err == nil && ctx.Err() != nil {
+			err = context.Cause(ctx)
+		}
+		if err == nil {
+			sort.Slice(ready, func(i, j int) bool {
+				return g.pos[ready[i]] < g.pos[ready[j]]
+			})
+			pending := ready[:0]
+			for _, i := range ready {
+				task := g.tasks[i]
+				if running[task.Op] >= options.limit(task.Op) {
+					pending = append(pending, i)
+					continue
+				}
+				running[task.Op]++
+				inflight++
+				progress.start(task)
+				go func(i int) {
+					results <- executeResult{task: i, err: fn(ctx, g.tasks[i])}
+				}(i)
+			}
+			ready = pending
+		}
+		if inflight == 0 {
+			break
+		}
+
+		res := <-results
+		inflight--
+		done++
+		task := g.tasks[res.task]
+		running[task.Op]--
+		progress.complete(task, res.err, done, len(g.tasks))
+
+		if res.err != nil {
+			if err == nil {
+				err = fmt.Errorf("task %v failed: %w", task, res.err)
+				cancel()
+			}
+			continue
+		}
+		for next := range g.adj[res.task] {
+			indegree[next]--
+			if indegree[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+	if done != len(g.tasks) {
+		return fmt.Errorf("dependency cycle: %d tasks were not executed", len(g.tasks)-done)
+	}
+	return nil
+}
+
+// Execute applies all plan tasks with fn, see Graph.Execute.
+func (p *Plan[T, K, O]) Execute(ctx context.Context, resolver Resolver[T, K, O], fn Executor[T, K, O], opts ...ExecuteOption[O]) error {
+	g, err := p.graph(resolver)
+	if err != nil {
+		return err
+	}
+	return g.Execute(ctx, fn, opts...)
+}
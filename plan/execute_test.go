@@ -0,0 +1,140 @@
+package plan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type executeTracker struct {
+	running map[resourceOps]int
+	peak    map[resourceOps]int
+	order   []string
+	mu      sync.Mutex
+}
+
+func (e *executeTracker) executor(delay time.Duration) Executor[resource, string, resourceOps] {
+	return func(ctx context.Context, task *Task[resource, string, resourceOps]) error {
+		e.mu.Lock()
+		e.running[task.Op]++
+		e.peak[task.Op] = max(e.peak[task.Op], e.running[task.Op])
+		e.mu.Unlock()
+
+		time.Sleep(delay)
+
+		e.mu.Lock()
+		e.running[task.Op]--
+		e.order = append(e.order, task.ID)
+		e.mu.Unlock()
+		return nil
+	}
+}
+
+func newExecuteTracker() *executeTracker {
+	return &executeTracker{
+		running: map[resourceOps]int{},
+		peak:    map[resourceOps]int{},
+	}
+}
+
+func TestExecute(t *testing.T) {
+	var current, next []resource
+	for i := range 5 {
+		current = append(current, resource{ID: fmt.Sprintf("old%d", i)})
+		next = append(next, resource{ID: fmt.Sprintf("new%d", i)})
+	}
+	p := New(resourceOpsEnum, current, next)
+
+	t.Run("per op concurrency", func(t *testing.T) {
+		tracker := newExecuteTracker()
+		err := p.Execute(
+			context.Background(), resourceResolver{}, tracker.executor(20*time.Millisecond),
+			WithOpConcurrency(resourceOpsEnum.Delete(), 1),
+			WithOpConcurrency(resourceOpsEnum.Create(), 5),
+		)
+		assert.NoError(t, err)
+		assert.Len(t, tracker.order, 10)
+		assert.Equal(t, 1, tracker.peak[resourceOpsEnum.Delete()])
+		assert.Equal(t, 5, tracker.peak[resourceOpsEnum.Create()])
+	})
+
+	t.Run("honors dependencies", func(t *testing.T) {
+		next := []resource{{ID: "a", Name: "a"}, {ID: "b", Name: "b"}, {ID: "c", Name: "c"}}
+		p := New(resourceOpsEnum, nil, next)
+		resolver := resourceResolver{"b": {next[0]}, "c": {next[1]}}
+
+		tracker := newExecuteTracker()
+		err := p.Execute(
+			context.Background(), resolver, tracker.executor(time.Millisecond),
+			WithConcurrency[resourceOps](10),
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "b", "c"}, tracker.order)
+		assert.Equal(t, 1, tracker.peak[resourceOpsEnum.Create()])
+	})
+
+	t.Run("stops on error", func(t *testing.T) {
+		next := []resource{{ID: "a", Name: "a"}, {ID: "b", Name: "b"}}
+		p := New(resourceOpsEnum, nil, next)
+		resolver := resourceResolver{"b": {next[0]}}
+		expectedErr := errors.New("failed")
+
+		var executed []string
+		err := p.Execute(context.Background(), resolver, func(ctx context.Context, task *Task[resource, string, resourceOps]) error {
+			executed = append(executed, task.ID)
+			return expectedErr
+		})
+		assert.ErrorIs(t, err, expectedErr)
+		assert.Equal(t, []string{"a"}, executed)
+	})
+}
+
+func TestExecuteProgress(t *testing.T) {
+	var current, next []resource
+	for i := range 5 {
+		current = append(current, resource{ID: fmt.Sprintf("old%d", i)})
+		next = append(next, resource{ID: fmt.Sprintf("new%d", i)})
+	}
+	p := New(resourceOpsEnum, current, next)
+
+	// callbacks are not synchronized, race detector catches calls from multiple goroutines
+	var (
+		started   = map[string]int{}
+		completed = map[string]int{}
+		progress  []int
+		total     int
+	)
+	err := p.Execute(
+		context.Background(), resourceResolver{}, newExecuteTracker().executor(time.Millisecond),
+		WithConcurrency[resourceOps](5),
+		WithProgress(Progress[resource, string, resourceOps]{
+			OnStart: func(task *Task[resource, string, resourceOps]) {
+				started[task.ID]++
+			},
+			OnComplete: func(task *Task[resource, string, resourceOps], err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, 1, started[task.ID])
+				completed[task.ID]++
+			},
+			OnProgress: func(done int, n int) {
+				progress = append(progress, done)
+				total = n
+			},
+		}),
+	)
+	assert.NoError(t, err)
+
+	assert.Len(t, started, 10)
+	assert.Len(t, completed, 10)
+	for id := range started {
+		assert.Equal(t, 1, started[id], id)
+		assert.Equal(t, 1, completed[id], id)
+	}
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, progress)
+	assert.Equal(t, 10, total)
+}
@@ -1,6 +1,8 @@
 package plan
 
 import (
+	"container/heap"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
@@ -28,6 +30,11 @@ type (
 		Equal(T) bool
 		Weight() int64
 	}
+	// CanonicalSpec may be implemented by spec to normalize it before rendering diff,
+	// for example to sort slices which order is ignored by Equal.
+	CanonicalSpec[T any] interface {
+		Canonical() T
+	}
 	Resolver[T Spec[K, T], K comparable, O Ops[O]] interface {
 		Requests(op O, spec T) []T
 		Provides(op O, spec T) []T
@@ -73,7 +80,16 @@ type (
 	Diff[T Spec[K, T], K comparable, O Ops[O]]       []DiffRecord[T, K, O]
 	DiffFilter[T Spec[K, T], K comparable, O Ops[O]] func(DiffRecord[T, K, O]) bool
 
-	Context[T Spec[K, T], K comparable, Y any] struct {
+	// GraphvizStyle describes node attributes used to render task of specific operation.
+	GraphvizStyle struct {
+		Shape     string
+		FillColor string
+	}
+	GraphvizStyles[O comparable] map[O]GraphvizStyle
+
+	Context[T Spec[K, T], K comparable, O Ops[O], Y any] struct {
+		ID      K
+		Op      O
 		Current T
 		Next    T
 		Data    Y
@@ -93,41 +109,94 @@ func DiffFilterOp[T Spec[K, T], K comparable, O Ops[O]](ops ...O) DiffFilter[T,
 	}
 }
 
+// DiffFilterID passes records of resources with specified ids.
+func DiffFilterID[T Spec[K, T], K comparable, O Ops[O]](ids ...K) DiffFilter[T, K, O] {
+	index := make(map[K]void, len(ids))
+	for _, id := range ids {
+		index[id] = void{}
+	}
+	return func(record DiffRecord[T, K, O]) bool {
+		var empty T
+		if record.Current != empty {
+			if _, ok := index[record.Current.Identify()]; ok {
+				return true
+			}
+		}
+		if record.Next != empty {
+			if _, ok := index[record.Next.Identify()]; ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// toposortCheckInterval is a number of processed tasks between context cancellation checks.
+const toposortCheckInterval = 1024
+
+// readyQueue is a min-heap of task indexes ordered by their position in the plan.
+type readyQueue struct {
+	items []int
+	pos   []int
+}
+
+func (q *readyQueue) Len() int           { return len(q.items) }
+func (q *readyQueue) Less(i, j int) bool { return q.pos[q.items[i]] < q.pos[q.items[j]] }
+func (q *readyQueue) Swap(i, j int)      { q.items[i], q.items[j] = q.items[j], q.items[i] }
+func (q *readyQueue) Push(x any)         { q.items = append(q.items, x.(int)) }
+func (q *readyQueue) Pop() any {
+	n := len(q.items) - 1
+	x := q.items[n]
+	q.items = q.items[:n]
+	return x
+}
+
 func (g *Graph[T, K, O]) Toposort() (Tasks[T, K, O], error) {
+	return g.ToposortContext(context.Background())
+}
+
+// ToposortContext is like Toposort, but stops with context error once ctx is done.
+func (g *Graph[T, K, O]) ToposortContext(ctx context.Context) (Tasks[T, K, O], error) {
 	if len(g.tasks) == 0 {
 		return g.tasks, nil
 	}
 
-	ready := make([]int, 0, len(g.tasks))
+	indegree := make([]int, len(g.indegree))
+	copy(indegree, g.indegree)
+
+	ready := &readyQueue{
+		items: make([]int, 0, len(g.tasks)),
+		pos:   g.pos,
+	}
 	for i := range g.tasks {
-		if g.indegree[i] == 0 {
-			ready = append(ready, i)
+		if indegree[i] == 0 {
+			ready.items = append(ready.items, i)
 		}
 	}
-	sort.Slice(ready, func(i, j int) bool {
-		return g.pos[ready[i]] < g.pos[ready[j]]
-	})
+	heap.Init(ready)
 
 	out := make(Tasks[T, K, O], 0, len(g.tasks))
-	for len(ready) > 0 {
-		curr := ready[0]
-		ready = ready[1:]
+	for ready.Len() > 0 {
+		if len(out)%toposortCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, context.Cause(ctx)
+			}
+		}
+
+		curr := heap.Pop(ready).(int)
 		out = append(out, g.tasks[curr])
 
 		for next := range g.adj[curr] {
-			g.indegree[next]--
-			if g.indegree[next] == 0 {
-				ready = append(ready, next)
-				sort.Slice(ready, func(i, j int) bool {
-					return g.pos[ready[i]] < g.pos[ready[j]]
-				})
+			indegree[next]--
+			if indegree[next] == 0 {
+				heap.Push(ready, next)
 			}
 		}
 	}
 
 	if len(out) != len(g.tasks) {
 		var unresolved []string
-		for i, deg := range g.indegree {
+		for i, deg := range indegree {
 			if deg > 0 {
 				unresolved = append(unresolved, g.tasks[i].String())
 			}
@@ -153,7 +222,17 @@ func (g *Graph[T, K, O]) label(s string) string {
 	return replacer.Replace(s)
 }
 
+// String renders graph in DOT format without styling.
 func (g *Graph[T, K, O]) String() string {
+	return g.render(nil)
+}
+
+// Styled renders graph in DOT format, nodes are styled according to their operation.
+func (g *Graph[T, K, O]) Styled(styles GraphvizStyles[O]) string {
+	return g.render(styles)
+}
+
+func (g *Graph[T, K, O]) render(styles GraphvizStyles[O]) string {
 	var b strings.Builder
 	b.WriteString("digraph plan {\n")
 
@@ -170,29 +249,20 @@ func (g *Graph[T, K, O]) String() string {
 			b.WriteString(nodeIDs[task])
 			b.WriteString(" [label=\"")
 			b.WriteString(label)
-			b.WriteString("\"];\n")
-		}
-
-		for i, edges := range g.adj {
-			if len(edges) == 0 {
-				continue
-			}
-			consumers := make([]int, 0, len(edges))
-			for idx := range edges {
-				consumers = append(consumers, idx)
-			}
-			sort.Slice(consumers, func(a, b int) bool {
-				return g.pos[consumers[a]] < g.pos[consumers[b]]
-			})
-
-			for _, j := range consumers {
-				b.WriteString("  ")
-				b.WriteString(nodeIDs[g.tasks[i]])
-				b.WriteString(" -> ")
-				b.WriteString(nodeIDs[g.tasks[j]])
-				b.WriteString(";\n")
+			b.WriteString("\"")
+			if style, ok := styles[task.Op]; ok {
+				b.WriteString(style.String())
 			}
+			b.WriteString("];\n")
 		}
+
+		g.edges(func(i, j int) {
+			b.WriteString("  ")
+			b.WriteString(nodeIDs[g.tasks[i]])
+			b.WriteString(" -> ")
+			b.WriteString(nodeIDs[g.tasks[j]])
+			b.WriteString(";\n")
+		})
 	}
 
 	b.WriteString("}\n")
@@ -200,6 +270,92 @@ func (g *Graph[T, K, O]) String() string {
 	return b.String()
 }
 
+// Mermaid renders graph as Mermaid flowchart.
+func (g *Graph[T, K, O]) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+
+	ordered, err := g.Toposort()
+	if err == nil {
+		nodeIDs := make(map[*Task[T, K, O]]string, len(g.tasks))
+		for _, task := range g.tasks {
+			nodeIDs[task] = g.nodeID(task)
+		}
+
+		for _, task := range ordered {
+			b.WriteString("  ")
+			b.WriteString(nodeIDs[task])
+			b.WriteString("[\"")
+			b.WriteString(g.mermaidLabel(fmt.Sprintf("%v\n%v", task.Op, task.Spec.String())))
+			b.WriteString("\"]\n")
+		}
+
+		g.edges(func(i, j int) {
+			b.WriteString("  ")
+			b.WriteString(nodeIDs[g.tasks[i]])
+			b.WriteString(" --> ")
+			b.WriteString(nodeIDs[g.tasks[j]])
+			b.WriteString("\n")
+		})
+	}
+
+	return b.String()
+}
+
+func (g *Graph[T, K, O]) mermaidLabel(s string) string {
+	replacer := strings.NewReplacer(
+		"\"", "#quot;",
+		"\n", "<br/>",
+	)
+	return replacer.Replace(s)
+}
+
+// edges calls fn for each provider -> consumer edge in stable order.
+func (g *Graph[T, K, O]) edges(fn func(provider, consumer int)) {
+	for i, edges := range g.adj {
+		if len(edges) == 0 {
+			continue
+		}
+		consumers := make([]int, 0, len(edges))
+		for idx := range edges {
+			consumers = append(consumers, idx)
+		}
+		sort.Slice(consumers, func(a, b int) bool {
+			return g.pos[consumers[a]] < g.pos[consumers[b]]
+		})
+
+		for _, j := range consumers {
+			fn(i, j)
+		}
+	}
+}
+
+func (s GraphvizStyle) String() string {
+	var b strings.Builder
+	if s.Shape != "" {
+		b.WriteString(", shape=\"")
+		b.WriteString(s.Shape)
+		b.WriteString("\"")
+	}
+	if s.FillColor != "" {
+		b.WriteString(", style=\"filled\", fillcolor=\"")
+		b.WriteString(s.FillColor)
+		b.WriteString("\"")
+	}
+	return b.String()
+}
+
+// DefaultGraphvizStyles returns styles which distinguish operations by color.
+func DefaultGraphvizStyles[O Ops[O]]() GraphvizStyles[O] {
+	var ops O
+	return GraphvizStyles[O]{
+		ops.Create(): {Shape: "box", FillColor: "palegreen"},
+		ops.Update(): {Shape: "box", FillColor: "orange"},
+		ops.Delete(): {Shape: "box", FillColor: "lightcoral"},
+		ops.Read():   {Shape: "box", FillColor: "lightgray"},
+	}
+}
+
 func (t Task[T, K, O]) String() string {
 	return fmt.Sprintf("%v(%v)", t.Op, t.Spec.String())
 }
@@ -246,6 +402,21 @@ func (p *Plan[T, K, O]) Changes() int {
 	return p.changes
 }
 
+// HasChanges reports whether plan contains create, update or delete tasks.
+func (p *Plan[T, K, O]) HasChanges() bool {
+	return p.changes > 0
+}
+
+// IsNoop reports whether applying plan changes nothing, it may still contain read tasks.
+func (p *Plan[T, K, O]) IsNoop() bool {
+	return !p.HasChanges()
+}
+
+// Empty reports whether plan contains no tasks at all.
+func (p *Plan[T, K, O]) Empty() bool {
+	return len(p.tasksIndex) == 0
+}
+
 func (p *Plan[T, K, O]) Tasks(ops ...O) Tasks[T, K, O] {
 	if len(ops) == 0 {
 		ops = p.opsEnum.All()
@@ -289,6 +460,15 @@ func (p *Plan[T, K, O]) Toposort(resolver Resolver[T, K, O], ops ...O) (Tasks[T,
 	return g.Toposort()
 }
 
+// ToposortContext is like Toposort, but stops with context error once ctx is done.
+func (p *Plan[T, K, O]) ToposortContext(ctx context.Context, resolver Resolver[T, K, O], ops ...O) (Tasks[T, K, O], error) {
+	g, err := p.graph(resolver, ops...)
+	if err != nil {
+		return nil, err
+	}
+	return g.ToposortContext(ctx)
+}
+
 func (p *Plan[T, K, O]) Graphviz(resolver Resolver[T, K, O], ops ...O) (string, error) {
 	g, err := p.graph(resolver, ops...)
 	if err != nil {
@@ -297,6 +477,24 @@ func (p *Plan[T, K, O]) Graphviz(resolver Resolver[T, K, O], ops ...O) (string,
 	return g.String(), nil
 }
 
+// Mermaid renders plan dependency graph as Mermaid flowchart.
+func (p *Plan[T, K, O]) Mermaid(resolver Resolver[T, K, O], ops ...O) (string, error) {
+	g, err := p.graph(resolver, ops...)
+	if err != nil {
+		return "", err
+	}
+	return g.Mermaid(), nil
+}
+
+// GraphvizStyled is like Graphviz, but nodes are styled with DefaultGraphvizStyles.
+func (p *Plan[T, K, O]) GraphvizStyled(resolver Resolver[T, K, O], ops ...O) (string, error) {
+	g, err := p.graph(resolver, ops...)
+	if err != nil {
+		return "", err
+	}
+	return g.Styled(DefaultGraphvizStyles[O]()), nil
+}
+
 func (p Plan[T, K, O]) Current() []T {
 	return p.current
 }
@@ -323,7 +521,7 @@ outer:
 		}
 
 		s += dump.Sdiff(
-			r.Current, r.Next,
+			canonical(r.Current), canonical(r.Next),
 			func(p *dump.DiffParameters) {
 				p.FromFile = fmt.Sprintf("current:\t%v", r.Current)
 				p.ToFile = fmt.Sprintf("next:\t%v", r.Next)
@@ -340,6 +538,17 @@ outer:
 	return s
 }
 
+func canonical[T comparable](spec T) T {
+	var empty T
+	if spec == empty {
+		return spec
+	}
+	if c, ok := any(spec).(CanonicalSpec[T]); ok {
+		return c.Canonical()
+	}
+	return spec
+}
+
 func (p *Plan[T, K, O]) findProvider(tasks Tasks[T, K, O], resolver Resolver[T, K, O], req T) (int, error) {
 	var (
 		bestIdx    = -1
@@ -463,16 +672,33 @@ func (p *Plan[T, K, O]) push(op O, id K, current T, next T) {
 	})
 }
 
+// build pushes tasks in order specs appear in current and next states,
+// so plan, its diff and rendered graph are stable for identical input.
 func (p *Plan[T, K, O]) build(current, next []T) {
 	currentIndex, nextIndex := p.index(current, next)
-	for id, nextSpec := range nextIndex {
+	pushed := make(map[K]void, len(currentIndex)+len(nextIndex))
+	for _, spec := range next {
+		id := spec.Identify()
+		if _, ok := pushed[id]; ok {
+			continue
+		}
+		pushed[id] = void{}
+
 		currentSpec, ok := currentIndex[id]
 		if !ok {
-			p.push(p.opsEnum.Create(), id, currentSpec, nextSpec)
+			p.push(p.opsEnum.Create(), id, currentSpec, nextIndex[id])
 		}
 	}
-	for id, currentSpec := range currentIndex {
+	clear(pushed)
+	for _, spec := range current {
+		id := spec.Identify()
+		if _, ok := pushed[id]; ok {
+			continue
+		}
+		pushed[id] = void{}
+
 		var op O
+		currentSpec := currentIndex[id]
 		nextSpec, ok := nextIndex[id]
 		if ok {
 			if currentSpec.Equal(nextSpec) {
@@ -500,8 +726,10 @@ func New[T Spec[K, T], K comparable, O Ops[O]](_ O, current, next []T) *Plan[T,
 	return plan
 }
 
-func TaskContext[T Spec[K, T], K comparable, O Ops[O], Y any](t *Task[T, K, O], data Y) Context[T, K, Y] {
-	return Context[T, K, Y]{
+func TaskContext[T Spec[K, T], K comparable, O Ops[O], Y any](t *Task[T, K, O], data Y) Context[T, K, O, Y] {
+	return Context[T, K, O, Y]{
+		ID:      t.ID,
+		Op:      t.Op,
 		Current: t.Current,
 		Next:    t.Next,
 		Data:    data,
@@ -1,6 +1,8 @@
 package plan
 
 import (
+	"fmt"
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -99,3 +101,195 @@ func TestPlan(t *testing.T) {
 		test(t, sp)
 	})
 }
+
+func TestPlanNoop(t *testing.T) {
+	specs := []resource{
+		{ID: "a", Name: "alpha", Size: 1},
+		{ID: "b", Name: "beta", Size: 2},
+	}
+
+	t.Run("no changes between equal states", func(t *testing.T) {
+		p := New(resourceOpsEnum, specs, specs)
+		assert.True(t, p.IsNoop())
+		assert.False(t, p.HasChanges())
+		assert.False(t, p.Empty())
+	})
+
+	t.Run("has changes", func(t *testing.T) {
+		p := New(resourceOpsEnum, specs, specs[:1])
+		assert.False(t, p.IsNoop())
+		assert.True(t, p.HasChanges())
+		assert.False(t, p.Empty())
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		p := New[resource, string](resourceOpsEnum, nil, nil)
+		assert.True(t, p.IsNoop())
+		assert.True(t, p.Empty())
+	})
+}
+
+func TestTaskContext(t *testing.T) {
+	p := New(resourceOpsEnum, nil, []resource{{ID: "a", Name: "alpha", Size: 1}})
+	task, ok := p.Task("a")
+	assert.True(t, ok)
+
+	ctx := TaskContext(task, 42)
+	assert.Equal(t, "a", ctx.ID)
+	assert.Equal(t, resourceOpsEnum.Create(), ctx.Op)
+	assert.Equal(t, task.Current, ctx.Current)
+	assert.Equal(t, task.Next, ctx.Next)
+	assert.Equal(t, 42, ctx.Data)
+}
+
+type resourceResolver map[string][]resource
+
+func (r resourceResolver) Requests(_ resourceOps, spec resource) []resource {
+	return r[spec.ID]
+}
+
+func (r resourceResolver) Provides(_ resourceOps, spec resource) []resource {
+	return []resource{spec}
+}
+
+func TestGraphvizStyled(t *testing.T) {
+	current := []resource{
+		{ID: "a", Name: "alpha", Size: 1},
+		{ID: "b", Name: "beta", Size: 2},
+		{ID: "c", Name: "gamma", Size: 3},
+	}
+	next := []resource{
+		{ID: "a", Name: "alpha", Size: 1},
+		{ID: "b", Name: "delta", Size: 4},
+		{ID: "d", Name: "epsilon", Size: 5},
+	}
+	p := New(resourceOpsEnum, current, next)
+
+	t.Run("styles nodes by op", func(t *testing.T) {
+		dot, err := p.GraphvizStyled(resourceResolver{})
+		assert.NoError(t, err)
+		for _, color := range []string{"palegreen", "orange", "lightcoral", "lightgray"} {
+			assert.Contains(t, dot, `fillcolor="`+color+`"`)
+		}
+	})
+
+	t.Run("plain mode has no styling", func(t *testing.T) {
+		dot, err := p.Graphviz(resourceResolver{})
+		assert.NoError(t, err)
+		assert.NotContains(t, dot, "fillcolor")
+	})
+}
+
+func TestMermaid(t *testing.T) {
+	next := []resource{
+		{ID: "a", Name: "alpha", Size: 1},
+		{ID: "b", Name: "beta", Size: 2},
+	}
+	p := New(resourceOpsEnum, nil, next)
+	resolver := resourceResolver{"b": {next[0]}}
+
+	out, err := p.Mermaid(resolver)
+	assert.NoError(t, err)
+
+	g, err := p.Graph(resolver)
+	assert.NoError(t, err)
+	a, _ := p.Task("a")
+	b, _ := p.Task("b")
+
+	assert.Equal(t, "flowchart TD\n"+
+		"  "+g.nodeID(a)+"[\"create<br/>a\"]\n"+
+		"  "+g.nodeID(b)+"[\"create<br/>b\"]\n"+
+		"  "+g.nodeID(a)+" --> "+g.nodeID(b)+"\n",
+		out,
+	)
+}
+
+type taggedResource struct {
+	ID   string
+	Tags []string
+}
+
+func (r *taggedResource) String() string   { return r.ID }
+func (r *taggedResource) Identify() string { return r.ID }
+func (r *taggedResource) Weight() int64    { return 0 }
+
+func (r *taggedResource) Equal(other *taggedResource) bool {
+	return slices.Equal(r.Canonical().Tags, other.Canonical().Tags)
+}
+
+func (r *taggedResource) Canonical() *taggedResource {
+	tags := slices.Clone(r.Tags)
+	slices.Sort(tags)
+	return &taggedResource{ID: r.ID, Tags: tags}
+}
+
+func TestDiffCanonical(t *testing.T) {
+	current := []*taggedResource{{ID: "a", Tags: []string{"x", "y", "z"}}}
+	next := []*taggedResource{{ID: "a", Tags: []string{"z", "x", "y"}}}
+
+	p := New(resourceOps(""), current, next)
+	assert.Len(t, p.Tasks(resourceOpsEnum.Read()), 1)
+	assert.False(t, p.HasChanges())
+	assert.Empty(t, p.Diff())
+
+	next = []*taggedResource{{ID: "a", Tags: []string{"z", "x"}}}
+	p = New(resourceOps(""), current, next)
+	assert.Len(t, p.Tasks(resourceOpsEnum.Update()), 1)
+	assert.Contains(t, p.Diff(), `-    (string) (len=1) "y",`)
+}
+
+func TestGraphvizDeterministic(t *testing.T) {
+	var current, next []resource
+	for i := range 50 {
+		current = append(current, resource{ID: fmt.Sprintf("r%02d", i), Name: "current"})
+		if i%3 != 0 {
+			next = append(next, resource{ID: fmt.Sprintf("r%02d", i), Name: fmt.Sprintf("next%d", i%2)})
+		}
+		next = append(next, resource{ID: fmt.Sprintf("n%02d", i), Name: fmt.Sprintf("new%02d", i)})
+	}
+	resolver := resourceResolver{}
+	for i := 1; i < 50; i++ {
+		resolver[fmt.Sprintf("n%02d", i)] = []resource{{Name: fmt.Sprintf("new%02d", i-1)}}
+	}
+
+	expected, err := New(resourceOpsEnum, current, next).GraphvizStyled(resolver)
+	assert.NoError(t, err)
+	for range 20 {
+		p := New(resourceOpsEnum, current, next)
+		dot, err := p.GraphvizStyled(resolver)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, dot)
+
+		again, err := p.GraphvizStyled(resolver)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, again)
+	}
+}
+
+func TestDiffFilterID(t *testing.T) {
+	current := []resource{
+		{ID: "a", Name: "alpha", Size: 1},
+		{ID: "b", Name: "beta", Size: 2},
+		{ID: "c", Name: "gamma", Size: 3},
+	}
+	next := []resource{
+		{ID: "a", Name: "alpha", Size: 10},
+		{ID: "b", Name: "beta", Size: 20},
+		{ID: "d", Name: "delta", Size: 4},
+	}
+	p := New(resourceOpsEnum, current, next)
+
+	diff := p.Diff(DiffFilterID[resource, string, resourceOps]("b"))
+	assert.Contains(t, diff, "current:\tb")
+	assert.Contains(t, diff, "+  Size: (int) 20")
+	assert.NotContains(t, diff, "current:\ta")
+	assert.NotContains(t, diff, "current:\tc")
+	assert.NotContains(t, diff, "next:\td")
+
+	t.Run("matches created and deleted resources", func(t *testing.T) {
+		diff := p.Diff(DiffFilterID[resource, string, resourceOps]("c", "d"))
+		assert.Contains(t, diff, "current:\tc")
+		assert.Contains(t, diff, "next:\td")
+		assert.NotContains(t, diff, "current:\ta")
+	})
+}
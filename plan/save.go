@@ -0,0 +1,162 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// savedPlanVersion is incremented on incompatible changes of saved plan format.
+const savedPlanVersion = 1
+
+type (
+	// SpecDecoder decodes spec encoded with encoding/json.
+	SpecDecoder[T any] func(data []byte) (T, error)
+
+	savedPlan struct {
+		Version int               `json:"version"`
+		Current []json.RawMessage `json:"current"`
+		Next    []json.RawMessage `json:"next"`
+		Diff    []savedRecord     `json:"diff"`
+	}
+	savedRecord struct {
+		Op      json.RawMessage `json:"op"`
+		Current json.RawMessage `json:"current"`
+		Next    json.RawMessage `json:"next"`
+	}
+)
+
+func encodeSpec[T comparable](spec T) (json.RawMessage, error) {
+	var empty T
+	if spec == empty {
+		return json.RawMessage("null"), nil
+	}
+	return json.Marshal(spec)
+}
+
+func encodeSpecs[T comparable](specs []T) ([]json.RawMessage, error) {
+	res := make([]json.RawMessage, 0, len(specs))
+	for _, spec := range specs {
+		buf, err := encodeSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, buf)
+	}
+	return res, nil
+}
+
+func decodeSpec[T any](decode SpecDecoder[T], data json.RawMessage) (T, error) {
+	var empty T
+	if len(data) == 0 || string(data) == "null" {
+		return empty, nil
+	}
+	return decode(data)
+}
+
+func decodeSpecs[T any](decode SpecDecoder[T], data []json.RawMessage) ([]T, error) {
+	res := make([]T, 0, len(data))
+	for _, buf := range data {
+		spec, err := decodeSpec(decode, buf)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, spec)
+	}
+	return res, nil
+}
+
+// Save writes plan as JSON, specs and ops are encoded with encoding/json.
+// Saved plan contains computed tasks, so loaded plan applies exactly
+// the same changes even if state has drifted since plan was made.
+func (p *Plan[T, K, O]) Save(w io.Writer) error {
+	var (
+		saved = savedPlan{Version: savedPlanVersion}
+		err   error
+	)
+	saved.Current, err = encodeSpecs(p.current)
+	if err != nil {
+		return fmt.Errorf("failed to encode current state: %w", err)
+	}
+	saved.Next, err = encodeSpecs(p.next)
+	if err != nil {
+		return fmt.Errorf("failed to encode next state: %w", err)
+	}
+
+	saved.Diff = make([]savedRecord, 0, len(p.diff))
+	for _, r := range p.diff {
+		var record savedRecord
+		record.Op, err = json.Marshal(r.Op)
+		if err != nil {
+			return fmt.Errorf("failed to encode op: %w", err)
+		}
+		record.Current, err = encodeSpec(r.Current)
+		if err != nil {
+			return fmt.Errorf("failed to encode current spec: %w", err)
+		}
+		record.Next, err = encodeSpec(r.Next)
+		if err != nil {
+			return fmt.Errorf("failed to encode next spec: %w", err)
+		}
+		saved.Diff = append(saved.Diff, record)
+	}
+
+	return json.NewEncoder(w).Encode(saved)
+}
+
+// LoadPlan reads plan written by Plan.Save, tasks are restored from saved plan as is.
+func LoadPlan[T Spec[K, T], K comparable, O Ops[O]](r io.Reader, opsEnum O, decode SpecDecoder[T]) (*Plan[T, K, O], error) {
+	var saved savedPlan
+	err := json.NewDecoder(r).Decode(&saved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode plan: %w", err)
+	}
+	if saved.Version != savedPlanVersion {
+		return nil, fmt.Errorf("unsupported plan version %d, expected %d", saved.Version, savedPlanVersion)
+	}
+
+	p := &Plan[T, K, O]{
+		opsEnum:    opsEnum,
+		tasksByOp:  TaskGroups[T, K, O]{},
+		tasksIndex: TaskIndex[T, K, O]{},
+		stat:       Stat[O]{},
+	}
+	p.current, err = decodeSpecs(decode, saved.Current)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode current state: %w", err)
+	}
+	p.next, err = decodeSpecs(decode, saved.Next)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode next state: %w", err)
+	}
+
+	var empty T
+	for n, record := range saved.Diff {
+		var op O
+		err = json.Unmarshal(record.Op, &op)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode op of record %d: %w", n, err)
+		}
+		current, err := decodeSpec(decode, record.Current)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode current spec of record %d: %w", n, err)
+		}
+		next, err := decodeSpec(decode, record.Next)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode next spec of record %d: %w", n, err)
+		}
+
+		var id K
+		switch {
+		case next != empty:
+			id = next.Identify()
+		case current != empty:
+			id = current.Identify()
+		default:
+			return nil, fmt.Errorf("record %d has no specs", n)
+		}
+		p.push(op, id, current, next)
+	}
+
+	return p, nil
+}
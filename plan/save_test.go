@@ -0,0 +1,60 @@
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeResource(data []byte) (resource, error) {
+	var r resource
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+func TestPlanSave(t *testing.T) {
+	current := []resource{
+		{ID: "a", Name: "alpha", Size: 1},
+		{ID: "b", Name: "beta", Size: 2},
+		{ID: "c", Name: "gamma", Size: 3},
+	}
+	next := []resource{
+		{ID: "a", Name: "alpha", Size: 1},
+		{ID: "b", Name: "delta", Size: 4},
+		{ID: "d", Name: "epsilon", Size: 5},
+	}
+	p := New(resourceOpsEnum, current, next)
+
+	var buf bytes.Buffer
+	require.NoError(t, p.Save(&buf))
+
+	loaded, err := LoadPlan[resource, string](&buf, resourceOpsEnum, decodeResource)
+	require.NoError(t, err)
+
+	assert.Equal(t, p.Tasks().String(), loaded.Tasks().String())
+	changes, stat := p.Stat()
+	loadedChanges, loadedStat := loaded.Stat()
+	assert.Equal(t, changes, loadedChanges)
+	assert.Equal(t, stat, loadedStat)
+	assert.Equal(t, p.Current(), loaded.Current())
+	assert.Equal(t, p.Next(), loaded.Next())
+	assert.Equal(t, p.Diff(), loaded.Diff())
+
+	for _, task := range p.Tasks() {
+		loadedTask, ok := loaded.Task(task.ID)
+		require.True(t, ok)
+		assert.Equal(t, task.Op, loadedTask.Op)
+		assert.Equal(t, task.Spec, loadedTask.Spec)
+		assert.Equal(t, task.Current, loadedTask.Current)
+		assert.Equal(t, task.Next, loadedTask.Next)
+		assert.Same(t, loaded, loadedTask.Plan)
+	}
+
+	t.Run("rejects unknown version", func(t *testing.T) {
+		_, err := LoadPlan[resource, string](bytes.NewBufferString(`{"version":0}`), resourceOpsEnum, decodeResource)
+		assert.ErrorContains(t, err, "unsupported plan version")
+	})
+}
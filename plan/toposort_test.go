@@ -0,0 +1,107 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testGraph = Graph[resource, string, resourceOps]
+
+// newWideGraph creates graph where single root provides every other task.
+func newWideGraph(n int) *testGraph {
+	g := &testGraph{
+		tasks:    make(Tasks[resource, string, resourceOps], n),
+		adj:      make([]map[int]void, n),
+		indegree: make([]int, n),
+		pos:      make([]int, n),
+	}
+	g.adj[0] = map[int]void{}
+	for i := range n {
+		id := fmt.Sprintf("r%06d", i)
+		g.tasks[i] = &Task[resource, string, resourceOps]{
+			ID:   id,
+			Op:   resourceOpsEnum.Create(),
+			Spec: resource{ID: id},
+		}
+		g.pos[i] = i
+		if i > 0 {
+			g.adj[0][i] = void{}
+			g.indegree[i] = 1
+		}
+	}
+	return g
+}
+
+// toposortSorted is a previous Toposort implementation which re-sorts ready set on each insert.
+func toposortSorted(g *testGraph) Tasks[resource, string, resourceOps] {
+	indegree := make([]int, len(g.indegree))
+	copy(indegree, g.indegree)
+
+	var ready []int
+	for i := range g.tasks {
+		if indegree[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	out := make(Tasks[resource, string, resourceOps], 0, len(g.tasks))
+	for len(ready) > 0 {
+		curr := ready[0]
+		ready = ready[1:]
+		out = append(out, g.tasks[curr])
+		for next := range g.adj[curr] {
+			indegree[next]--
+			if indegree[next] == 0 {
+				ready = append(ready, next)
+				sort.Slice(ready, func(i, j int) bool {
+					return g.pos[ready[i]] < g.pos[ready[j]]
+				})
+			}
+		}
+	}
+	return out
+}
+
+func TestToposortContext(t *testing.T) {
+	g := newWideGraph(10000)
+
+	t.Run("keeps plan order", func(t *testing.T) {
+		out, err := g.ToposortContext(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, toposortSorted(newWideGraph(100)), out[:100])
+
+		again, err := g.Toposort()
+		assert.NoError(t, err)
+		assert.Equal(t, out, again, "toposort should not modify graph")
+	})
+
+	t.Run("stops on cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		started := time.Now()
+		out, err := g.ToposortContext(ctx)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, out)
+		assert.Less(t, time.Since(started), 100*time.Millisecond)
+	})
+}
+
+func BenchmarkToposort(b *testing.B) {
+	g := newWideGraph(2000)
+
+	b.Run("sorted", func(b *testing.B) {
+		for range b.N {
+			toposortSorted(g)
+		}
+	})
+	b.Run("heap", func(b *testing.B) {
+		for range b.N {
+			_, _ = g.Toposort()
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"git.tatikoma.dev/corpix/atlas/seq"
+)
+
+type (
+	// Cache runs workloads on the pool and caches successful results by key for TTL.
+	// Concurrent calls with the same key share single workload execution,
+	// which is canceled only when all waiting callers are gone.
+	Cache struct {
+		pool    *Pool
+		results *seq.LRU[string, Result]
+		calls   map[string]*cacheCall
+		gen     uint64
+		mu      sync.Mutex
+	}
+	cacheCall struct {
+		done    chan void
+		cancel  context.CancelFunc
+		result  Result
+		gen     uint64
+		waiters int
+	}
+)
+
+func (c *Cache) RunContext(ctx context.Context, key string, fn Workload) (any, error) {
+	c.mu.Lock()
+	if r, ok := c.results.Get(key); ok {
+		c.mu.Unlock()
+		return r.Val, r.Err
+	}
+	call, ok := c.calls[key]
+	if !ok {
+		c.gen++
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &cacheCall{done: make(chan void), cancel: cancel, gen: c.gen}
+		c.calls[key] = call
+		go c.run(callCtx, key, call, fn)
+	}
+	call.waiters++
+	c.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		c.leave(key, call)
+		return nil, ctx.Err()
+	case <-call.done:
+		return call.result.Val, call.result.Err
+	}
+}
+
+// leave cancels shared call once the last waiter has gone.
+func (c *Cache) leave(key string, call *cacheCall) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	call.waiters--
+	if call.waiters > 0 {
+		return
+	}
+	call.cancel()
+	if current, ok := c.calls[key]; ok && current.gen == call.gen {
+		delete(c.calls, key)
+	}
+}
+
+func (c *Cache) run(ctx context.Context, key string, call *cacheCall, fn Workload) {
+	defer call.cancel()
+	val, err := c.pool.RunContext(ctx, fn)
+	call.result = Result{Val: val, Err: err}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// call is detached from calls by Forget or when all waiters left,
+	// result of such call is stale and should not be cached
+	if current, ok := c.calls[key]; ok && current.gen == call.gen {
+		if err == nil {
+			c.results.Add(key, call.result)
+		}
+		delete(c.calls, key)
+	}
+	close(call.done)
+}
+
+func (c *Cache) Run(key string, fn Workload) (any, error) {
+	return c.RunContext(context.Background(), key, fn)
+}
+
+// Forget removes cached result for key, result of in-flight call for key is not cached.
+func (c *Cache) Forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results.Remove(key)
+	delete(c.calls, key)
+}
+
+// NewCache creates cache holding at most size results for ttl.
+func NewCache(p *Pool, size int, ttl time.Duration) *Cache {
+	return &Cache{
+		pool:    p,
+		results: seq.NewLRU[string, Result](size, ttl),
+		calls:   map[string]*cacheCall{},
+	}
+}
@@ -0,0 +1,189 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheDeduplicatesCalls(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Size = 4
+	p := New(cfg)
+	defer p.Close()
+	c := NewCache(p, 16, time.Minute)
+
+	var calls atomic.Int32
+	release := make(chan void)
+	fn := func(ctx context.Context) (any, error) {
+		calls.Add(1)
+		<-release
+		return "value", nil
+	}
+
+	numCallers := 10
+	var wg sync.WaitGroup
+	wg.Add(numCallers)
+	errCh := make(chan error, numCallers)
+	for range numCallers {
+		go func() {
+			defer wg.Done()
+			val, err := c.Run("key", fn)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			if val != "value" {
+				errCh <- errors.New("unexpected value")
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errCh)
+	for err := range errCh {
+		t.Error(err)
+	}
+
+	if _, err := c.Run("key", fn); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected workload to run once, got %d", n)
+	}
+}
+
+func TestCacheExpiresAndSkipsErrors(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Size = 1
+	p := New(cfg)
+	defer p.Close()
+	c := NewCache(p, 16, 20*time.Millisecond)
+
+	var calls atomic.Int32
+	expectedErr := errors.New("job failed")
+	fn := func(ctx context.Context) (any, error) {
+		if calls.Add(1) == 1 {
+			return nil, expectedErr
+		}
+		return "value", nil
+	}
+
+	if _, err := c.Run("key", fn); !errors.Is(err, expectedErr) {
+		t.Errorf("expected error %v, got %v", expectedErr, err)
+	}
+	if _, err := c.Run("key", fn); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := c.Run("key", fn); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected error to not be cached, got %d calls", n)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, err := c.Run("key", fn); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("expected expired result to be recomputed, got %d calls", n)
+	}
+}
+
+func TestCacheWaiterCancellation(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Size = 2
+	p := New(cfg)
+	defer p.Close()
+	c := NewCache(p, 16, time.Minute)
+
+	started := make(chan void)
+	release := make(chan void)
+	fn := func(ctx context.Context) (any, error) {
+		close(started)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-release:
+			return "value", nil
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := c.RunContext(ctx, "key", fn)
+		firstErr <- err
+	}()
+	<-started
+
+	second := make(chan any, 1)
+	go func() {
+		val, err := c.Run("key", fn)
+		if err != nil {
+			second <- err
+			return
+		}
+		second <- val
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected first caller to be canceled, got %v", err)
+	}
+	close(release)
+	if val := <-second; val != "value" {
+		t.Errorf("expected remaining waiter to get value, got %v", val)
+	}
+}
+
+func TestCacheForgetInFlight(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Size = 2
+	p := New(cfg)
+	defer p.Close()
+	c := NewCache(p, 16, time.Minute)
+
+	var calls atomic.Int32
+	started := make(chan void)
+	release := make(chan void)
+	stale := func(ctx context.Context) (any, error) {
+		calls.Add(1)
+		close(started)
+		<-release
+		return "stale", nil
+	}
+
+	done := make(chan void)
+	go func() {
+		defer close(done)
+		if val, _ := c.Run("key", stale); val != "stale" {
+			t.Errorf("expected in-flight caller to get its result, got %v", val)
+		}
+	}()
+	<-started
+	c.Forget("key")
+	close(release)
+	<-done
+
+	val, err := c.Run("key", func(ctx context.Context) (any, error) {
+		calls.Add(1)
+		return "fresh", nil
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if val != "fresh" {
+		t.Errorf("expected result of call started before Forget to be dropped, got %v", val)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected 2 calls, got %d", n)
+	}
+}
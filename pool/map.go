@@ -0,0 +1,38 @@
+package pool
+
+import (
+	"context"
+	"sync"
+)
+
+// Map applies fn to each item concurrently on the pool, results keep items order.
+// First error cancels remaining jobs and is returned.
+func Map[T any, R any](ctx context.Context, p *Pool, items []T, fn func(ctx context.Context, item T) (R, error)) ([]R, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var (
+		results = make([]R, len(items))
+		wg      sync.WaitGroup
+	)
+	wg.Add(len(items))
+	for n, item := range items {
+		go func() {
+			defer wg.Done()
+			val, err := p.RunContext(ctx, func(ctx context.Context) (any, error) {
+				return fn(ctx, item)
+			})
+			if err != nil {
+				cancel(err)
+				return
+			}
+			results[n], _ = val.(R)
+		}()
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, context.Cause(ctx)
+	}
+	return results, nil
+}
@@ -0,0 +1,98 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMapPreservesOrder(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Size = 4
+	p := New(cfg)
+	defer p.Close()
+
+	items := []int{5, 1, 4, 2, 3}
+	res, err := Map(context.Background(), p, items, func(ctx context.Context, item int) (int, error) {
+		time.Sleep(time.Duration(item) * time.Millisecond)
+		return item * 10, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []int{50, 10, 40, 20, 30}
+	for n := range expected {
+		if res[n] != expected[n] {
+			t.Errorf("expected %v, got %v", expected, res)
+			break
+		}
+	}
+}
+
+func TestMapErrorCancelsRemaining(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Size = 4
+	p := New(cfg)
+	defer p.Close()
+
+	expectedErr := errors.New("item failed")
+	var (
+		canceled atomic.Int32
+		running  sync.WaitGroup
+	)
+	items := []int{0, 1, 2, 3}
+	running.Add(len(items) - 1)
+	started := time.Now()
+	_, err := Map(context.Background(), p, items, func(ctx context.Context, item int) (any, error) {
+		if item == 0 {
+			running.Wait()
+			return nil, expectedErr
+		}
+		running.Done()
+		select {
+		case <-ctx.Done():
+			canceled.Add(1)
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+			return nil, nil
+		}
+	})
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error %v, got %v", expectedErr, err)
+	}
+	if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+		t.Errorf("expected Map to fail fast, took %v", elapsed)
+	}
+
+	deadline := time.Now().Add(500 * time.Millisecond)
+	for canceled.Load() != 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := canceled.Load(); n != 3 {
+		t.Errorf("expected remaining items to be canceled, got %d", n)
+	}
+}
+
+func TestMapContextCancellation(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Size = 2
+	p := New(cfg)
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err := Map(ctx, p, []int{1, 2, 3}, func(ctx context.Context, item int) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled error, got %v", err)
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"runtime"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var (
@@ -18,20 +21,48 @@ var (
 
 type (
 	Pool struct {
-		closeCh chan void
-		jobs    chan *Job
-		wg      sync.WaitGroup
-		cfg     Config
+		closeCh   chan void
+		drainedCh chan void
+		jobs      chan *Job
+		queue     jobQueue
+		// slots limits number of queued jobs, ready signals workers about queued job.
+		slots    chan void
+		ready    chan void
+		wg       sync.WaitGroup
+		cfg      Config
+		inFlight atomic.Int64
+
+		// workers is a number of running workers, it exceeds cfg.Size until surplus workers exit after shrink.
+		workers int
+		closed  bool
+		// resizeCh is closed and replaced on shrink to wake idle workers.
+		resizeCh chan void
+		sizeMu   sync.Mutex
+
+		// pending is a number of jobs submitted with RunContext which are not finished yet.
+		pending  int
+		draining bool
+		mu       sync.Mutex
 	}
 
 	Config struct {
 		Size    int
 		Backlog int
+		// DefaultJobTimeout limits time of jobs submitted with Run* methods including time in the queue,
+		// caller context deadline wins if it is earlier, zero means no limit.
+		DefaultJobTimeout time.Duration
+		// OnPanic is called with value recovered from panicked job and stack of the panic,
+		// it is called even if job result is dropped because job context is done.
+		OnPanic func(recovered any, stack []byte)
 	}
 	Job struct {
 		Ctx      context.Context
 		Fn       Workload
 		ResultCh chan Result
+
+		tracked  bool
+		priority int
+		seq      uint64
 	}
 	Workload func(ctx context.Context) (any, error)
 
@@ -43,14 +74,30 @@ type (
 	void = struct{}
 )
 
-func (p *Pool) workersRun() {
-	p.wg.Add(p.cfg.Size)
-	for range p.cfg.Size {
+func (p *Pool) workersRun(n int) {
+	p.workers += n
+	p.wg.Add(n)
+	for range n {
 		go p.worker()
 	}
 }
 
+// workerNext returns channel which wakes idle worker on resize,
+// exit is true if worker is surplus after shrink.
+func (p *Pool) workerNext() (resizeCh chan void, exit bool) {
+	p.sizeMu.Lock()
+	defer p.sizeMu.Unlock()
+	if p.workers > p.cfg.Size {
+		p.workers--
+		return nil, true
+	}
+	return p.resizeCh, false
+}
+
 func (p *Pool) workerRecovery(r any) error {
+	if p.cfg.OnPanic != nil {
+		p.cfg.OnPanic(r, debug.Stack())
+	}
 	switch v := r.(type) {
 	case error:
 		return v
@@ -62,21 +109,39 @@ func (p *Pool) workerRecovery(r any) error {
 func (p *Pool) worker() {
 	defer p.wg.Done()
 	for {
+		resizeCh, exit := p.workerNext()
+		if exit {
+			return
+		}
 		select {
 		case <-p.closeCh:
 			return
+		case <-resizeCh:
+		case <-p.ready:
+			job := p.queue.pop()
+			<-p.slots
+			p.workerRunJob(job)
+			if job.tracked {
+				p.release()
+			}
 		case job := <-p.jobs:
 			p.workerRunJob(job)
+			if job.tracked {
+				p.release()
+			}
 		}
 	}
 }
 
 func (p *Pool) workerRunJob(job *Job) {
+	p.inFlight.Add(1)
+	defer p.inFlight.Add(-1)
 	defer func() {
 		if r := recover(); r != nil {
+			err := p.workerRecovery(r)
 			select {
 			case <-job.Ctx.Done():
-			case job.ResultCh <- Result{Err: p.workerRecovery(r)}:
+			case job.ResultCh <- Result{Err: err}:
 			default:
 			}
 		}
@@ -99,14 +164,56 @@ func (p *Pool) JobWithContext(ctx context.Context, fn Workload) *Job {
 	}
 }
 
+// acquire accounts job which is about to be submitted, it fails once pool is draining.
+func (p *Pool) acquire() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.draining {
+		return false
+	}
+	p.pending++
+	return true
+}
+
+func (p *Pool) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending--
+	if p.draining && p.pending == 0 {
+		close(p.drainedCh)
+	}
+}
+
 func (p *Pool) RunContext(ctx context.Context, fn Workload) (any, error) {
+	return p.RunContextPriority(ctx, 0, fn)
+}
+
+// RunContextPriority runs fn once a worker is free, queued jobs of higher priority
+// are dispatched first. Backlog limits number of queued jobs of all priorities,
+// though single job is allowed to wait in the queue even if backlog is zero.
+// Jobs submitted to JobsCh bypass the queue.
+func (p *Pool) RunContextPriority(ctx context.Context, priority int, fn Workload) (any, error) {
+	if !p.acquire() {
+		return nil, ErrClosing
+	}
+	if p.cfg.DefaultJobTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.cfg.DefaultJobTimeout)
+		defer cancel()
+	}
 	job := p.JobWithContext(ctx, fn)
+	job.tracked = true
+	job.priority = priority
 	select {
 	case <-ctx.Done():
+		p.release()
 		return nil, ctx.Err()
 	case <-p.closeCh:
+		p.release()
 		return nil, ErrClosing
-	case p.jobs <- job:
+	case p.slots <- void{}:
+		p.queue.push(job)
+		p.ready <- void{}
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
@@ -122,21 +229,102 @@ func (p *Pool) Run(fn Workload) (any, error) {
 	return p.RunContext(context.Background(), fn)
 }
 
-func (p *Pool) Size() int           { return p.cfg.Size }
-func (p *Pool) Backlog() int        { return p.cfg.Backlog }
+// RunTimeout runs fn with context which is canceled after d.
+func (p *Pool) RunTimeout(fn Workload, d time.Duration) (any, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return p.RunContext(ctx, fn)
+}
+
+func (p *Pool) Size() int {
+	p.sizeMu.Lock()
+	defer p.sizeMu.Unlock()
+	return p.cfg.Size
+}
+
+func (p *Pool) Backlog() int        { return cap(p.jobs) }
 func (p *Pool) JobsCh() chan<- *Job { return p.jobs }
 
+// Resize changes number of workers, queued jobs are kept. On shrink surplus workers
+// exit once they finish current job, running jobs are never interrupted.
+func (p *Pool) Resize(n int) error {
+	if n < 1 {
+		return fmt.Errorf("pool size should be positive, got %d", n)
+	}
+	p.sizeMu.Lock()
+	defer p.sizeMu.Unlock()
+	if p.closed {
+		return ErrClosing
+	}
+
+	p.cfg.Size = n
+	if n > p.workers {
+		p.workersRun(n - p.workers)
+		return nil
+	}
+	close(p.resizeCh)
+	p.resizeCh = make(chan void)
+	return nil
+}
+
+// InFlight returns number of jobs which are executing right now.
+func (p *Pool) InFlight() int { return int(p.inFlight.Load()) }
+
+// Queued returns number of jobs waiting in the backlog for a free worker.
+func (p *Pool) Queued() int { return len(p.jobs) + p.queue.size() }
+
+// Close stops workers, jobs left in the backlog are dropped, see Drain.
 func (p *Pool) Close() {
-	close(p.closeCh)
+	p.sizeMu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.closeCh)
+	}
+	p.sizeMu.Unlock()
 	p.wg.Wait()
 }
 
+// Drain stops accepting new jobs and waits until jobs submitted with Run or RunContext
+// are finished, then closes the pool. Jobs submitted directly to JobsCh are not waited for.
+// If ctx is done first its error is returned and the pool keeps running jobs,
+// so Drain could be retried or Close could be called to drop the rest.
+func (p *Pool) Drain(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.draining {
+		p.draining = true
+		if p.pending == 0 {
+			close(p.drainedCh)
+		}
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.drainedCh:
+		p.Close()
+		return nil
+	default:
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.closeCh:
+		return ErrClosing
+	case <-p.drainedCh:
+		p.Close()
+		return nil
+	}
+}
+
 func New(c Config) *Pool {
 	p := &Pool{
-		cfg:     c,
-		closeCh: make(chan void),
-		jobs:    make(chan *Job, c.Backlog),
+		cfg:       c,
+		closeCh:   make(chan void),
+		drainedCh: make(chan void),
+		jobs:      make(chan *Job, c.Backlog),
+		slots:     make(chan void, max(c.Backlog, 1)),
+		ready:     make(chan void, max(c.Backlog, 1)),
+		resizeCh:  make(chan void),
 	}
-	p.workersRun()
+	p.workersRun(c.Size)
 	return p
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -108,6 +110,40 @@ func TestPoolPanicRecovery(t *testing.T) {
 	}
 }
 
+func TestPoolOnPanic(t *testing.T) {
+	type panicEvent struct {
+		recovered any
+		stack     []byte
+	}
+	events := make(chan panicEvent, 1)
+	cfg := DefaultConfig
+	cfg.Size = 1
+	cfg.OnPanic = func(recovered any, stack []byte) {
+		events <- panicEvent{recovered, stack}
+	}
+	p := New(cfg)
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := p.JobWithContext(ctx, func(ctx context.Context) (any, error) {
+		cancel()
+		panic("intentional panic")
+	})
+	p.JobsCh() <- job
+
+	select {
+	case ev := <-events:
+		if ev.recovered != "intentional panic" {
+			t.Errorf("expected recovered panic value, got %v", ev.recovered)
+		}
+		if !strings.Contains(string(ev.stack), "TestPoolOnPanic") {
+			t.Errorf("expected stack of panicked job, got %s", ev.stack)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("panic hook was not called for canceled job")
+	}
+}
+
 func TestPoolClosePreventsNewJobs(t *testing.T) {
 	cfg := DefaultConfig
 	cfg.Size = 1
@@ -268,3 +304,392 @@ func TestPoolJobsChCancellationPreventsCompletionSignal(t *testing.T) {
 
 	time.Sleep(250 * time.Millisecond)
 }
+
+func TestPoolDrain(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Size = 1
+	cfg.Backlog = 4
+	p := New(cfg)
+	defer p.Close()
+
+	var releaseOnce sync.Once
+	release := make(chan void)
+	defer releaseOnce.Do(func() { close(release) })
+	submit := func(fn Workload) chan Result {
+		resultCh := make(chan Result, 1)
+		go func() {
+			val, err := p.Run(fn)
+			resultCh <- Result{Val: val, Err: err}
+		}()
+		return resultCh
+	}
+	awaitPending := func(n int) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for {
+			p.mu.Lock()
+			pending := p.pending
+			p.mu.Unlock()
+			if pending == n {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d pending jobs, got %d", n, pending)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	started := make(chan void)
+	results := []chan Result{submit(func(ctx context.Context) (any, error) {
+		close(started)
+		<-release
+		return 0, nil
+	})}
+	<-started
+	results = append(results, submit(func(ctx context.Context) (any, error) {
+		panic("intentional panic")
+	}))
+	for n := 2; n < 4; n++ {
+		results = append(results, submit(func(ctx context.Context) (any, error) {
+			return n, nil
+		}))
+	}
+	awaitPending(4)
+
+	t.Run("deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if err := p.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected deadline exceeded error, got %v", err)
+		}
+	})
+
+	if _, err := p.Run(func(ctx context.Context) (any, error) { return nil, nil }); !errors.Is(err, ErrClosing) {
+		t.Errorf("expected ErrClosing error for new job, got %v", err)
+	}
+
+	drained := make(chan error, 1)
+	go func() { drained <- p.Drain(context.Background()) }()
+	releaseOnce.Do(func() { close(release) })
+
+	select {
+	case err := <-drained:
+		if err != nil {
+			t.Errorf("unexpected drain error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("drain did not finish")
+	}
+
+	for n, resultCh := range results {
+		r := <-resultCh
+		switch n {
+		case 1:
+			if r.Err == nil || r.Err.Error() != "intentional panic" {
+				t.Errorf("expected panic error, got %v", r.Err)
+			}
+		default:
+			if r.Err != nil || r.Val != n {
+				t.Errorf("expected job %d to finish, got %v, %v", n, r.Val, r.Err)
+			}
+		}
+	}
+}
+
+func TestPoolDrainIdle(t *testing.T) {
+	p := New(DefaultConfig)
+	defer p.Close()
+
+	if err := p.Drain(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := p.Drain(context.Background()); err != nil {
+		t.Errorf("unexpected error on repeated drain: %v", err)
+	}
+	if _, err := p.Run(func(ctx context.Context) (any, error) { return nil, nil }); !errors.Is(err, ErrClosing) {
+		t.Errorf("expected ErrClosing error, got %v", err)
+	}
+}
+
+func TestPoolMetrics(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Size = 4
+	cfg.Backlog = 8
+	p := New(cfg)
+	defer p.Close()
+
+	if p.Backlog() != cfg.Backlog {
+		t.Errorf("expected backlog %d, got %d", cfg.Backlog, p.Backlog())
+	}
+
+	var (
+		wg       sync.WaitGroup
+		exceeded atomic.Int32
+	)
+	check := func() {
+		if n := p.InFlight(); n > cfg.Size || n < 0 {
+			exceeded.Add(1)
+		}
+		if n := p.Queued(); n > cfg.Backlog {
+			exceeded.Add(1)
+		}
+	}
+	for n := range 200 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := context.Background()
+			if n%3 == 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, time.Millisecond)
+				defer cancel()
+			}
+			_, _ = p.RunContext(ctx, func(ctx context.Context) (any, error) {
+				check()
+				if n%7 == 0 {
+					panic("intentional panic")
+				}
+				time.Sleep(time.Millisecond)
+				return nil, nil
+			})
+			check()
+		}()
+	}
+	wg.Wait()
+
+	if n := exceeded.Load(); n > 0 {
+		t.Errorf("metrics were out of bounds %d times", n)
+	}
+	deadline := time.Now().Add(time.Second)
+	for (p.InFlight() != 0 || p.Queued() != 0) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if p.InFlight() != 0 || p.Queued() != 0 {
+		t.Errorf("expected idle pool, got %d in flight and %d queued", p.InFlight(), p.Queued())
+	}
+}
+
+func TestPoolPriority(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Size = 1
+	cfg.Backlog = 4
+	p := New(cfg)
+	defer p.Close()
+
+	started, release := make(chan void), make(chan void)
+	go func() {
+		_, _ = p.Run(func(ctx context.Context) (any, error) {
+			close(started)
+			<-release
+			return nil, nil
+		})
+	}()
+	<-started
+
+	var (
+		order []string
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+	)
+	submit := func(name string, priority int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := p.RunContextPriority(context.Background(), priority, func(ctx context.Context) (any, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				order = append(order, name)
+				return nil, nil
+			})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	awaitQueued := func(n int) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for p.Queued() != n {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d queued jobs, got %d", n, p.Queued())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	for n, name := range []string{"low1", "low2", "low3"} {
+		submit(name, 0)
+		awaitQueued(n + 1)
+	}
+	submit("high", 10)
+	awaitQueued(4)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.RunContextPriority(ctx, 100, func(ctx context.Context) (any, error) { return nil, nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected full backlog to block job of any priority, got %v", err)
+	}
+
+	close(release)
+	wg.Wait()
+
+	expected := []string{"high", "low1", "low2", "low3"}
+	if fmt.Sprint(order) != fmt.Sprint(expected) {
+		t.Errorf("expected order %v, got %v", expected, order)
+	}
+}
+
+func TestPoolJobTimeout(t *testing.T) {
+	waitCtx := func(ctx context.Context) (any, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+			return "should not return", nil
+		}
+	}
+	expectDeadline := func(t *testing.T, run func() (any, error)) {
+		t.Helper()
+		started := time.Now()
+		_, err := run()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected deadline exceeded error, got %v", err)
+		}
+		if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+			t.Errorf("expected job to be canceled by the earliest deadline, took %v", elapsed)
+		}
+	}
+
+	t.Run("default", func(t *testing.T) {
+		cfg := DefaultConfig
+		cfg.DefaultJobTimeout = 20 * time.Millisecond
+		p := New(cfg)
+		defer p.Close()
+
+		expectDeadline(t, func() (any, error) { return p.Run(waitCtx) })
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+		defer cancel()
+		expectDeadline(t, func() (any, error) { return p.RunContext(ctx, waitCtx) })
+	})
+
+	t.Run("caller deadline is earlier", func(t *testing.T) {
+		cfg := DefaultConfig
+		cfg.DefaultJobTimeout = time.Hour
+		p := New(cfg)
+		defer p.Close()
+
+		expectDeadline(t, func() (any, error) { return p.RunTimeout(waitCtx, 20*time.Millisecond) })
+	})
+
+	t.Run("run timeout", func(t *testing.T) {
+		p := New(DefaultConfig)
+		defer p.Close()
+
+		expectDeadline(t, func() (any, error) { return p.RunTimeout(waitCtx, 20*time.Millisecond) })
+		val, err := p.RunTimeout(func(ctx context.Context) (any, error) { return "done", nil }, time.Second)
+		if err != nil || val != "done" {
+			t.Errorf("expected job to finish, got %v, %v", val, err)
+		}
+	})
+}
+
+func TestPoolResize(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Size = 2
+	cfg.Backlog = 16
+	p := New(cfg)
+	defer p.Close()
+
+	const jobs = 200
+	var (
+		runs    [jobs]atomic.Int32
+		running atomic.Int32
+		peak    atomic.Int32
+		wg      sync.WaitGroup
+	)
+	wg.Add(jobs)
+	for n := range jobs {
+		go func() {
+			defer wg.Done()
+			val, err := p.Run(func(ctx context.Context) (any, error) {
+				cur := running.Add(1)
+				defer running.Add(-1)
+				for {
+					prev := peak.Load()
+					if cur <= prev || peak.CompareAndSwap(prev, cur) {
+						break
+					}
+				}
+				runs[n].Add(1)
+				time.Sleep(time.Millisecond)
+				return n, nil
+			})
+			if err != nil || val != n {
+				t.Errorf("expected job %d to finish, got %v, %v", n, val, err)
+			}
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := p.Resize(8); err != nil {
+		t.Fatalf("unexpected grow error: %v", err)
+	}
+	if p.Size() != 8 {
+		t.Errorf("expected size 8, got %d", p.Size())
+	}
+	time.Sleep(20 * time.Millisecond)
+	if err := p.Resize(1); err != nil {
+		t.Fatalf("unexpected shrink error: %v", err)
+	}
+	wg.Wait()
+
+	for n := range runs {
+		if r := runs[n].Load(); r != 1 {
+			t.Errorf("expected job %d to run once, ran %d times", n, r)
+		}
+	}
+	if peak.Load() <= 2 {
+		t.Errorf("expected more than 2 concurrent jobs after grow, got %d", peak.Load())
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		p.sizeMu.Lock()
+		workers := p.workers
+		p.sizeMu.Unlock()
+		if workers == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected surplus workers to exit, %d left", workers)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := p.Resize(0); err == nil {
+		t.Error("expected error for zero size")
+	}
+}
+
+func TestPoolResizeClose(t *testing.T) {
+	p := New(DefaultConfig)
+
+	var wg sync.WaitGroup
+	for n := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.Resize(n%4 + 1); err != nil && !errors.Is(err, ErrClosing) {
+				t.Errorf("unexpected resize error: %v", err)
+			}
+		}()
+	}
+	p.Close()
+	wg.Wait()
+
+	if err := p.Resize(4); !errors.Is(err, ErrClosing) {
+		t.Errorf("expected ErrClosing error, got %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package pool
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// jobQueue orders jobs by priority, jobs of the same priority are kept in submission order.
+type jobQueue struct {
+	jobs []*Job
+	seq  uint64
+	mu   sync.Mutex
+}
+
+func (q *jobQueue) Push(x any) { q.jobs = append(q.jobs, x.(*Job)) }
+func (q *jobQueue) Pop() any {
+	n := len(q.jobs) - 1
+	job := q.jobs[n]
+	q.jobs[n] = nil
+	q.jobs = q.jobs[:n]
+	return job
+}
+func (q *jobQueue) Len() int      { return len(q.jobs) }
+func (q *jobQueue) Swap(i, j int) { q.jobs[i], q.jobs[j] = q.jobs[j], q.jobs[i] }
+func (q *jobQueue) Less(i, j int) bool {
+	if q.jobs[i].priority != q.jobs[j].priority {
+		return q.jobs[i].priority > q.jobs[j].priority
+	}
+	return q.jobs[i].seq < q.jobs[j].seq
+}
+
+func (q *jobQueue) push(job *Job) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.seq++
+	job.seq = q.seq
+	heap.Push(q, job)
+}
+
+func (q *jobQueue) pop() *Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return heap.Pop(q).(*Job)
+}
+
+func (q *jobQueue) size() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs)
+}
@@ -0,0 +1,62 @@
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	// TypedPool runs workloads returning T on the underlying Pool, so callers do not assert results.
+	TypedPool[T any] struct {
+		pool *Pool
+	}
+	TypedWorkload[T any] func(ctx context.Context) (T, error)
+)
+
+// NewTyped creates pool with its own workers.
+func NewTyped[T any](c Config) *TypedPool[T] {
+	return Typed[T](New(c))
+}
+
+// Typed returns typed view of p sharing its workers and backlog with other users of p.
+func Typed[T any](p *Pool) *TypedPool[T] {
+	return &TypedPool[T]{pool: p}
+}
+
+func (w TypedWorkload[T]) workload() Workload {
+	return func(ctx context.Context) (any, error) {
+		return w(ctx)
+	}
+}
+
+// result converts untyped result, zero T is returned on error, e.g. if workload panicked.
+func (p *TypedPool[T]) result(val any, err error) (T, error) {
+	var empty T
+	if err != nil {
+		return empty, err
+	}
+	res, _ := val.(T)
+	return res, nil
+}
+
+func (p *TypedPool[T]) RunContext(ctx context.Context, fn TypedWorkload[T]) (T, error) {
+	return p.result(p.pool.RunContext(ctx, fn.workload()))
+}
+
+func (p *TypedPool[T]) RunContextPriority(ctx context.Context, priority int, fn TypedWorkload[T]) (T, error) {
+	return p.result(p.pool.RunContextPriority(ctx, priority, fn.workload()))
+}
+
+func (p *TypedPool[T]) RunTimeout(fn TypedWorkload[T], d time.Duration) (T, error) {
+	return p.result(p.pool.RunTimeout(fn.workload(), d))
+}
+
+func (p *TypedPool[T]) Run(fn TypedWorkload[T]) (T, error) {
+	return p.result(p.pool.Run(fn.workload()))
+}
+
+// Pool returns underlying untyped pool.
+func (p *TypedPool[T]) Pool() *Pool { return p.pool }
+
+func (p *TypedPool[T]) Close()                          { p.pool.Close() }
+func (p *TypedPool[T]) Drain(ctx context.Context) error { return p.pool.Drain(ctx) }
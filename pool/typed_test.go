@@ -0,0 +1,70 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type typedResult struct {
+	ID   int
+	Name string
+}
+
+func TestTypedPool(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Size = 2
+	p := NewTyped[typedResult](cfg)
+	defer p.Close()
+
+	res, err := p.Run(func(ctx context.Context) (typedResult, error) {
+		return typedResult{ID: 1, Name: "one"}, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Name != "one" {
+		t.Errorf("expected result name %q, got %q", "one", res.Name)
+	}
+
+	expectedErr := errors.New("job failed")
+	res, err = p.RunContext(context.Background(), func(ctx context.Context) (typedResult, error) {
+		return typedResult{ID: 2}, expectedErr
+	})
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error %v, got %v", expectedErr, err)
+	}
+	if res != (typedResult{}) {
+		t.Errorf("expected zero result on error, got %+v", res)
+	}
+
+	res, err = p.Run(func(ctx context.Context) (typedResult, error) {
+		panic("intentional panic")
+	})
+	if err == nil || err.Error() != "intentional panic" {
+		t.Errorf("expected panic error, got %v", err)
+	}
+	if res != (typedResult{}) {
+		t.Errorf("expected zero result on panic, got %+v", res)
+	}
+}
+
+func TestTypedPoolSharesWorkers(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Size = 1
+	p := New(cfg)
+	defer p.Close()
+
+	ints, strs := Typed[int](p), Typed[string](p)
+	n, err := ints.Run(func(ctx context.Context) (int, error) { return 42, nil })
+	if err != nil || n != 42 {
+		t.Errorf("expected 42, got %v, %v", n, err)
+	}
+	s, err := strs.Run(func(ctx context.Context) (string, error) { return "42", nil })
+	if err != nil || s != "42" {
+		t.Errorf("expected %q, got %q, %v", "42", s, err)
+	}
+	if ints.Pool() != p || strs.Pool() != p {
+		t.Error("expected typed pools to share underlying pool")
+	}
+}
@@ -11,6 +11,8 @@ import (
 var (
 	ErrNoRows      = pgx.ErrNoRows
 	ErrTooManyRows = pgx.ErrTooManyRows
+
+	ErrUnknownQuery = errors.New("unknown query")
 )
 
 func ErrIsNoRows(err error) bool {
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	pgx "github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"git.tatikoma.dev/corpix/atlas/errors"
+	"git.tatikoma.dev/corpix/atlas/log"
+)
+
+const DefaultListenerReconnectDelay = time.Second
+
+type (
+	Notification = pgconn.Notification
+
+	// ListenerConn is a dedicated connection used by Listener.
+	ListenerConn interface {
+		Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+		WaitForNotification(ctx context.Context) (*Notification, error)
+		Release()
+	}
+
+	// Listener delivers postgres notifications from LISTEN channels,
+	// Notifications channel may be used as a source for rpc.Stream.
+	Listener struct {
+		acquire        func(ctx context.Context) (ListenerConn, error)
+		notifications  chan *Notification
+		channels       []string
+		reconnectDelay time.Duration
+	}
+	ListenerOption func(*Listener)
+
+	listenerPoolConn struct {
+		*pgxpool.Conn
+	}
+)
+
+func WithListenerReconnectDelay(d time.Duration) ListenerOption {
+	return func(l *Listener) {
+		l.reconnectDelay = d
+	}
+}
+
+// WithListenerAcquire overrides the way dedicated connection is acquired.
+func WithListenerAcquire(acquire func(ctx context.Context) (ListenerConn, error)) ListenerOption {
+	return func(l *Listener) {
+		l.acquire = acquire
+	}
+}
+
+func (c listenerPoolConn) WaitForNotification(ctx context.Context) (*Notification, error) {
+	return c.Conn.Conn().WaitForNotification(ctx)
+}
+
+// Notifications returns channel with received notifications, it is closed when Run returns.
+func (l *Listener) Notifications() <-chan *Notification {
+	return l.notifications
+}
+
+// Run listens for notifications until ctx is done,
+// connection is re-acquired after reconnect delay when it is lost.
+func (l *Listener) Run(ctx context.Context) error {
+	defer close(l.notifications)
+
+	for {
+		err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		log.Ctx(ctx).Warn().
+			Err(err).
+			Strs("channels", l.channels).
+			Dur("delay", l.reconnectDelay).
+			Msg("listener connection lost, reconnecting")
+
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-time.After(l.reconnectDelay):
+		}
+	}
+}
+
+func (l *Listener) listen(ctx context.Context) error {
+	conn, err := l.acquire(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to acquire listener connection")
+	}
+	defer conn.Release()
+	defer func() {
+		// connection returns to the pool, so it should not receive notifications anymore
+		unlistenCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+		defer cancel()
+		_, _ = conn.Exec(unlistenCtx, "UNLISTEN *")
+	}()
+
+	for _, channel := range l.channels {
+		_, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize())
+		if err != nil {
+			return errors.Wrapf(err, "failed to listen on %q", channel)
+		}
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to wait for notification")
+		}
+		select {
+		case l.notifications <- notification:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// NewListener creates listener which acquires dedicated connection from pool
+// and issues LISTEN for each channel.
+func NewListener(pool *pgxpool.Pool, channels []string, opts ...ListenerOption) *Listener {
+	l := &Listener{
+		acquire: func(ctx context.Context) (ListenerConn, error) {
+			conn, err := pool.Acquire(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return listenerPoolConn{conn}, nil
+		},
+		notifications:  make(chan *Notification),
+		channels:       channels,
+		reconnectDelay: DefaultListenerReconnectDelay,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
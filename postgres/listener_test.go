@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.tatikoma.dev/corpix/atlas/errors"
+)
+
+type testListenerConn struct {
+	notifications chan *Notification
+	execs         []string
+	released      bool
+	mu            sync.Mutex
+}
+
+func (c *testListenerConn) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.execs = append(c.execs, sql)
+	return pgconn.CommandTag{}, nil
+}
+
+func (c *testListenerConn) WaitForNotification(ctx context.Context) (*Notification, error) {
+	select {
+	case n, ok := <-c.notifications:
+		if !ok {
+			return nil, errors.New("connection lost")
+		}
+		return n, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *testListenerConn) Release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.released = true
+}
+
+func TestListener(t *testing.T) {
+	conns := []*testListenerConn{
+		{notifications: make(chan *Notification, 1)},
+		{notifications: make(chan *Notification, 1)},
+	}
+	acquired := 0
+	l := NewListener(nil, []string{"events"},
+		WithListenerReconnectDelay(time.Millisecond),
+		WithListenerAcquire(func(context.Context) (ListenerConn, error) {
+			conn := conns[acquired]
+			acquired++
+			return conn, nil
+		}),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- l.Run(ctx) }()
+
+	receive := func(t *testing.T) *Notification {
+		t.Helper()
+		select {
+		case n := <-l.Notifications():
+			return n
+		case <-time.After(time.Second):
+			t.Fatal("notification was not delivered")
+			return nil
+		}
+	}
+
+	conns[0].notifications <- &Notification{Channel: "events", Payload: "first"}
+	assert.Equal(t, "first", receive(t).Payload)
+
+	close(conns[0].notifications) // connection lost
+	conns[1].notifications <- &Notification{Channel: "events", Payload: "second"}
+	assert.Equal(t, "second", receive(t).Payload)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("listener did not stop")
+	}
+
+	_, ok := <-l.Notifications()
+	assert.False(t, ok)
+	for _, conn := range conns {
+		require.Equal(t, []string{`LISTEN "events"`, "UNLISTEN *"}, conn.execs)
+		assert.True(t, conn.released)
+	}
+}
@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"sync"
+
+	pgx "github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"git.tatikoma.dev/corpix/atlas/errors"
+)
+
+type (
+	// Querier is implemented by Pool and Tx.
+	Querier interface {
+		Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+		Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+		QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	}
+
+	// Queries is a registry of named SQL queries.
+	// Statement caching is handled by pgx itself.
+	Queries struct {
+		db      Querier
+		queries map[string]string
+		mu      sync.RWMutex
+	}
+
+	errRow struct {
+		err error
+	}
+)
+
+func (r errRow) Scan(...any) error {
+	return r.err
+}
+
+// Register adds named query, name should be unique.
+func (q *Queries) Register(name, query string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.queries[name]; ok {
+		return errors.Errorf("query %q is already registered", name)
+	}
+	q.queries[name] = query
+	return nil
+}
+
+// SQL returns query registered with name.
+func (q *Queries) SQL(name string) (string, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	query, ok := q.queries[name]
+	return query, ok
+}
+
+func (q *Queries) query(name string) (string, error) {
+	query, ok := q.SQL(name)
+	if !ok {
+		return "", errors.Errorf("%w: %q", ErrUnknownQuery, name)
+	}
+	return query, nil
+}
+
+// With returns registry which shares queries, but runs them on db (transaction, for example).
+func (q *Queries) With(db Querier) *Queries {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return NewQueries(db, q.queries)
+}
+
+func (q *Queries) Exec(ctx context.Context, name string, args ...any) (pgconn.CommandTag, error) {
+	query, err := q.query(name)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return q.db.Exec(ctx, query, args...)
+}
+
+func (q *Queries) Query(ctx context.Context, name string, args ...any) (pgx.Rows, error) {
+	query, err := q.query(name)
+	if err != nil {
+		return nil, err
+	}
+	return q.db.Query(ctx, query, args...)
+}
+
+// QueryRow runs named query, error about unknown query is returned on Scan.
+func (q *Queries) QueryRow(ctx context.Context, name string, args ...any) pgx.Row {
+	query, err := q.query(name)
+	if err != nil {
+		return errRow{err: err}
+	}
+	return q.db.QueryRow(ctx, query, args...)
+}
+
+func NewQueries(db Querier, queries map[string]string) *Queries {
+	q := &Queries{
+		db:      db,
+		queries: make(map[string]string, len(queries)),
+	}
+	for name, query := range queries {
+		q.queries[name] = query
+	}
+	return q
+}
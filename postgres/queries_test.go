@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueries(t *testing.T) {
+	ctx := context.Background()
+	mockPool, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockPool.Close()
+
+	queries := NewQueries(mockPool, map[string]string{
+		"insert": `INSERT INTO items (value) VALUES ($1)`,
+	})
+	require.NoError(t, queries.Register("get", `SELECT value FROM items WHERE id = $1`))
+	assert.Error(t, queries.Register("get", `SELECT 1`), "duplicate name should be rejected")
+
+	mockPool.ExpectExec("INSERT INTO items").
+		WithArgs("a").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	tag, err := queries.Exec(ctx, "insert", "a")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, tag.RowsAffected())
+
+	mockPool.ExpectQuery("SELECT value FROM items").
+		WithArgs(1).
+		WillReturnRows(pgxmock.NewRows([]string{"value"}).AddRow("a"))
+	var value string
+	require.NoError(t, queries.QueryRow(ctx, "get", 1).Scan(&value))
+	assert.Equal(t, "a", value)
+
+	mockPool.ExpectBegin()
+	mockPool.ExpectExec("INSERT INTO items").
+		WithArgs("b").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockPool.ExpectCommit()
+	_, err = WithTxContext(ctx, mockPool, func(tx Tx) (any, error) {
+		return queries.With(tx).Exec(ctx, "insert", "b")
+	})
+	require.NoError(t, err)
+
+	_, err = queries.Exec(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrUnknownQuery)
+	assert.ErrorIs(t, queries.QueryRow(ctx, "unknown").Scan(&value), ErrUnknownQuery)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
@@ -0,0 +1,28 @@
+package postgres
+
+import (
+	pgx "github.com/jackc/pgx/v5"
+)
+
+type (
+	Rows = pgx.Rows
+	Row  = pgx.CollectableRow
+)
+
+// CollectRows scans every row with fn and closes rows.
+func CollectRows[T any](rows Rows, fn pgx.RowToFunc[T]) ([]T, error) {
+	return pgx.CollectRows(rows, fn)
+}
+
+// CollectRow scans first row with fn and closes rows,
+// ErrNoRows is returned when there are no rows.
+func CollectRow[T any](rows Rows, fn pgx.RowToFunc[T]) (T, error) {
+	return pgx.CollectOneRow(rows, fn)
+}
+
+// ScanStruct scans row into struct T matching columns to fields by `db` tag
+// or by field name (case-insensitive) when tag is absent, fields tagged `db:"-"` are ignored.
+// Every column must have matching field.
+func ScanStruct[T any](row Row) (T, error) {
+	return pgx.RowToStructByName[T](row)
+}
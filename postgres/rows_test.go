@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testItem struct {
+	ID    int64  `db:"id"`
+	Value string `db:"value"`
+}
+
+func TestCollectRows(t *testing.T) {
+	ctx := context.Background()
+	mockPool, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockPool.Close()
+
+	t.Run("collects structs", func(t *testing.T) {
+		mockPool.ExpectQuery("SELECT id, value FROM items").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "value"}).
+				AddRow(int64(1), "a").
+				AddRow(int64(2), "b"))
+
+		rows, err := mockPool.Query(ctx, "SELECT id, value FROM items")
+		require.NoError(t, err)
+		items, err := CollectRows(rows, ScanStruct[testItem])
+		require.NoError(t, err)
+		assert.Equal(t, []testItem{{ID: 1, Value: "a"}, {ID: 2, Value: "b"}}, items)
+	})
+
+	t.Run("collects with custom func", func(t *testing.T) {
+		mockPool.ExpectQuery("SELECT value FROM items").
+			WillReturnRows(pgxmock.NewRows([]string{"value"}).
+				AddRow("a").
+				AddRow("b"))
+
+		rows, err := mockPool.Query(ctx, "SELECT value FROM items")
+		require.NoError(t, err)
+		values, err := CollectRows(rows, func(row Row) (string, error) {
+			var v string
+			err := row.Scan(&v)
+			return v, err
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, values)
+	})
+
+	t.Run("collects single row", func(t *testing.T) {
+		mockPool.ExpectQuery("SELECT id, value FROM items").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "value"}).
+				AddRow(int64(1), "a"))
+
+		rows, err := mockPool.Query(ctx, "SELECT id, value FROM items")
+		require.NoError(t, err)
+		item, err := CollectRow(rows, ScanStruct[testItem])
+		require.NoError(t, err)
+		assert.Equal(t, testItem{ID: 1, Value: "a"}, item)
+	})
+
+	t.Run("reports no rows", func(t *testing.T) {
+		mockPool.ExpectQuery("SELECT id, value FROM items").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "value"}))
+
+		rows, err := mockPool.Query(ctx, "SELECT id, value FROM items")
+		require.NoError(t, err)
+		_, err = CollectRow(rows, ScanStruct[testItem])
+		assert.True(t, ErrIsNoRows(err))
+	})
+
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"git.tatikoma.dev/corpix/protoc-gen-grpc-capabilities/capabilities"
+)
+
+// DescribeACL returns human-readable listing of methods and capabilities they require,
+// one "method: rule" line per method sorted by method name.
+func DescribeACL(acl capabilities.CapabilityRuleMap) string {
+	var b strings.Builder
+	for _, method := range slices.Sorted(maps.Keys(acl)) {
+		fmt.Fprintf(&b, "%s: %s\n", method, acl[method].String())
+	}
+	return b.String()
+}
+
+// DescribeACL returns human-readable listing of configured ACL, see DescribeACL.
+func (a *Auth) DescribeACL() string {
+	return DescribeACL(a.acl)
+}
+
+// ValidateACL checks ACL against methods served by the server (they could be collected
+// with grpc.Server.GetServiceInfo), it reports rules for unknown methods, which are
+// usually typos, and known methods which are neither covered by rule nor public.
+// Problems are logged as warnings and returned.
+func (a *Auth) ValidateACL(methods ...string) []string {
+	var (
+		known    = make(map[string]void, len(methods))
+		warnings []string
+	)
+	for _, method := range methods {
+		known[method] = void{}
+	}
+
+	for _, method := range slices.Sorted(maps.Keys(a.acl)) {
+		if _, ok := known[method]; !ok {
+			warnings = append(warnings, fmt.Sprintf("acl rule for unknown method %q", method))
+		}
+	}
+
+	for _, method := range slices.Sorted(maps.Keys(known)) {
+		if _, ok := a.acl[method]; ok {
+			continue
+		}
+		if _, ok := a.publicMethods[method]; ok {
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("method %q has no acl rule", method))
+	}
+
+	for _, warning := range warnings {
+		log.Warn().Msg(warning)
+	}
+	return warnings
+}
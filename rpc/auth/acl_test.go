@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"git.tatikoma.dev/corpix/protoc-gen-grpc-capabilities/capabilities"
+)
+
+func TestDescribeACL(t *testing.T) {
+	var (
+		get    = &capabilities.CapabilityRule{}
+		update = &capabilities.CapabilityRule{}
+		acl    = capabilities.CapabilityRuleMap{
+			"/atlas.Test/Update": update,
+			"/atlas.Test/Get":    get,
+		}
+	)
+	assert.Equal(t,
+		"/atlas.Test/Get: "+get.String()+"\n"+
+			"/atlas.Test/Update: "+update.String()+"\n",
+		DescribeACL(acl),
+	)
+	assert.Equal(t, DescribeACL(acl), (&Auth{acl: acl}).DescribeACL())
+	assert.Empty(t, DescribeACL(nil))
+}
+
+func TestValidateACL(t *testing.T) {
+	a := &Auth{acl: capabilities.CapabilityRuleMap{
+		"/atlas.Test/Get":   &capabilities.CapabilityRule{},
+		"/atlas.Test/Updte": &capabilities.CapabilityRule{},
+	}}
+	WithPublicMethods("/grpc.health.v1.Health/Check")(a)
+
+	assert.Equal(t, []string{
+		`acl rule for unknown method "/atlas.Test/Updte"`,
+		`method "/atlas.Test/Update" has no acl rule`,
+	}, a.ValidateACL(
+		"/atlas.Test/Update",
+		"/atlas.Test/Get",
+		"/grpc.health.v1.Health/Check",
+	))
+
+	assert.Empty(t, a.ValidateACL("/atlas.Test/Get", "/atlas.Test/Updte"))
+}
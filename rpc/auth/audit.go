@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"context"
+
+	"git.tatikoma.dev/corpix/protoc-gen-grpc-capabilities/capabilities"
+)
+
+type (
+	AuditDecision string
+
+	// AuditEvent describes authorization decision made for the call.
+	AuditEvent struct {
+		Method       string
+		Capabilities capabilities.Capabilities
+		Email        string
+		Decision     AuditDecision
+		Reason       string
+	}
+
+	AuditHook func(ctx context.Context, ev AuditEvent)
+)
+
+const (
+	AuditDecisionAllow AuditDecision = "allow"
+	AuditDecisionDeny  AuditDecision = "deny"
+)
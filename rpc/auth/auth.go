@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/asn1"
@@ -14,6 +15,8 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
@@ -22,12 +25,18 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"git.tatikoma.dev/corpix/atlas/seq"
 	"git.tatikoma.dev/corpix/protoc-gen-grpc-capabilities/capabilities"
 )
 
 var (
 	TokenStateCookieName = "token_state_" + hex.EncodeToString(fnv.New64a().Sum([]byte(fmt.Sprintf("%T", token{}))))[:8]
 	TokenCookieName      = "token_" + hex.EncodeToString(fnv.New64a().Sum([]byte(fmt.Sprintf("%T", token{}))))[:8]
+	// TokenRedirectCookieName stores path requested before authentication to return to after login.
+	TokenRedirectCookieName = "token_redirect_" + hex.EncodeToString(fnv.New64a().Sum([]byte(fmt.Sprintf("%T", token{}))))[:8]
+
+	// DefaultCertificateCapabilitiesCacheSize is a number of client certificates which capabilities are cached.
+	DefaultCertificateCapabilitiesCacheSize = 1024
 
 	// well_known_private_prefix + [ord(x) for x in "atlas"]
 	CapabilitiesCertificateOID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 97, 116, 108, 97, 115}
@@ -47,6 +56,9 @@ type (
 
 		Certificate *CertificateConfig
 		Token       *TokenConfig
+
+		// Audit is called on every authorization decision, optional.
+		Audit AuditHook
 	}
 
 	CertificateConfig struct {
@@ -62,12 +74,33 @@ type (
 		Issuer string
 		Client string
 		Secret string
+
+		// Introspection is used to verify tokens which are not valid JWT, optional.
+		Introspection *IntrospectionConfig
+	}
+
+	// TokenVerifier verifies token and returns its claims, see WithTokenVerifier.
+	TokenVerifier interface {
+		Verify(ctx context.Context, token string) (*Claims, error)
+	}
+	TokenVerifierFunc func(ctx context.Context, token string) (*Claims, error)
+
+	oidcTokenVerifier struct {
+		verifier *oidc.IDTokenVerifier
 	}
 
 	token struct {
 		Provider     *oidc.Provider
-		Verifier     *oidc.IDTokenVerifier
+		Verifier     TokenVerifier
+		Keys         *KeySet
 		OAuth2Config oauth2.Config
+
+		config      *TokenConfig
+		redirectURL string
+		discovery   tokenDiscovery
+		discovering *tokenDiscoveryCall
+		discovered  atomic.Bool
+		mu          sync.Mutex
 	}
 
 	Auth struct {
@@ -76,12 +109,26 @@ type (
 		tlsManager *TLSConfigCertificateManager
 		token      *token
 		acl        capabilities.CapabilityRuleMap
+
+		spiffe              map[string][]string
+		publicMethods       map[string]void
+		claimsSecret        []byte
+		signedClaims        bool
+		sessions            SessionStore
+		discovery           tokenDiscovery
+		requireClientCert   bool
+		streamTokenInterval time.Duration
+		certCapabilities    *seq.LRU[[sha256.Size]byte, capabilities.Capabilities]
 	}
 
 	Option func(*Auth)
 )
 
-func (token) rand(n int) (string, error) {
+func (*token) rand(n int) (string, error) {
+	return randString(n)
+}
+
+func randString(n int) (string, error) {
 	b := make([]byte, n)
 	if _, err := io.ReadFull(rand.Reader, b); err != nil {
 		return "", err
@@ -89,7 +136,7 @@ func (token) rand(n int) (string, error) {
 	return base64.RawURLEncoding.EncodeToString(b), nil
 }
 
-func (token) setCookie(w http.ResponseWriter, r *http.Request, name, value string, age time.Duration) {
+func (*token) setCookie(w http.ResponseWriter, r *http.Request, name, value string, age time.Duration) {
 	c := &http.Cookie{
 		Name:     name,
 		Value:    value,
@@ -101,6 +148,84 @@ func (token) setCookie(w http.ResponseWriter, r *http.Request, name, value strin
 	http.SetCookie(w, c)
 }
 
+// WithRequireClientCert makes client certificate mandatory for all connections,
+// token authentication is not used as a fallback in this mode.
+func WithRequireClientCert() Option {
+	return func(a *Auth) {
+		a.requireClientCert = true
+	}
+}
+
+// WithStreamTokenReverification verifies token of the stream every interval while it is running,
+// stream is terminated with Unauthenticated once token expires or becomes invalid otherwise.
+func WithStreamTokenReverification(interval time.Duration) Option {
+	return func(a *Auth) {
+		a.streamTokenInterval = interval
+	}
+}
+
+// WithCertificateCapabilitiesCache sets number of client certificates which decoded capabilities
+// are cached, DefaultCertificateCapabilitiesCacheSize is used by default, size <= 0 disables cache.
+func WithCertificateCapabilitiesCache(size int) Option {
+	return func(a *Auth) {
+		a.certCapabilities = nil
+		if size > 0 {
+			a.certCapabilities = seq.NewLRU[[sha256.Size]byte, capabilities.Capabilities](size, 0)
+		}
+	}
+}
+
+// WithSPIFFECapabilities maps SPIFFE IDs (spiffe:// URI SAN of the client certificate)
+// to capability strings, so services may be authorized by mesh identity.
+// Clients presenting SPIFFE ID missing from the map are denied.
+func WithSPIFFECapabilities(ids map[string][]string) Option {
+	return func(a *Auth) {
+		a.spiffe = ids
+	}
+}
+
+// WithPublicMethods allows anonymous access to methods (full gRPC method names),
+// authentication and authorization are skipped for them entirely.
+func WithPublicMethods(methods ...string) Option {
+	return func(a *Auth) {
+		if a.publicMethods == nil {
+			a.publicMethods = make(map[string]void, len(methods))
+		}
+		for _, method := range methods {
+			a.publicMethods[method] = void{}
+		}
+	}
+}
+
+// WithTokenVerifier replaces OIDC token verifier, e.g. with a fake returning canned claims in tests.
+// Without Config.Token provider discovery is skipped, so only token authentication is available.
+func WithTokenVerifier(v TokenVerifier) Option {
+	return func(a *Auth) {
+		a.token = &token{
+			Verifier: v,
+			config:   &TokenConfig{},
+		}
+		a.token.discovered.Store(true)
+	}
+}
+
+func (f TokenVerifierFunc) Verify(ctx context.Context, token string) (*Claims, error) {
+	return f(ctx, token)
+}
+
+func (v oidcTokenVerifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	idToken, err := v.verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	var claims Claims
+	err = idToken.Claims(&claims)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse claims")
+	}
+	return &claims, nil
+}
+
 func (a *Auth) TLSConfig() *tls.Config {
 	return a.tls.Clone()
 }
@@ -117,18 +242,34 @@ func (a *Auth) CertificateManager() *TLSConfigCertificateManager {
 	return a.tlsManager
 }
 
+// KeySet returns issuer verification keys, it is nil until token provider is discovered.
+func (a *Auth) KeySet() *KeySet {
+	if a.token == nil {
+		return nil
+	}
+	a.token.mu.Lock()
+	defer a.token.mu.Unlock()
+	return a.token.Keys
+}
+
 func (a *Auth) tokenClaims(ctx context.Context, token string) (*Claims, error) {
-	idToken, err := a.token.Verifier.Verify(ctx, token)
+	err := a.token.ready(ctx)
 	if err != nil {
-		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+		return nil, status.Errorf(codes.Unavailable, "token provider unavailable: %v", err)
 	}
-	var claims Claims
-	err = idToken.Claims(&claims)
+	claims, err := a.token.Verifier.Verify(ctx, token)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to parse claims: %v", err)
+		if a.token.config.Introspection == nil {
+			return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+		}
+		claims, introspectErr := a.token.introspect(ctx, token)
+		if introspectErr != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v, introspection: %v", err, introspectErr)
+		}
+		return claims, nil
 	}
 
-	return &claims, nil
+	return claims, nil
 }
 
 func New(cfg Config, opts ...Option) (*Auth, error) {
@@ -157,38 +298,48 @@ func New(cfg Config, opts ...Option) (*Auth, error) {
 		ApplyCRLVerifier(tc, NewCRLVerifier(cfg.Certificate.CRL, cfg.Certificate.CRLPolicy))
 	}
 
-	//
-
-	var t *token
-	if cfg.Token != nil {
-		provider, err := oidc.NewProvider(ctx, cfg.Token.Issuer)
-		if err != nil {
-			return nil, err
-		}
-		t = &token{
-			Provider: provider,
-			Verifier: provider.Verifier(&oidc.Config{ClientID: cfg.Token.Client}),
-			OAuth2Config: oauth2.Config{
-				ClientID:     cfg.Token.Client,
-				ClientSecret: cfg.Token.Secret,
-				Endpoint:     provider.Endpoint(),
-				RedirectURL:  cfg.URL.String() + "/auth/token/callback",
-				Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
-			},
-		}
-	}
-
 	a := &Auth{
 		config:     &cfg,
 		tls:        tc,
 		tlsManager: tccm,
-		token:      t,
 		acl:        cfg.ACL,
+		discovery:  tokenDiscovery{attempts: 1},
+
+		certCapabilities: seq.NewLRU[[sha256.Size]byte, capabilities.Capabilities](DefaultCertificateCapabilitiesCacheSize, 0),
 	}
 
 	for _, opt := range opts {
 		opt(a)
 	}
+	if a.signedClaims && len(a.claimsSecret) == 0 {
+		return nil, errors.New("signed claims secret must not be empty")
+	}
+
+	if cfg.Token != nil {
+		var verifier TokenVerifier
+		if a.token != nil {
+			verifier = a.token.Verifier
+		}
+		a.token = &token{
+			Verifier:    verifier,
+			config:      cfg.Token,
+			redirectURL: cfg.URL.String() + "/auth/token/callback",
+			discovery:   a.discovery,
+		}
+		if !a.discovery.lazy {
+			err = a.token.ready(ctx)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if a.requireClientCert {
+		err = ApplyRequireClientCertPolicy(a.tls)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return a, nil
 }
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"git.tatikoma.dev/corpix/protoc-gen-grpc-capabilities/capabilities"
+)
+
+// UnionCapabilities returns new capabilities containing capabilities of both a and b,
+// capability of b wins if both have the same id.
+func UnionCapabilities(a, b capabilities.Capabilities) capabilities.Capabilities {
+	res := make(capabilities.Capabilities, len(a)+len(b))
+	for k, v := range a {
+		res[k] = v
+	}
+	for k, v := range b {
+		res[k] = v
+	}
+	return res
+}
+
+// IntersectCapabilities returns new capabilities containing capabilities of a
+// which ids are also present in b.
+func IntersectCapabilities(a, b capabilities.Capabilities) capabilities.Capabilities {
+	res := capabilities.Capabilities{}
+	for k, v := range a {
+		if _, ok := b[k]; ok {
+			res[k] = v
+		}
+	}
+	return res
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"git.tatikoma.dev/corpix/protoc-gen-grpc-capabilities/capabilities"
+)
+
+func TestUnionCapabilities(t *testing.T) {
+	var (
+		read        = capabilities.NewCapability("read")
+		writeUsers  = capabilities.NewCapability("write", "users")
+		writeGroups = capabilities.NewCapability("write", "groups")
+		admin       = capabilities.NewCapability("admin")
+
+		a = capabilities.Capabilities{"read": read, "write": writeUsers}
+		b = capabilities.Capabilities{"write": writeGroups, "admin": admin}
+	)
+
+	assert.Equal(t, capabilities.Capabilities{
+		"read":  read,
+		"write": writeGroups,
+		"admin": admin,
+	}, UnionCapabilities(a, b))
+	assert.Equal(t, capabilities.Capabilities{"read": read, "write": writeUsers}, a, "arguments are not modified")
+	assert.Equal(t, capabilities.Capabilities{"write": writeGroups, "admin": admin}, b, "arguments are not modified")
+
+	assert.Empty(t, UnionCapabilities(nil, nil))
+	assert.Equal(t, a, UnionCapabilities(a, nil))
+}
+
+func TestIntersectCapabilities(t *testing.T) {
+	var (
+		read        = capabilities.NewCapability("read")
+		writeUsers  = capabilities.NewCapability("write", "users")
+		writeGroups = capabilities.NewCapability("write", "groups")
+		admin       = capabilities.NewCapability("admin")
+
+		a = capabilities.Capabilities{"read": read, "write": writeUsers}
+		b = capabilities.Capabilities{"write": writeGroups, "admin": admin}
+	)
+
+	assert.Equal(t, capabilities.Capabilities{"write": writeUsers}, IntersectCapabilities(a, b))
+	assert.Equal(t, capabilities.Capabilities{"write": writeGroups}, IntersectCapabilities(b, a))
+	assert.Empty(t, IntersectCapabilities(a, capabilities.Capabilities{"admin": admin}))
+	assert.Empty(t, IntersectCapabilities(a, nil))
+}
@@ -16,11 +16,13 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"git.tatikoma.dev/corpix/atlas/errors"
+	"git.tatikoma.dev/corpix/atlas/log"
 )
 
 const (
@@ -28,9 +30,26 @@ const (
 	CACertFile = "ca-cert.pem"
 	CRLFile    = "ca-crl.pem"
 	SerialFile = "serial"
+	// BundleNameSuffix is inserted into certificate file name to get bundle file name.
+	BundleNameSuffix = "bundle"
 
 	DefaultCRLValidity = 24 * 7 * time.Hour // week
 	DefaultFileMode    = 0o640
+	CertValidityYears  = 10
+)
+
+var (
+	ErrCertFileExists = errors.New("certificate file already exists")
+	ErrCertMissingSAN = errors.New("server certificate has no subject alternative names")
+
+	// certAuthorityInfoAccessOID is id-pe-authorityInfoAccess, x509 generates it from template.
+	certAuthorityInfoAccessOID = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 1}
+
+	// caLocks serializes operations which share CA, keyed by absolute CA key path.
+	caLocks = struct {
+		locks map[string]*sync.Mutex
+		sync.Mutex
+	}{locks: map[string]*sync.Mutex{}}
 )
 
 type (
@@ -48,6 +67,7 @@ type (
 
 	CertToolGenerateOptions struct {
 		IPAddresses  string
+		OutDir       string
 		NamePrefix   string
 		Type         string
 		CAKeyPath    string
@@ -57,14 +77,34 @@ type (
 		Region       string
 		NameSuffix   string
 		Capabilities []string
-		ExtKeyUsage  []x509.ExtKeyUsage
-		KeyUsage     x509.KeyUsage
-		FileMode     os.FileMode
-		GenerateCA   bool
+		// ExtraExtensions are appended to certificate extensions as is, e.g. tenant identifier.
+		ExtraExtensions []pkix.Extension
+		ExtKeyUsage     []x509.ExtKeyUsage
+		KeyUsage        x509.KeyUsage
+		FileMode        os.FileMode
+		// CAKeyOwner sets owner of generated CA key, optional.
+		CAKeyOwner *CertFileOwner
+		GenerateCA bool
+		// CommonNameSAN adds common name to subject alternative names if it is missing there.
+		CommonNameSAN bool
+		// StrictSAN refuses to generate server certificate without subject alternative names,
+		// otherwise only a warning is logged.
+		StrictSAN bool
+		// Bundle additionally writes leaf certificate followed by CA certificate into a single file.
+		Bundle bool
+		// Force allows to overwrite existing CA or certificate files.
+		Force bool
+	}
+
+	// CertFileOwner is numeric owner and group of the file.
+	CertFileOwner struct {
+		UID int
+		GID int
 	}
 
 	CertToolRevokeOptions struct {
 		RevocationTime time.Time
+		OutDir         string
 		NamePrefix     string
 		CACertPath     string
 		CAKeyPath      string
@@ -77,6 +117,7 @@ type (
 	}
 
 	CertToolCRLInitOptions struct {
+		OutDir      string
 		NamePrefix  string
 		CACertPath  string
 		CAKeyPath   string
@@ -84,6 +125,36 @@ type (
 		CRLValidity time.Duration
 		FileMode    os.FileMode
 	}
+
+	CertToolRotateCAOptions struct {
+		OutDir     string
+		NamePrefix string
+		CACertPath string
+		CAKeyPath  string
+		// CommonName and Region of the new CA, subject of the old CA is kept if empty.
+		CommonName string
+		Region     string
+		// CrossSign writes new CA certificate signed by the old CA into CrossCertPath,
+		// so peers which still trust only the old CA accept leafs of the new CA.
+		CrossSign     bool
+		CrossCertPath string
+		FileMode      os.FileMode
+		// CAKeyOwner sets owner of generated CA key, optional.
+		CAKeyOwner *CertFileOwner
+		// Force allows to overwrite backups of the CA left by the previous rotation.
+		Force bool
+	}
+
+	CertToolReissueOptions struct {
+		OutDir     string
+		NamePrefix string
+		CACertPath string
+		CAKeyPath  string
+		// CertPath is a leaf certificate file name inside of OutDir (with NamePrefix),
+		// it is replaced with the re-issued one.
+		CertPath string
+		FileMode os.FileMode
+	}
 )
 
 func NewCertTypeRegistry() *CertTypeRegistry {
@@ -122,8 +193,20 @@ func (r *CertTypeRegistry) Lookup(name string) (CertType, error) {
 	return certType, nil
 }
 
-// Generate creates certificates based on options. Caller is responsible for synchronization.
+// Generate creates certificates based on options.
+// It is safe to call concurrently for certificates sharing the same CA.
 func (ct *CertTool) Generate(opts CertToolGenerateOptions) error {
+	err := ct.ensureOutDir(opts.OutDir)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := ct.lockCA(ct.caKeyPath(opts))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	if opts.GenerateCA {
 		return ct.generateCA(opts)
 	}
@@ -151,8 +234,14 @@ func (ct *CertTool) Revoke(opts CertToolRevokeOptions) error {
 		return err
 	}
 
-	caCertPath := ct.caCertPathWithPrefix(opts.NamePrefix, opts.CACertPath)
-	caKeyPath := ct.caKeyPathWithPrefix(opts.NamePrefix, opts.CAKeyPath)
+	caCertPath := ct.caCertPathWithPrefix(opts.OutDir, opts.NamePrefix, opts.CACertPath)
+	caKeyPath := ct.caKeyPathWithPrefix(opts.OutDir, opts.NamePrefix, opts.CAKeyPath)
+	unlock, err := ct.lockCA(caKeyPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	caCert, caKey, err := ct.readCAFiles(caCertPath, caKeyPath)
 	if err != nil {
 		return err
@@ -171,7 +260,7 @@ func (ct *CertTool) Revoke(opts CertToolRevokeOptions) error {
 		return nil
 	}
 
-	crlPath = ct.crlPathWithPrefix(opts.NamePrefix, crlPath)
+	crlPath = ct.crlPathWithPrefix(opts.OutDir, opts.NamePrefix, crlPath)
 	rl, err := ct.readCRL(crlPath, caCert)
 	if err != nil {
 		return err
@@ -211,18 +300,29 @@ func (ct *CertTool) Revoke(opts CertToolRevokeOptions) error {
 		return err
 	}
 
-	return ct.writePEMFile(crlPath, "X509 CRL", crlBytes, opts.FileMode)
+	return ct.writePEMFile(crlPath, "X509 CRL", crlBytes, opts.FileMode, nil)
 }
 
 // InitCRL creates a new empty CRL.
 func (ct *CertTool) InitCRL(opts CertToolCRLInitOptions) error {
-	crlPath := ct.crlPathWithPrefix(opts.NamePrefix, strings.TrimSpace(opts.CRLPath))
+	err := ct.ensureOutDir(opts.OutDir)
+	if err != nil {
+		return err
+	}
+
+	crlPath := ct.crlPathWithPrefix(opts.OutDir, opts.NamePrefix, strings.TrimSpace(opts.CRLPath))
 	if crlPath == "" {
 		return errors.New("crl path is required")
 	}
 
-	caCertPath := ct.caCertPathWithPrefix(opts.NamePrefix, opts.CACertPath)
-	caKeyPath := ct.caKeyPathWithPrefix(opts.NamePrefix, opts.CAKeyPath)
+	caCertPath := ct.caCertPathWithPrefix(opts.OutDir, opts.NamePrefix, opts.CACertPath)
+	caKeyPath := ct.caKeyPathWithPrefix(opts.OutDir, opts.NamePrefix, opts.CAKeyPath)
+	unlock, err := ct.lockCA(caKeyPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	caCert, caKey, err := ct.readCAFiles(caCertPath, caKeyPath)
 	if err != nil {
 		return err
@@ -256,70 +356,329 @@ func (ct *CertTool) InitCRL(opts CertToolCRLInitOptions) error {
 		return err
 	}
 
-	return ct.writePEMFile(crlPath, "X509 CRL", crlBytes, opts.FileMode)
+	return ct.writePEMFile(crlPath, "X509 CRL", crlBytes, opts.FileMode, nil)
+}
+
+// RotateCA replaces CA key and certificate with the new ones, old files are kept
+// with ".prev" name suffix, so leafs issued by the old CA could be verified
+// until they are re-issued with Reissue. Existing ".prev" files are not
+// overwritten without opts.Force. Old CA key is restored if new CA could not be written.
+func (ct *CertTool) RotateCA(opts CertToolRotateCAOptions) error {
+	err := ct.ensureOutDir(opts.OutDir)
+	if err != nil {
+		return err
+	}
+
+	caCertPath := ct.caCertPathWithPrefix(opts.OutDir, opts.NamePrefix, opts.CACertPath)
+	caKeyPath := ct.caKeyPathWithPrefix(opts.OutDir, opts.NamePrefix, opts.CAKeyPath)
+	unlock, err := ct.lockCA(caKeyPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	prevCertPath, prevKeyPath := withNameSuffix(caCertPath, "prev"), withNameSuffix(caKeyPath, "prev")
+	serialOpts := CertToolGenerateOptions{OutDir: opts.OutDir, NamePrefix: opts.NamePrefix, FileMode: opts.FileMode, Force: opts.Force}
+	err = ct.checkOverwrite(serialOpts, prevCertPath, prevKeyPath)
+	if err != nil {
+		return err
+	}
+
+	oldCert, oldKey, err := ct.readCAFiles(caCertPath, caKeyPath)
+	if err != nil {
+		return errors.Errorf("reading CA: %w", err)
+	}
+
+	serial, err := ct.loadSerial(serialOpts)
+	if err != nil {
+		return errors.Errorf("error loading serial: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	subject := oldCert.Subject
+	subject.Names, subject.ExtraNames = nil, nil
+	if opts.CommonName != "" {
+		subject.CommonName = opts.CommonName
+	}
+	serial.Set(serial.Add(serial, big.NewInt(1)))
+	template, err := ct.caTemplate(serial, subject, key)
+	if err != nil {
+		return err
+	}
+	ct.applyRegion(template, opts.Region)
+
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+
+	var crossBytes []byte
+	if opts.CrossSign {
+		serial.Set(serial.Add(serial, big.NewInt(1)))
+		template.SerialNumber = serial
+		if template.NotAfter.After(oldCert.NotAfter) {
+			template.NotAfter = oldCert.NotAfter
+		}
+		crossBytes, err = x509.CreateCertificate(rand.Reader, template, oldCert, &key.PublicKey, oldKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	oldCertPEM, err := os.ReadFile(caCertPath)
+	if err != nil {
+		return err
+	}
+	oldKeyPEM, err := os.ReadFile(caKeyPath)
+	if err != nil {
+		return err
+	}
+	err = ct.writeFile(prevCertPath, oldCertPEM, opts.FileMode, nil)
+	if err != nil {
+		return err
+	}
+	err = ct.writeFile(prevKeyPath, oldKeyPEM, opts.FileMode, opts.CAKeyOwner)
+	if err != nil {
+		return err
+	}
+
+	if crossBytes != nil {
+		crossPath := opts.CrossCertPath
+		if crossPath == "" {
+			crossPath = withNameSuffix(caCertPath, "cross")
+		}
+		err = ct.writePEMFile(crossPath, "CERTIFICATE", crossBytes, opts.FileMode, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	err = ct.writePEMFile(caKeyPath, "EC PRIVATE KEY", keyBytes, opts.FileMode, opts.CAKeyOwner)
+	if err != nil {
+		return err
+	}
+	err = ct.writePEMFile(caCertPath, "CERTIFICATE", certBytes, opts.FileMode, nil)
+	if err != nil {
+		// key and certificate of the CA must match, old key goes back in place
+		restoreErr := ct.writeFile(caKeyPath, oldKeyPEM, opts.FileMode, opts.CAKeyOwner)
+		if restoreErr != nil {
+			return errors.Errorf("writing CA certificate: %w, restoring CA key: %w", err, restoreErr)
+		}
+		return err
+	}
+
+	err = ct.saveSerial(serialOpts, serial)
+	if err != nil {
+		return errors.Errorf("error saving serial: %w", err)
+	}
+	return nil
+}
+
+// Reissue signs existing leaf certificate with the current CA, subject, alternative names,
+// key usage, capabilities and extra extensions are copied from the leaf, its key is kept as is.
+func (ct *CertTool) Reissue(opts CertToolReissueOptions) error {
+	if strings.TrimSpace(opts.CertPath) == "" {
+		return errors.New("certificate path is required")
+	}
+
+	caCertPath := ct.caCertPathWithPrefix(opts.OutDir, opts.NamePrefix, opts.CACertPath)
+	caKeyPath := ct.caKeyPathWithPrefix(opts.OutDir, opts.NamePrefix, opts.CAKeyPath)
+	unlock, err := ct.lockCA(caKeyPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	caCert, caKey, err := ct.readCAFiles(caCertPath, caKeyPath)
+	if err != nil {
+		return errors.Errorf("reading CA: %w", err)
+	}
+
+	certPath := ct.namespacePrefix(opts.OutDir, opts.NamePrefix, opts.CertPath)
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return err
+	}
+	cert, err := ct.parseCert(certPEM)
+	if err != nil {
+		return err
+	}
+
+	serialOpts := CertToolGenerateOptions{OutDir: opts.OutDir, NamePrefix: opts.NamePrefix, FileMode: opts.FileMode}
+	serial, err := ct.loadSerial(serialOpts)
+	if err != nil {
+		return errors.Errorf("error loading serial: %w", err)
+	}
+	serial.Set(serial.Add(serial, big.NewInt(1)))
+
+	subject := cert.Subject
+	subject.Names = nil
+	template := &x509.Certificate{
+		SerialNumber:   serial,
+		Subject:        subject,
+		NotBefore:      time.Now(),
+		NotAfter:       time.Now().AddDate(CertValidityYears, 0, 0),
+		IPAddresses:    cert.IPAddresses,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		URIs:           cert.URIs,
+		KeyUsage:       cert.KeyUsage,
+		ExtKeyUsage:    cert.ExtKeyUsage,
+	}
+	for _, ext := range cert.Extensions {
+		if !isStandardCertExtension(ext.Id) {
+			template.ExtraExtensions = append(template.ExtraExtensions, ext)
+		}
+	}
+
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, caCert, cert.PublicKey, caKey)
+	if err != nil {
+		return err
+	}
+
+	// serial must be persisted before certificate is written, otherwise it could be reused
+	err = ct.saveSerial(serialOpts, serial)
+	if err != nil {
+		return errors.Errorf("error saving serial: %w", err)
+	}
+	return ct.writePEMFile(certPath, "CERTIFICATE", certBytes, opts.FileMode, nil)
 }
 
 func (ct *CertTool) namespace(opts CertToolGenerateOptions, fileName string) string {
-	return ct.namespacePrefix(opts.NamePrefix, fileName)
+	return ct.namespacePrefix(opts.OutDir, opts.NamePrefix, fileName)
 }
 
-func (ct *CertTool) namespacePrefix(namePrefix, fileName string) string {
+// namespacePrefix resolves fileName prefixed with namePrefix inside of outDir.
+func (ct *CertTool) namespacePrefix(outDir, namePrefix, fileName string) string {
 	if namePrefix != "" {
-		return namePrefix + "." + fileName
+		fileName = namePrefix + "." + fileName
+	}
+	if outDir != "" {
+		return filepath.Join(outDir, fileName)
 	}
 	return fileName
 }
 
 func (ct *CertTool) certFileName(opts CertToolGenerateOptions, fileName string) string {
 	if opts.NameSuffix != "" {
-		ext := filepath.Ext(fileName)
-		base := strings.TrimSuffix(fileName, ext)
-		if ext == "" {
-			fileName = base + "." + opts.NameSuffix
-		} else {
-			fileName = base + "." + opts.NameSuffix + ext
-		}
+		fileName = withNameSuffix(fileName, opts.NameSuffix)
 	}
 	return ct.namespace(opts, fileName)
 }
 
+// bundleFileName returns path of the bundle, e.g. server-cert.bundle.pem.
+func (ct *CertTool) bundleFileName(opts CertToolGenerateOptions, certType CertType) string {
+	return ct.certFileName(opts, withNameSuffix(certType.CertFile, BundleNameSuffix))
+}
+
+// isStandardCertExtension reports whether extension is generated by x509 package
+// from certificate template fields, such extensions are not copied on reissue.
+func isStandardCertExtension(id asn1.ObjectIdentifier) bool {
+	return (len(id) == 4 && id[0] == 2 && id[1] == 5 && id[2] == 29) || id.Equal(certAuthorityInfoAccessOID)
+}
+
+// withNameSuffix inserts suffix before extension of fileName.
+func withNameSuffix(fileName, suffix string) string {
+	ext := filepath.Ext(fileName)
+	return strings.TrimSuffix(fileName, ext) + "." + suffix + ext
+}
+
 func (ct *CertTool) caKeyPath(opts CertToolGenerateOptions) string {
-	return ct.caKeyPathWithPrefix(opts.NamePrefix, opts.CAKeyPath)
+	return ct.caKeyPathWithPrefix(opts.OutDir, opts.NamePrefix, opts.CAKeyPath)
 }
 
 func (ct *CertTool) caCertPath(opts CertToolGenerateOptions) string {
-	return ct.caCertPathWithPrefix(opts.NamePrefix, opts.CACertPath)
+	return ct.caCertPathWithPrefix(opts.OutDir, opts.NamePrefix, opts.CACertPath)
 }
 
-func (ct *CertTool) caKeyPathWithPrefix(namePrefix, path string) string {
+// caKeyPathWithPrefix returns path as is if it is set,
+// outDir applies only to default file names.
+func (ct *CertTool) caKeyPathWithPrefix(outDir, namePrefix, path string) string {
 	if path != "" {
 		return path
 	}
-	return ct.namespacePrefix(namePrefix, CAKeyFile)
+	return ct.namespacePrefix(outDir, namePrefix, CAKeyFile)
 }
 
-func (ct *CertTool) caCertPathWithPrefix(namePrefix, path string) string {
+func (ct *CertTool) caCertPathWithPrefix(outDir, namePrefix, path string) string {
 	if path != "" {
 		return path
 	}
-	return ct.namespacePrefix(namePrefix, CACertFile)
+	return ct.namespacePrefix(outDir, namePrefix, CACertFile)
 }
 
-func (ct *CertTool) crlPathWithPrefix(namePrefix, path string) string {
+func (ct *CertTool) crlPathWithPrefix(outDir, namePrefix, path string) string {
 	if path != "" {
 		return path
 	}
-	return ct.namespacePrefix(namePrefix, CRLFile)
+	return ct.namespacePrefix(outDir, namePrefix, CRLFile)
+}
+
+// lockCA acquires lock for CA identified by key path, returns unlock function.
+func (ct *CertTool) lockCA(caKeyPath string) (func(), error) {
+	key, err := filepath.Abs(caKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	caLocks.Lock()
+	lock, ok := caLocks.locks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		caLocks.locks[key] = lock
+	}
+	caLocks.Unlock()
+
+	lock.Lock()
+	return lock.Unlock, nil
+}
+
+// ensureOutDir creates output directory if it is missing.
+func (ct *CertTool) ensureOutDir(outDir string) error {
+	if outDir == "" {
+		return nil
+	}
+	err := os.MkdirAll(outDir, 0o750)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create output directory %q", outDir)
+	}
+	return nil
+}
+
+// generateFiles returns paths of files which Generate writes.
+func (ct *CertTool) generateFiles(opts CertToolGenerateOptions) ([]string, error) {
+	var files []string
+	if opts.GenerateCA || !ct.fileExists(ct.caKeyPath(opts)) {
+		files = append(files, ct.caCertPath(opts), ct.caKeyPath(opts))
+	}
+	if !opts.GenerateCA {
+		certType, err := ct.Lookup(opts.Type)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files,
+			ct.certFileName(opts, certType.CertFile),
+			ct.certFileName(opts, certType.KeyFile),
+		)
+		if opts.Bundle {
+			files = append(files, ct.bundleFileName(opts, certType))
+		}
+	}
+	return append(files, ct.namespace(opts, SerialFile)), nil
 }
 
 func (ct *CertTool) loadSerial(opts CertToolGenerateOptions) (*big.Int, error) {
 	serialFilePath := ct.namespace(opts, SerialFile)
 	if !ct.fileExists(serialFilePath) {
-		mode := opts.FileMode
-		if mode == 0 {
-			mode = DefaultFileMode
-		}
-		err := os.WriteFile(serialFilePath, []byte("1"), mode)
+		err := ct.writeFile(serialFilePath, []byte("1"), opts.FileMode, nil)
 		if err != nil {
 			return nil, errors.Errorf("error initializing cert serial number cache: %v", err)
 		}
@@ -340,11 +699,7 @@ func (ct *CertTool) loadSerial(opts CertToolGenerateOptions) (*big.Int, error) {
 }
 
 func (ct *CertTool) saveSerial(opts CertToolGenerateOptions, serial *big.Int) error {
-	mode := opts.FileMode
-	if mode == 0 {
-		mode = DefaultFileMode
-	}
-	return os.WriteFile(ct.namespace(opts, SerialFile), []byte(serial.String()), mode)
+	return ct.writeFile(ct.namespace(opts, SerialFile), []byte(serial.String()), opts.FileMode, nil)
 }
 
 func (ct *CertTool) generateCerts(opts CertToolGenerateOptions, certType CertType) error {
@@ -375,6 +730,11 @@ func (ct *CertTool) generateCerts(opts CertToolGenerateOptions, certType CertTyp
 }
 
 func (ct *CertTool) generateCA(opts CertToolGenerateOptions) error {
+	err := ct.checkOverwrite(opts, ct.caKeyPath(opts), ct.caCertPath(opts))
+	if err != nil {
+		return err
+	}
+
 	serial, err := ct.loadSerial(opts)
 	if err != nil {
 		return errors.Errorf("error loading serial: %w", err)
@@ -391,23 +751,10 @@ func (ct *CertTool) generateCA(opts CertToolGenerateOptions) error {
 		return err
 	}
 
-	subjectKeyID, err := ct.subjectKeyID(&key.PublicKey)
+	template, err := ct.caTemplate(serial, pkix.Name{CommonName: opts.CommonName}, key)
 	if err != nil {
 		return err
 	}
-
-	template := &x509.Certificate{
-		SerialNumber: serial,
-		Subject: pkix.Name{
-			CommonName: opts.CommonName,
-		},
-		NotBefore:             time.Now(),
-		NotAfter:              time.Now().AddDate(10, 0, 0),
-		IsCA:                  true,
-		BasicConstraintsValid: true,
-		SubjectKeyId:          subjectKeyID,
-		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
-	}
 	ct.applyRegion(template, opts.Region)
 
 	certBytes, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
@@ -415,7 +762,7 @@ func (ct *CertTool) generateCA(opts CertToolGenerateOptions) error {
 		return err
 	}
 
-	err = ct.writePEMFile(ct.caCertPath(opts), "CERTIFICATE", certBytes, opts.FileMode)
+	err = ct.writePEMFile(ct.caCertPath(opts), "CERTIFICATE", certBytes, opts.FileMode, nil)
 	if err != nil {
 		return err
 	}
@@ -425,10 +772,40 @@ func (ct *CertTool) generateCA(opts CertToolGenerateOptions) error {
 		return err
 	}
 
-	return ct.writePEMFile(ct.caKeyPath(opts), "EC PRIVATE KEY", keyBytes, opts.FileMode)
+	return ct.writePEMFile(ct.caKeyPath(opts), "EC PRIVATE KEY", keyBytes, opts.FileMode, opts.CAKeyOwner)
+}
+
+func (ct *CertTool) caTemplate(serial *big.Int, subject pkix.Name, key *ecdsa.PrivateKey) (*x509.Certificate, error) {
+	subjectKeyID, err := ct.subjectKeyID(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().AddDate(CertValidityYears, 0, 0),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		SubjectKeyId:          subjectKeyID,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}, nil
 }
 
 func (ct *CertTool) generateCert(opts CertToolGenerateOptions, certType CertType, serial *big.Int, caCert *x509.Certificate, caKey *ecdsa.PrivateKey) error {
+	files := []string{
+		ct.certFileName(opts, certType.KeyFile),
+		ct.certFileName(opts, certType.CertFile),
+	}
+	if opts.Bundle {
+		files = append(files, ct.bundleFileName(opts, certType))
+	}
+	err := ct.checkOverwrite(opts, files...)
+	if err != nil {
+		return err
+	}
+
 	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
 		return err
@@ -442,41 +819,58 @@ func (ct *CertTool) generateCert(opts CertToolGenerateOptions, certType CertType
 			CommonName: opts.CommonName,
 		},
 		NotBefore: time.Now(),
-		NotAfter:  time.Now().AddDate(10, 0, 0),
+		NotAfter:  time.Now().AddDate(CertValidityYears, 0, 0),
 	}
 	ct.applyRegion(template, opts.Region)
 	ct.applyAltNames(template, opts.IPAddresses, opts.DNSNames)
 	ct.applyKeyUsage(template, opts.KeyUsage, opts.ExtKeyUsage)
 
+	err = ct.checkAltNames(template, opts)
+	if err != nil {
+		return err
+	}
+
 	err = ct.applyCapabilities(template, opts.Capabilities)
 	if err != nil {
 		return err
 	}
+	template.ExtraExtensions = append(template.ExtraExtensions, opts.ExtraExtensions...)
 
 	certBytes, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
 	if err != nil {
 		return err
 	}
 
-	err = ct.writePEMFile(ct.certFileName(opts, certType.CertFile), "CERTIFICATE", certBytes, opts.FileMode)
+	err = ct.writePEMFile(ct.certFileName(opts, certType.CertFile), "CERTIFICATE", certBytes, opts.FileMode, nil)
 	if err != nil {
 		return err
 	}
 
+	if opts.Bundle {
+		bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certBytes})
+		bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCert.Raw})...)
+		err = ct.writeFile(ct.bundleFileName(opts, certType), bundle, opts.FileMode, nil)
+		if err != nil {
+			return err
+		}
+	}
+
 	keyBytes, err := x509.MarshalECPrivateKey(key)
 	if err != nil {
 		return err
 	}
 
-	return ct.writePEMFile(ct.certFileName(opts, certType.KeyFile), "EC PRIVATE KEY", keyBytes, opts.FileMode)
+	return ct.writePEMFile(ct.certFileName(opts, certType.KeyFile), "EC PRIVATE KEY", keyBytes, opts.FileMode, nil)
 }
 
+// applyRegion encodes region into subject province, country is limited
+// to two letter codes and does not fit region identifiers like "eu-west-1".
 func (ct *CertTool) applyRegion(template *x509.Certificate, region string) {
 	region = strings.TrimSpace(region)
 	if region == "" {
 		return
 	}
-	template.Subject.Country = []string{strings.ToUpper(region)}
+	template.Subject.Province = []string{region}
 }
 
 func (ct *CertTool) applyAltNames(template *x509.Certificate, ipAddresses, dnsNames string) {
@@ -496,6 +890,37 @@ func (ct *CertTool) applyAltNames(template *x509.Certificate, ipAddresses, dnsNa
 	}
 }
 
+// checkAltNames ensures server certificate has subject alternative names,
+// TLS clients ignore common name and reject certificates without them.
+func (ct *CertTool) checkAltNames(template *x509.Certificate, opts CertToolGenerateOptions) error {
+	commonName := strings.TrimSpace(template.Subject.CommonName)
+	if opts.CommonNameSAN && commonName != "" {
+		if ip := net.ParseIP(commonName); ip != nil {
+			if !slices.ContainsFunc(template.IPAddresses, ip.Equal) {
+				template.IPAddresses = append(template.IPAddresses, ip)
+			}
+		} else if !slices.Contains(template.DNSNames, commonName) {
+			template.DNSNames = append(template.DNSNames, commonName)
+		}
+	}
+
+	if len(template.DNSNames) > 0 || len(template.IPAddresses) > 0 || len(template.URIs) > 0 {
+		return nil
+	}
+	if len(template.ExtKeyUsage) > 0 &&
+		!slices.Contains(template.ExtKeyUsage, x509.ExtKeyUsageServerAuth) &&
+		!slices.Contains(template.ExtKeyUsage, x509.ExtKeyUsageAny) {
+		return nil
+	}
+	if opts.StrictSAN {
+		return errors.Wrapf(ErrCertMissingSAN, "common name %q", commonName)
+	}
+	log.Warn().
+		Str("common_name", commonName).
+		Msg("server certificate has no subject alternative names, TLS clients will reject it")
+	return nil
+}
+
 func (ct *CertTool) applyKeyUsage(template *x509.Certificate, keyUsage x509.KeyUsage, extKeyUsage []x509.ExtKeyUsage) {
 	if keyUsage != 0 {
 		template.KeyUsage = keyUsage
@@ -577,7 +1002,16 @@ func (ct *CertTool) parsePrivateKey(keyPEM []byte) (*ecdsa.PrivateKey, error) {
 	return x509.ParseECPrivateKey(block.Bytes)
 }
 
-func (ct *CertTool) writePEMFile(path, pemType string, data []byte, mode os.FileMode) error {
+func (ct *CertTool) writePEMFile(path, pemType string, data []byte, mode os.FileMode, owner *CertFileOwner) error {
+	return ct.writeFile(path, pem.EncodeToMemory(&pem.Block{
+		Type:  pemType,
+		Bytes: data,
+	}), mode, owner)
+}
+
+// writeFile atomically replaces file at path with data, mode is applied
+// as is (umask is not involved), DefaultFileMode is used if mode is zero.
+func (ct *CertTool) writeFile(path string, data []byte, mode os.FileMode, owner *CertFileOwner) error {
 	if mode == 0 {
 		mode = DefaultFileMode
 	}
@@ -598,10 +1032,13 @@ func (ct *CertTool) writePEMFile(path, pemType string, data []byte, mode os.File
 	if err != nil {
 		return err
 	}
-	err = pem.Encode(tmpFile, &pem.Block{
-		Type:  pemType,
-		Bytes: data,
-	})
+	if owner != nil {
+		err = tmpFile.Chown(owner.UID, owner.GID)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tmpFile.Write(data)
 	if err != nil {
 		return err
 	}
@@ -613,6 +1050,19 @@ func (ct *CertTool) writePEMFile(path, pemType string, data []byte, mode os.File
 	return os.Rename(tmpFile.Name(), path)
 }
 
+// checkOverwrite refuses to replace existing files unless opts.Force is set.
+func (ct *CertTool) checkOverwrite(opts CertToolGenerateOptions, paths ...string) error {
+	if opts.Force {
+		return nil
+	}
+	for _, path := range paths {
+		if ct.fileExists(path) {
+			return errors.Wrapf(ErrCertFileExists, "refusing to overwrite %q without force", path)
+		}
+	}
+	return nil
+}
+
 func (ct *CertTool) fileExists(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil
@@ -1,6 +1,14 @@
 package auth
 
 import (
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"math/big"
 	"os"
 	"strconv"
@@ -18,6 +26,20 @@ type (
 		setGenerateOptions func(*app.Context, *CertToolGenerateOptions) error
 	}
 	CertAppOption func(*CertApp)
+
+	// CertAppResult describes result of the action, it is printed with "--output json".
+	CertAppResult struct {
+		Action      string     `json:"action"`
+		Files       []string   `json:"files"`
+		Serial      string     `json:"serial,omitempty"`
+		Fingerprint string     `json:"fingerprint,omitempty"`
+		NotAfter    *time.Time `json:"not_after,omitempty"`
+	}
+)
+
+const (
+	CertAppOutputText = "text"
+	CertAppOutputJSON = "json"
 )
 
 const (
@@ -48,6 +70,31 @@ var (
 	}
 
 	CertRevocationReasonsByName = map[string]int{}
+
+	CertKeyUsages = map[string]x509.KeyUsage{
+		"digitalSignature":  x509.KeyUsageDigitalSignature,
+		"contentCommitment": x509.KeyUsageContentCommitment,
+		"keyEncipherment":   x509.KeyUsageKeyEncipherment,
+		"dataEncipherment":  x509.KeyUsageDataEncipherment,
+		"keyAgreement":      x509.KeyUsageKeyAgreement,
+		"certSign":          x509.KeyUsageCertSign,
+		"crlSign":           x509.KeyUsageCRLSign,
+		"encipherOnly":      x509.KeyUsageEncipherOnly,
+		"decipherOnly":      x509.KeyUsageDecipherOnly,
+	}
+
+	CertExtKeyUsages = map[string]x509.ExtKeyUsage{
+		"any":             x509.ExtKeyUsageAny,
+		"serverAuth":      x509.ExtKeyUsageServerAuth,
+		"clientAuth":      x509.ExtKeyUsageClientAuth,
+		"codeSigning":     x509.ExtKeyUsageCodeSigning,
+		"emailProtection": x509.ExtKeyUsageEmailProtection,
+		"ipsecEndSystem":  x509.ExtKeyUsageIPSECEndSystem,
+		"ipsecTunnel":     x509.ExtKeyUsageIPSECTunnel,
+		"ipsecUser":       x509.ExtKeyUsageIPSECUser,
+		"timeStamping":    x509.ExtKeyUsageTimeStamping,
+		"ocspSigning":     x509.ExtKeyUsageOCSPSigning,
+	}
 )
 
 func NewCertApp(opts ...CertAppOption) *CertApp {
@@ -84,6 +131,10 @@ func (*CertApp) Flags() app.Flags {
 			Name:  "name",
 			Usage: "name to prepend to output certificate (eg %name%.ca-cert.pem)",
 		},
+		&app.StringFlag{
+			Name:  "out-dir",
+			Usage: "directory to write output files into (defaults to current directory)",
+		},
 		&app.StringFlag{
 			Name:  "type",
 			Usage: "type of certificate to generate",
@@ -102,15 +153,15 @@ func (*CertApp) Flags() app.Flags {
 		},
 		&app.StringFlag{
 			Name:  "ca-cert",
-			Usage: "path to CA certificate (defaults to ./ca-cert.pem or name prefix)",
+			Usage: "path to CA certificate (defaults to ca-cert.pem in out-dir with name prefix)",
 		},
 		&app.StringFlag{
 			Name:  "ca-key",
-			Usage: "path to CA key (defaults to ./ca-key.pem or name prefix)",
+			Usage: "path to CA key (defaults to ca-key.pem in out-dir with name prefix)",
 		},
 		&app.StringFlag{
 			Name:  "crl",
-			Usage: "path to CRL file (defaults to ./ca-crl.pem or name prefix)",
+			Usage: "path to CRL file (defaults to ca-crl.pem in out-dir with name prefix)",
 		},
 		&app.StringFlag{
 			Name:  "cert-path",
@@ -125,6 +176,10 @@ func (*CertApp) Flags() app.Flags {
 			Usage: "file mode for generated files (octal, e.g. 640)",
 			Value: "640",
 		},
+		&app.StringFlag{
+			Name:  "ca-key-owner",
+			Usage: "numeric owner of generated CA key (uid:gid)",
+		},
 		&app.StringFlag{
 			Name:  "revocation-time",
 			Usage: "revocation time (RFC3339, defaults to now)",
@@ -145,15 +200,52 @@ func (*CertApp) Flags() app.Flags {
 			Name:  "dns-names",
 			Usage: "comma separated list of allowed hostnames to encode into certificate",
 		},
+		&app.StringFlag{
+			Name:  "key-usage",
+			Usage: "comma separated list of key usages (e.g. digitalSignature,keyEncipherment)",
+		},
+		&app.StringFlag{
+			Name:  "ext-key-usage",
+			Usage: "comma separated list of extended key usages (e.g. serverAuth,clientAuth,codeSigning)",
+		},
+		&app.StringSliceFlag{
+			Name:  "extension",
+			Usage: "extra certificate extension as oid=base64value, prefix with critical: to mark it critical",
+		},
 		&app.StringFlag{
 			Name:  "common-name",
 			Usage: "common name for certificate",
 			Value: "localhost",
 		},
+		&app.BoolFlag{
+			Name:  "common-name-san",
+			Usage: "add common name to subject alternative names if it is missing there",
+		},
+		&app.BoolFlag{
+			Name:  "strict-san",
+			Usage: "refuse to generate server certificate without subject alternative names",
+		},
 		&app.StringFlag{
 			Name:  "region",
 			Usage: "region identifier to encode into certificate subject",
 		},
+		&app.BoolFlag{
+			Name:  "bundle",
+			Usage: "also write certificate followed by CA certificate into a single bundle file",
+		},
+		&app.BoolFlag{
+			Name:  "force",
+			Usage: "overwrite existing CA and certificate files",
+		},
+		&app.StringFlag{
+			Name:  "output",
+			Usage: "output format, text or json (prints results of actions as JSON objects)",
+			Value: CertAppOutputText,
+		},
+		&app.BoolFlag{
+			Name:  "dry-run",
+			Usage: "print actions and files which would be written without writing anything",
+		},
 	}
 }
 
@@ -174,6 +266,15 @@ func (a *CertApp) Cert(ctx *app.Context) error {
 	if err != nil {
 		return err
 	}
+	caKeyOwner, err := parseFileOwner(ctx.String("ca-key-owner"))
+	if err != nil {
+		return err
+	}
+
+	output := ctx.String("output")
+	if output != CertAppOutputText && output != CertAppOutputJSON {
+		return errors.Errorf("invalid output %q, expected %s or %s", output, CertAppOutputText, CertAppOutputJSON)
+	}
 
 	if revoke && initCRL {
 		return errors.New("init-crl and revoke are mutually exclusive")
@@ -183,35 +284,78 @@ func (a *CertApp) Cert(ctx *app.Context) error {
 	}
 
 	tool := NewCertTool(a.Registry)
+	dryRun := ctx.Bool("dry-run")
 	if generateCA {
-		err := tool.Generate(CertToolGenerateOptions{
+		opts := CertToolGenerateOptions{
+			OutDir:     ctx.String("out-dir"),
 			NamePrefix: ctx.String("name"),
 			CACertPath: ctx.String("ca-cert"),
 			CAKeyPath:  ctx.String("ca-key"),
 			CommonName: ctx.String("common-name"),
 			Region:     ctx.String("region"),
 			FileMode:   fileMode,
+			CAKeyOwner: caKeyOwner,
 			GenerateCA: true,
-		})
-		if err != nil {
-			return errors.Wrap(err, "error generating CA certificates")
+			Force:      ctx.Bool("force"),
+		}
+		if dryRun {
+			err := a.dryRunGenerate(ctx, tool, "generate-ca", opts)
+			if err != nil {
+				return err
+			}
+		} else {
+			files, err := tool.generateFiles(opts)
+			if err != nil {
+				return err
+			}
+			err = tool.Generate(opts)
+			if err != nil {
+				return errors.Wrap(err, "error generating CA certificates")
+			}
+			log.Info().Msg("generated CA certificate")
+			if output == CertAppOutputJSON {
+				err = a.printResult(ctx, tool, "generate-ca", tool.caCertPath(opts), files)
+				if err != nil {
+					return err
+				}
+			}
 		}
-		log.Info().Msg("generated CA certificate")
 	}
 
 	if initCRL {
-		err := tool.InitCRL(CertToolCRLInitOptions{
+		opts := CertToolCRLInitOptions{
+			OutDir:      ctx.String("out-dir"),
 			NamePrefix:  ctx.String("name"),
 			CACertPath:  ctx.String("ca-cert"),
 			CAKeyPath:   ctx.String("ca-key"),
 			CRLPath:     ctx.String("crl"),
 			CRLValidity: ctx.Duration("crl-validity"),
 			FileMode:    fileMode,
-		})
-		if err != nil {
-			return errors.Wrap(err, "error initializing CRL")
 		}
-		log.Info().Msg("initialized CRL")
+		if dryRun {
+			validity := opts.CRLValidity
+			if validity == 0 {
+				validity = DefaultCRLValidity
+			}
+			a.printDryRun(ctx, "init-crl",
+				"ca", tool.caCertPathWithPrefix(opts.OutDir, opts.NamePrefix, opts.CACertPath),
+				"validity", validity.String(),
+				"files", tool.crlPathWithPrefix(opts.OutDir, opts.NamePrefix, strings.TrimSpace(opts.CRLPath)),
+			)
+		} else {
+			err := tool.InitCRL(opts)
+			if err != nil {
+				return errors.Wrap(err, "error initializing CRL")
+			}
+			log.Info().Msg("initialized CRL")
+			if output == CertAppOutputJSON {
+				crlPath := tool.crlPathWithPrefix(opts.OutDir, opts.NamePrefix, strings.TrimSpace(opts.CRLPath))
+				err = a.printResult(ctx, tool, "init-crl", "", []string{crlPath})
+				if err != nil {
+					return err
+				}
+			}
+		}
 	}
 
 	if revoke {
@@ -246,7 +390,8 @@ func (a *CertApp) Cert(ctx *app.Context) error {
 			return err
 		}
 
-		err = tool.Revoke(CertToolRevokeOptions{
+		opts := CertToolRevokeOptions{
+			OutDir:         ctx.String("out-dir"),
 			NamePrefix:     ctx.String("name"),
 			CACertPath:     ctx.String("ca-cert"),
 			CAKeyPath:      ctx.String("ca-key"),
@@ -257,24 +402,75 @@ func (a *CertApp) Cert(ctx *app.Context) error {
 			RevocationTime: revocationTime,
 			CRLValidity:    crlValidity,
 			FileMode:       fileMode,
-		})
-		if err != nil {
-			return errors.Wrap(err, "error revoking certificate")
 		}
-		log.Info().Msg("revoked certificate")
+		if dryRun {
+			var crlPath string
+			if strings.TrimSpace(opts.CRLPath) != "" {
+				crlPath = tool.crlPathWithPrefix(opts.OutDir, opts.NamePrefix, strings.TrimSpace(opts.CRLPath))
+			}
+			a.printDryRun(ctx, "revoke",
+				"ca", tool.caCertPathWithPrefix(opts.OutDir, opts.NamePrefix, opts.CACertPath),
+				"certificate", opts.CertPath,
+				"serial", opts.SerialNumber,
+				"reason", CertRevocationReasons[opts.ReasonCode],
+				"files", crlPath,
+			)
+		} else {
+			err = tool.Revoke(opts)
+			if err != nil {
+				return errors.Wrap(err, "error revoking certificate")
+			}
+			log.Info().Msg("revoked certificate")
+			if output == CertAppOutputJSON {
+				var files []string
+				if strings.TrimSpace(opts.CRLPath) != "" {
+					files = append(files, tool.crlPathWithPrefix(opts.OutDir, opts.NamePrefix, strings.TrimSpace(opts.CRLPath)))
+				}
+				err = a.printResult(ctx, tool, "revoke", opts.CertPath, files)
+				if err != nil {
+					return err
+				}
+			}
+		}
 	}
 
 	if certType != "" {
+		keyUsage, err := parseKeyUsage(ctx.String("key-usage"))
+		if err != nil {
+			return err
+		}
+		extKeyUsage, err := parseExtKeyUsage(ctx.String("ext-key-usage"))
+		if err != nil {
+			return err
+		}
+		var extensions []pkix.Extension
+		for _, text := range ctx.StringSlice("extension") {
+			extension, err := parseCertExtension(text)
+			if err != nil {
+				return err
+			}
+			extensions = append(extensions, extension)
+		}
+
 		opts := CertToolGenerateOptions{
-			NamePrefix:  ctx.String("name"),
-			Type:        certType,
-			CACertPath:  ctx.String("ca-cert"),
-			CAKeyPath:   ctx.String("ca-key"),
-			FileMode:    fileMode,
-			IPAddresses: ctx.String("ip-addresses"),
-			DNSNames:    ctx.String("dns-names"),
-			CommonName:  ctx.String("common-name"),
-			Region:      ctx.String("region"),
+			OutDir:          ctx.String("out-dir"),
+			NamePrefix:      ctx.String("name"),
+			Type:            certType,
+			CACertPath:      ctx.String("ca-cert"),
+			CAKeyPath:       ctx.String("ca-key"),
+			FileMode:        fileMode,
+			CAKeyOwner:      caKeyOwner,
+			IPAddresses:     ctx.String("ip-addresses"),
+			DNSNames:        ctx.String("dns-names"),
+			CommonName:      ctx.String("common-name"),
+			Region:          ctx.String("region"),
+			KeyUsage:        keyUsage,
+			ExtKeyUsage:     extKeyUsage,
+			ExtraExtensions: extensions,
+			CommonNameSAN:   ctx.Bool("common-name-san"),
+			StrictSAN:       ctx.Bool("strict-san"),
+			Bundle:          ctx.Bool("bundle"),
+			Force:           ctx.Bool("force"),
 		}
 		if a.setGenerateOptions != nil {
 			err := a.setGenerateOptions(ctx, &opts)
@@ -283,16 +479,115 @@ func (a *CertApp) Cert(ctx *app.Context) error {
 			}
 		}
 
-		err := tool.Generate(opts)
+		if dryRun {
+			err := a.dryRunGenerate(ctx, tool, "generate", opts)
+			if err != nil {
+				return err
+			}
+		} else {
+			files, err := tool.generateFiles(opts)
+			if err != nil {
+				return err
+			}
+			err = tool.Generate(opts)
+			if err != nil {
+				return errors.Wrap(err, "error generating certificates")
+			}
+			log.Info().Msg("generated certificate")
+			if output == CertAppOutputJSON {
+				certType, err := tool.Lookup(opts.Type)
+				if err != nil {
+					return err
+				}
+				err = a.printResult(ctx, tool, "generate", tool.certFileName(opts, certType.CertFile), files)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (a *CertApp) dryRunGenerate(ctx *app.Context, tool *CertTool, action string, opts CertToolGenerateOptions) error {
+	files, err := tool.generateFiles(opts)
+	if err != nil {
+		return err
+	}
+
+	template := &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName: opts.CommonName,
+		},
+	}
+	tool.applyRegion(template, opts.Region)
+	tool.applyAltNames(template, opts.IPAddresses, opts.DNSNames)
+	if !opts.GenerateCA {
+		tool.applyKeyUsage(template, opts.KeyUsage, opts.ExtKeyUsage)
+		err = tool.checkAltNames(template, opts)
 		if err != nil {
-			return errors.Wrap(err, "error generating certificates")
+			return err
 		}
-		log.Info().Msg("generated certificate")
 	}
 
+	ipAddresses := make([]string, 0, len(template.IPAddresses))
+	for _, ip := range template.IPAddresses {
+		ipAddresses = append(ipAddresses, ip.String())
+	}
+
+	a.printDryRun(ctx, action,
+		"type", opts.Type,
+		"subject", template.Subject.String(),
+		"dns-names", strings.Join(template.DNSNames, ", "),
+		"ip-addresses", strings.Join(ipAddresses, ", "),
+		"capabilities", strings.Join(opts.Capabilities, ", "),
+		"validity", fmt.Sprintf("%d years", CertValidityYears),
+		"files", strings.Join(files, ", "),
+	)
 	return nil
 }
 
+// printResult writes JSON encoded CertAppResult, serial, fingerprint
+// and expiration are taken from certificate at certPath if it is set.
+func (*CertApp) printResult(ctx *app.Context, tool *CertTool, action string, certPath string, files []string) error {
+	result := CertAppResult{
+		Action: action,
+		Files:  files,
+	}
+	if result.Files == nil {
+		result.Files = []string{}
+	}
+	if certPath != "" {
+		certPEM, err := os.ReadFile(certPath)
+		if err != nil {
+			return err
+		}
+		cert, err := tool.parseCert(certPEM)
+		if err != nil {
+			return err
+		}
+		fingerprint := sha256.Sum256(cert.Raw)
+		result.Serial = cert.SerialNumber.String()
+		result.Fingerprint = hex.EncodeToString(fingerprint[:])
+		result.NotAfter = &cert.NotAfter
+	}
+
+	return json.NewEncoder(ctx.App.Writer).Encode(result)
+}
+
+// printDryRun writes action description with non-empty key/value fields.
+func (*CertApp) printDryRun(ctx *app.Context, action string, fields ...string) {
+	w := ctx.App.Writer
+	_, _ = fmt.Fprintf(w, "dry-run: %s\n", action)
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i+1] == "" {
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "  %s: %s\n", fields[i], fields[i+1])
+	}
+}
+
 func (*CertApp) parseRevocationReason(reason string) (int, error) {
 	if reason == "" {
 		return CertRevocationReasonUnspecified, nil
@@ -304,6 +599,72 @@ func (*CertApp) parseRevocationReason(reason string) (int, error) {
 	return 0, errors.Errorf("invalid revocation reason: %s", reason)
 }
 
+// parseKeyUsage parses comma separated names of CertKeyUsages.
+func parseKeyUsage(text string) (x509.KeyUsage, error) {
+	var usage x509.KeyUsage
+	for _, name := range strings.Split(text, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		v, ok := CertKeyUsages[name]
+		if !ok {
+			return 0, errors.Errorf("invalid key usage: %s", name)
+		}
+		usage |= v
+	}
+	return usage, nil
+}
+
+// parseExtKeyUsage parses comma separated names of CertExtKeyUsages.
+func parseExtKeyUsage(text string) ([]x509.ExtKeyUsage, error) {
+	var usages []x509.ExtKeyUsage
+	for _, name := range strings.Split(text, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		v, ok := CertExtKeyUsages[name]
+		if !ok {
+			return nil, errors.Errorf("invalid extended key usage: %s", name)
+		}
+		usages = append(usages, v)
+	}
+	return usages, nil
+}
+
+// parseCertExtension parses extension in [critical:]oid=base64value form.
+func parseCertExtension(text string) (pkix.Extension, error) {
+	var extension pkix.Extension
+	text = strings.TrimSpace(text)
+	rest, critical := strings.CutPrefix(text, "critical:")
+	oidText, valueText, ok := strings.Cut(rest, "=")
+	if !ok {
+		return extension, errors.Errorf("invalid extension %q, expected oid=base64value", text)
+	}
+
+	var oid asn1.ObjectIdentifier
+	for _, part := range strings.Split(strings.TrimSpace(oidText), ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return extension, errors.Errorf("invalid extension oid %q", oidText)
+		}
+		oid = append(oid, n)
+	}
+	if len(oid) < 2 {
+		return extension, errors.Errorf("invalid extension oid %q", oidText)
+	}
+	value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(valueText))
+	if err != nil {
+		return extension, errors.Wrapf(err, "invalid extension %s value", oidText)
+	}
+
+	extension.Id = oid
+	extension.Critical = critical
+	extension.Value = value
+	return extension, nil
+}
+
 func parseFileMode(text string) (os.FileMode, error) {
 	text = strings.TrimSpace(text)
 	if text == "" {
@@ -315,3 +676,27 @@ func parseFileMode(text string) (os.FileMode, error) {
 	}
 	return os.FileMode(value), nil
 }
+
+func parseFileOwner(text string) (*CertFileOwner, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+	uid, gid, ok := strings.Cut(text, ":")
+	if !ok {
+		return nil, errors.Errorf("invalid owner %q, expected uid:gid", text)
+	}
+	var (
+		owner CertFileOwner
+		err   error
+	)
+	owner.UID, err = strconv.Atoi(uid)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid owner uid %q", uid)
+	}
+	owner.GID, err = strconv.Atoi(gid)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid owner gid %q", gid)
+	}
+	return &owner, nil
+}
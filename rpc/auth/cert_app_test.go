@@ -0,0 +1,320 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+)
+
+func runTestCertApp(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	registry := NewCertTypeRegistry()
+	require.NoError(t, registry.Register("server", CertType{
+		KeyFile:  "server-key.pem",
+		CertFile: "server-cert.pem",
+	}))
+
+	var buf bytes.Buffer
+	cliApp := cli.NewApp()
+	cliApp.Writer = &buf
+	cliApp.Commands = []*cli.Command{NewCertApp(WithCertAppRegistry(registry)).Command()}
+
+	err := cliApp.Run(append([]string{"atlas", "cert"}, args...))
+	return buf.String(), err
+}
+
+func TestCertAppDryRun(t *testing.T) {
+	dir := t.TempDir()
+	prefix := filepath.Join(dir, "test")
+
+	out, err := runTestCertApp(t,
+		"--dry-run",
+		"--name", prefix,
+		"--generate-ca",
+		"--init-crl",
+		"--type", "server",
+		"--dns-names", "localhost",
+		"--ip-addresses", "127.0.0.1",
+	)
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	assert.Contains(t, out, "dry-run: generate-ca")
+	assert.Contains(t, out, "dry-run: init-crl")
+	assert.Contains(t, out, "dry-run: generate\n")
+	assert.Contains(t, out, prefix+"."+CACertFile)
+	assert.Contains(t, out, prefix+"."+CAKeyFile)
+	assert.Contains(t, out, prefix+"."+CRLFile)
+	assert.Contains(t, out, prefix+".server-cert.pem")
+	assert.Contains(t, out, prefix+".server-key.pem")
+	assert.Contains(t, out, "CN=localhost")
+	assert.Contains(t, out, "127.0.0.1")
+}
+
+func TestCertAppOverwrite(t *testing.T) {
+	prefix := filepath.Join(t.TempDir(), "test")
+
+	t.Run("ca", func(t *testing.T) {
+		_, err := runTestCertApp(t, "--name", prefix, "--generate-ca")
+		require.NoError(t, err)
+		caCert, err := os.ReadFile(prefix + "." + CACertFile)
+		require.NoError(t, err)
+
+		_, err = runTestCertApp(t, "--name", prefix, "--generate-ca")
+		assert.ErrorIs(t, err, ErrCertFileExists)
+		unchanged, err := os.ReadFile(prefix + "." + CACertFile)
+		require.NoError(t, err)
+		assert.Equal(t, caCert, unchanged)
+
+		_, err = runTestCertApp(t, "--name", prefix, "--generate-ca", "--force")
+		require.NoError(t, err)
+		regenerated, err := os.ReadFile(prefix + "." + CACertFile)
+		require.NoError(t, err)
+		assert.NotEqual(t, caCert, regenerated)
+	})
+
+	t.Run("certificate", func(t *testing.T) {
+		_, err := runTestCertApp(t, "--name", prefix, "--type", "server")
+		require.NoError(t, err)
+
+		_, err = runTestCertApp(t, "--name", prefix, "--type", "server")
+		assert.ErrorIs(t, err, ErrCertFileExists)
+
+		_, err = runTestCertApp(t, "--name", prefix, "--type", "server", "--force")
+		require.NoError(t, err)
+	})
+}
+
+func TestCertAppOutDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "certs")
+
+	_, err := runTestCertApp(t, "--out-dir", dir, "--name", "test", "--generate-ca", "--init-crl")
+	require.NoError(t, err)
+	_, err = runTestCertApp(t, "--out-dir", dir, "--name", "test", "--type", "server")
+	require.NoError(t, err)
+
+	for _, name := range []string{
+		"test." + CACertFile,
+		"test." + CAKeyFile,
+		"test." + CRLFile,
+		"test." + SerialFile,
+		"test.server-cert.pem",
+		"test.server-key.pem",
+	} {
+		assert.FileExists(t, filepath.Join(dir, name))
+	}
+	assert.NoFileExists(t, "test."+CACertFile)
+}
+
+func TestCertAppMode(t *testing.T) {
+	dir := t.TempDir()
+	args := func(args ...string) []string {
+		return append([]string{"--out-dir", dir, "--mode", "600"}, args...)
+	}
+
+	_, err := runTestCertApp(t, args("--generate-ca", "--init-crl")...)
+	require.NoError(t, err)
+	_, err = runTestCertApp(t, args("--type", "server")...)
+	require.NoError(t, err)
+
+	require.NoError(t, os.Chmod(filepath.Join(dir, CRLFile), 0o644))
+	_, err = runTestCertApp(t, args("--revoke", "--crl", filepath.Join(dir, CRLFile), "--cert-path", filepath.Join(dir, "server-cert.pem"))...)
+	require.NoError(t, err)
+
+	for _, name := range []string{CACertFile, CAKeyFile, CRLFile, SerialFile, "server-cert.pem", "server-key.pem"} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), name)
+	}
+
+	_, err = runTestCertApp(t, "--out-dir", dir, "--mode", "9", "--generate-ca")
+	assert.ErrorContains(t, err, "invalid mode")
+}
+
+func TestCertAppRegion(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := runTestCertApp(t, "--out-dir", dir, "--generate-ca", "--region", "eu-west-1")
+	require.NoError(t, err)
+	_, err = runTestCertApp(t, "--out-dir", dir, "--type", "server", "--region", "eu-west-1")
+	require.NoError(t, err)
+
+	tool := NewCertTool(nil)
+	for _, name := range []string{CACertFile, "server-cert.pem"} {
+		certPEM, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		cert, err := tool.parseCert(certPEM)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"eu-west-1"}, cert.Subject.Province, name)
+		assert.Contains(t, cert.Subject.String(), "ST=eu-west-1", name)
+	}
+
+	out, err := runTestCertApp(t, "--out-dir", dir, "--type", "server", "--region", "us-east-1", "--dry-run")
+	require.NoError(t, err)
+	assert.Contains(t, out, "ST=us-east-1")
+}
+
+func TestCertAppBundle(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := runTestCertApp(t, "--out-dir", dir, "--type", "server", "--bundle")
+	require.NoError(t, err)
+
+	readPEM := func(t *testing.T, name string) []byte {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		return data
+	}
+	bundle := readPEM(t, "server-cert.bundle.pem")
+	var blocks [][]byte
+	for {
+		var block *pem.Block
+		block, bundle = pem.Decode(bundle)
+		if block == nil {
+			break
+		}
+		assert.Equal(t, "CERTIFICATE", block.Type)
+		blocks = append(blocks, pem.EncodeToMemory(block))
+	}
+	require.Len(t, blocks, 2)
+	assert.Equal(t, readPEM(t, "server-cert.pem"), blocks[0], "leaf goes first")
+	assert.Equal(t, readPEM(t, CACertFile), blocks[1])
+
+	out, err := runTestCertApp(t, "--out-dir", dir, "--type", "server", "--bundle", "--dry-run")
+	require.NoError(t, err)
+	assert.Contains(t, out, filepath.Join(dir, "server-cert.bundle.pem"))
+}
+
+func TestParseKeyUsage(t *testing.T) {
+	usage, err := parseKeyUsage("digitalSignature, keyEncipherment")
+	require.NoError(t, err)
+	assert.Equal(t, x509.KeyUsageDigitalSignature|x509.KeyUsageKeyEncipherment, usage)
+
+	extUsage, err := parseExtKeyUsage("serverAuth,codeSigning")
+	require.NoError(t, err)
+	assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageCodeSigning}, extUsage)
+
+	usage, err = parseKeyUsage("")
+	require.NoError(t, err)
+	assert.Zero(t, usage)
+	extUsage, err = parseExtKeyUsage("")
+	require.NoError(t, err)
+	assert.Nil(t, extUsage)
+
+	_, err = parseKeyUsage("digitalSignature,signing")
+	assert.ErrorContains(t, err, "invalid key usage: signing")
+	_, err = parseExtKeyUsage("serverauth")
+	assert.ErrorContains(t, err, "invalid extended key usage: serverauth")
+}
+
+func TestCertAppKeyUsage(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := runTestCertApp(t, "--out-dir", dir, "--type", "server",
+		"--key-usage", "digitalSignature", "--ext-key-usage", "codeSigning")
+	require.NoError(t, err)
+
+	certPEM, err := os.ReadFile(filepath.Join(dir, "server-cert.pem"))
+	require.NoError(t, err)
+	cert, err := NewCertTool(nil).parseCert(certPEM)
+	require.NoError(t, err)
+	assert.Equal(t, x509.KeyUsageDigitalSignature, cert.KeyUsage)
+	assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}, cert.ExtKeyUsage)
+
+	_, err = runTestCertApp(t, "--out-dir", dir, "--type", "server", "--force", "--ext-key-usage", "signing")
+	assert.ErrorContains(t, err, "invalid extended key usage")
+}
+
+func TestCertAppOutputJSON(t *testing.T) {
+	dir := t.TempDir()
+
+	out, err := runTestCertApp(t, "--out-dir", dir, "--generate-ca", "--type", "server", "--dns-names", "localhost", "--output", "json")
+	require.NoError(t, err)
+
+	var results []CertAppResult
+	dec := json.NewDecoder(strings.NewReader(out))
+	for dec.More() {
+		var result CertAppResult
+		require.NoError(t, dec.Decode(&result))
+		results = append(results, result)
+	}
+	require.Len(t, results, 2)
+
+	tool := NewCertTool(nil)
+	for n, expected := range []struct {
+		action string
+		cert   string
+		files  []string
+	}{
+		{"generate-ca", CACertFile, []string{CACertFile, CAKeyFile, SerialFile}},
+		{"generate", "server-cert.pem", []string{"server-cert.pem", "server-key.pem", SerialFile}},
+	} {
+		certPEM, err := os.ReadFile(filepath.Join(dir, expected.cert))
+		require.NoError(t, err)
+		cert, err := tool.parseCert(certPEM)
+		require.NoError(t, err)
+
+		result := results[n]
+		assert.Equal(t, expected.action, result.Action)
+		assert.Equal(t, cert.SerialNumber.String(), result.Serial)
+		assert.Len(t, result.Fingerprint, 64)
+		require.NotNil(t, result.NotAfter)
+		assert.True(t, cert.NotAfter.Equal(*result.NotAfter))
+		for _, name := range expected.files {
+			assert.Contains(t, result.Files, filepath.Join(dir, name))
+		}
+	}
+
+	_, err = runTestCertApp(t, "--out-dir", dir, "--type", "server", "--output", "yaml")
+	assert.ErrorContains(t, err, "invalid output")
+}
+
+func TestParseCertExtension(t *testing.T) {
+	extension, err := parseCertExtension("1.3.6.1.4.1.99999.1=dGVuYW50")
+	require.NoError(t, err)
+	assert.Equal(t, pkix.Extension{
+		Id:    asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1},
+		Value: []byte("tenant"),
+	}, extension)
+
+	extension, err = parseCertExtension("critical:1.2.3=dGVuYW50")
+	require.NoError(t, err)
+	assert.True(t, extension.Critical)
+
+	for _, text := range []string{"1.2.3", "1=dGVuYW50", "1.a.3=dGVuYW50", "1.2.3=not base64"} {
+		_, err = parseCertExtension(text)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestCertAppExtension(t *testing.T) {
+	dir := t.TempDir()
+	tenantOID := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}
+
+	_, err := runTestCertApp(t, "--out-dir", dir, "--type", "server",
+		"--extension", tenantOID.String()+"="+base64.StdEncoding.EncodeToString([]byte("tenant")))
+	require.NoError(t, err)
+
+	certPEM, err := os.ReadFile(filepath.Join(dir, "server-cert.pem"))
+	require.NoError(t, err)
+	cert, err := NewCertTool(nil).parseCert(certPEM)
+	require.NoError(t, err)
+	assert.Contains(t, cert.Extensions, pkix.Extension{Id: tenantOID, Value: []byte("tenant")})
+}
@@ -0,0 +1,296 @@
+package auth
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.tatikoma.dev/corpix/atlas/log"
+)
+
+func TestCertToolGenerateConcurrent(t *testing.T) {
+	const types = 8
+
+	registry := NewCertTypeRegistry()
+	for n := range types {
+		name := fmt.Sprintf("type%d", n)
+		require.NoError(t, registry.Register(name, CertType{
+			KeyFile:  name + "-key.pem",
+			CertFile: name + "-cert.pem",
+		}))
+	}
+	tool := NewCertTool(registry)
+	dir := t.TempDir()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, types)
+	for n := range types {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- tool.Generate(CertToolGenerateOptions{
+				OutDir:     dir,
+				Type:       fmt.Sprintf("type%d", n),
+				CommonName: "localhost",
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	caCert, _, err := tool.readCAFiles(filepath.Join(dir, CACertFile), filepath.Join(dir, CAKeyFile))
+	require.NoError(t, err)
+
+	serials := map[string]struct{}{}
+	for n := range types {
+		certPEM, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("type%d-cert.pem", n)))
+		require.NoError(t, err)
+		cert, err := tool.parseCert(certPEM)
+		require.NoError(t, err)
+		require.NoError(t, cert.CheckSignatureFrom(caCert))
+		serials[cert.SerialNumber.String()] = struct{}{}
+	}
+	assert.Len(t, serials, types)
+}
+
+func TestCertToolFileMode(t *testing.T) {
+	registry := NewCertTypeRegistry()
+	require.NoError(t, registry.Register("server", CertType{
+		KeyFile:  "server-key.pem",
+		CertFile: "server-cert.pem",
+	}))
+	tool := NewCertTool(registry)
+
+	for _, mode := range []os.FileMode{0o600, 0o660} {
+		t.Run(mode.String(), func(t *testing.T) {
+			dir := t.TempDir()
+			owner := &CertFileOwner{UID: os.Getuid(), GID: os.Getgid()}
+			require.NoError(t, tool.Generate(CertToolGenerateOptions{
+				OutDir:     dir,
+				Type:       "server",
+				CommonName: "localhost",
+				FileMode:   mode,
+				CAKeyOwner: owner,
+			}))
+			require.NoError(t, tool.InitCRL(CertToolCRLInitOptions{
+				OutDir:   dir,
+				FileMode: mode,
+			}))
+
+			for _, name := range []string{CACertFile, CAKeyFile, CRLFile, SerialFile, "server-cert.pem", "server-key.pem"} {
+				info, err := os.Stat(filepath.Join(dir, name))
+				require.NoError(t, err)
+				assert.Equal(t, mode, info.Mode().Perm(), name)
+			}
+		})
+	}
+
+	t.Run("default", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, tool.Generate(CertToolGenerateOptions{OutDir: dir, GenerateCA: true}))
+		info, err := os.Stat(filepath.Join(dir, CAKeyFile))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(DefaultFileMode), info.Mode().Perm())
+	})
+}
+
+func TestParseFileOwner(t *testing.T) {
+	owner, err := parseFileOwner("1000:1001")
+	require.NoError(t, err)
+	assert.Equal(t, &CertFileOwner{UID: 1000, GID: 1001}, owner)
+
+	owner, err = parseFileOwner("")
+	require.NoError(t, err)
+	assert.Nil(t, owner)
+
+	for _, text := range []string{"1000", "user:1000", "1000:group"} {
+		_, err = parseFileOwner(text)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestCertToolRotateCA(t *testing.T) {
+	registry := NewCertTypeRegistry()
+	require.NoError(t, registry.Register("server", CertType{
+		KeyFile:  "server-key.pem",
+		CertFile: "server-cert.pem",
+	}))
+	tool := NewCertTool(registry)
+	dir := t.TempDir()
+
+	require.NoError(t, tool.Generate(CertToolGenerateOptions{
+		OutDir:       dir,
+		Type:         "server",
+		CommonName:   "localhost",
+		Region:       "eu-west-1",
+		DNSNames:     "localhost,example.com",
+		IPAddresses:  "127.0.0.1",
+		Capabilities: []string{"read", "write"},
+		ExtraExtensions: []pkix.Extension{
+			{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}, Value: []byte{0x0c, 0x04, 't', 'e', 's', 't'}},
+		},
+	}))
+	readCert := func(t *testing.T, name string) *x509.Certificate {
+		t.Helper()
+		certPEM, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		cert, err := tool.parseCert(certPEM)
+		require.NoError(t, err)
+		return cert
+	}
+	oldCA := readCert(t, CACertFile)
+	oldLeaf := readCert(t, "server-cert.pem")
+
+	require.NoError(t, tool.RotateCA(CertToolRotateCAOptions{OutDir: dir, CrossSign: true}))
+
+	newCA := readCert(t, CACertFile)
+	assert.NotEqual(t, oldCA.SubjectKeyId, newCA.SubjectKeyId)
+	assert.Equal(t, oldCA.Subject.String(), newCA.Subject.String())
+	assert.Equal(t, oldCA.Raw, readCert(t, "ca-cert.prev.pem").Raw)
+	_, _, err := tool.readCAFiles(filepath.Join(dir, "ca-cert.prev.pem"), filepath.Join(dir, "ca-key.prev.pem"))
+	require.NoError(t, err)
+	assert.Error(t, oldLeaf.CheckSignatureFrom(newCA))
+
+	require.NoError(t, tool.Reissue(CertToolReissueOptions{OutDir: dir, CertPath: "server-cert.pem"}))
+
+	leaf := readCert(t, "server-cert.pem")
+	require.NoError(t, leaf.CheckSignatureFrom(newCA))
+	assert.Equal(t, oldLeaf.Subject.String(), leaf.Subject.String())
+	assert.Equal(t, oldLeaf.DNSNames, leaf.DNSNames)
+	assert.Equal(t, oldLeaf.IPAddresses, leaf.IPAddresses)
+	assert.Equal(t, oldLeaf.PublicKey, leaf.PublicKey)
+	assert.NotEqual(t, oldLeaf.SerialNumber, leaf.SerialNumber)
+	assert.Contains(t, leaf.Extensions, pkix.Extension{
+		Id:    CapabilitiesCertificateOID,
+		Value: extensionValue(t, oldLeaf, CapabilitiesCertificateOID),
+	})
+	assert.Contains(t, leaf.Extensions, pkix.Extension{
+		Id:    asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1},
+		Value: extensionValue(t, oldLeaf, asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}),
+	})
+	for _, ext := range leaf.Extensions {
+		assert.Equal(t, 1, countExtensions(leaf, ext.Id), "extension %v is duplicated", ext.Id)
+	}
+
+	t.Run("cross signed", func(t *testing.T) {
+		roots, intermediates := x509.NewCertPool(), x509.NewCertPool()
+		roots.AddCert(oldCA)
+		intermediates.AddCert(readCert(t, "ca-cert.cross.pem"))
+		_, err := leaf.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			DNSName:       "example.com",
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("previous CA backup is kept without force", func(t *testing.T) {
+		err := tool.RotateCA(CertToolRotateCAOptions{OutDir: dir})
+		require.ErrorIs(t, err, ErrCertFileExists)
+		assert.Equal(t, newCA.Raw, readCert(t, CACertFile).Raw)
+		assert.Equal(t, oldCA.Raw, readCert(t, "ca-cert.prev.pem").Raw)
+
+		require.NoError(t, tool.RotateCA(CertToolRotateCAOptions{OutDir: dir, Force: true}))
+		assert.Equal(t, newCA.Raw, readCert(t, "ca-cert.prev.pem").Raw)
+	})
+}
+
+func countExtensions(cert *x509.Certificate, id asn1.ObjectIdentifier) int {
+	var n int
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(id) {
+			n++
+		}
+	}
+	return n
+}
+
+func extensionValue(t *testing.T, cert *x509.Certificate, id asn1.ObjectIdentifier) []byte {
+	t.Helper()
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(id) {
+			return ext.Value
+		}
+	}
+	t.Fatalf("extension %v is missing", id)
+	return nil
+}
+
+func TestCertToolCheckAltNames(t *testing.T) {
+	tool := NewCertTool(nil)
+	check := func(t *testing.T, commonName string, opts CertToolGenerateOptions, extKeyUsage ...x509.ExtKeyUsage) (*x509.Certificate, string, error) {
+		t.Helper()
+		buf, restore := log.Capture()
+		defer restore()
+
+		template := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+		tool.applyKeyUsage(template, 0, extKeyUsage)
+		err := tool.checkAltNames(template, opts)
+		return template, buf.String(), err
+	}
+
+	t.Run("warning", func(t *testing.T) {
+		_, out, err := check(t, "localhost", CertToolGenerateOptions{})
+		require.NoError(t, err)
+		assert.Contains(t, out, "no subject alternative names")
+
+		_, out, err = check(t, "localhost", CertToolGenerateOptions{}, x509.ExtKeyUsageClientAuth)
+		require.NoError(t, err)
+		assert.Empty(t, out, "client certificates do not need alternative names")
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		_, _, err := check(t, "localhost", CertToolGenerateOptions{StrictSAN: true}, x509.ExtKeyUsageServerAuth)
+		assert.ErrorIs(t, err, ErrCertMissingSAN)
+	})
+
+	t.Run("common name", func(t *testing.T) {
+		template, out, err := check(t, "localhost", CertToolGenerateOptions{CommonNameSAN: true, StrictSAN: true})
+		require.NoError(t, err)
+		assert.Empty(t, out)
+		assert.Equal(t, []string{"localhost"}, template.DNSNames)
+
+		template, _, err = check(t, "127.0.0.1", CertToolGenerateOptions{CommonNameSAN: true})
+		require.NoError(t, err)
+		assert.Empty(t, template.DNSNames)
+		require.Len(t, template.IPAddresses, 1)
+		assert.Equal(t, "127.0.0.1", template.IPAddresses[0].String())
+	})
+
+	t.Run("generate", func(t *testing.T) {
+		registry := NewCertTypeRegistry()
+		require.NoError(t, registry.Register("server", CertType{
+			KeyFile:  "server-key.pem",
+			CertFile: "server-cert.pem",
+		}))
+		tool := NewCertTool(registry)
+		dir := t.TempDir()
+
+		err := tool.Generate(CertToolGenerateOptions{OutDir: dir, Type: "server", CommonName: "localhost", StrictSAN: true})
+		require.ErrorIs(t, err, ErrCertMissingSAN)
+		require.NoError(t, tool.Generate(CertToolGenerateOptions{
+			OutDir:        dir,
+			Type:          "server",
+			CommonName:    "localhost",
+			StrictSAN:     true,
+			CommonNameSAN: true,
+		}))
+
+		certPEM, err := os.ReadFile(filepath.Join(dir, "server-cert.pem"))
+		require.NoError(t, err)
+		cert, err := tool.parseCert(certPEM)
+		require.NoError(t, err)
+		assert.NoError(t, cert.VerifyHostname("localhost"))
+	})
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	CSRFHeaderName = "X-CSRF-Token"
+	CSRFFormField  = "csrf_token"
+)
+
+type (
+	csrf                struct{}
+	csrfTokenContextKey void
+)
+
+var (
+	CSRFCookieName = "csrf_" + hex.EncodeToString(fnv.New64a().Sum([]byte(fmt.Sprintf("%T", csrf{}))))[:8]
+
+	CSRFTokenContextKey csrfTokenContextKey
+)
+
+// CSRFToken returns token which should be submitted with unsafe requests
+// in CSRFHeaderName header or CSRFFormField form field.
+func CSRFToken(ctx context.Context) string {
+	token, _ := ctx.Value(CSRFTokenContextKey).(string)
+	return token
+}
+
+// CSRF protects unsafe requests (other than GET, HEAD, OPTIONS, TRACE) with
+// double submit cookie, token from the cookie must be repeated in the request header
+// or form field. Exempt paths are matched exactly, paths ending with slash match by prefix.
+func (h *HTTP) CSRF(next http.Handler, exempt ...string) http.Handler {
+	isExempt := func(path string) bool {
+		for _, e := range exempt {
+			if path == e || (strings.HasSuffix(e, "/") && strings.HasPrefix(path, e)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var value string
+		if c, err := r.Cookie(CSRFCookieName); err == nil && c.Value != "" {
+			value = c.Value
+		}
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			if value == "" {
+				var err error
+				value, err = randString(32)
+				if err != nil {
+					http.Error(w, "internal error", http.StatusInternalServerError)
+					return
+				}
+				http.SetCookie(w, &http.Cookie{
+					Name:     CSRFCookieName,
+					Value:    value,
+					Secure:   r.TLS != nil,
+					SameSite: http.SameSiteStrictMode,
+					Path:     "/",
+				})
+			}
+		default:
+			if isExempt(r.URL.Path) {
+				break
+			}
+			submitted := r.Header.Get(CSRFHeaderName)
+			if submitted == "" {
+				submitted = r.PostFormValue(CSRFFormField)
+			}
+			if value == "" || subtle.ConstantTimeCompare([]byte(value), []byte(submitted)) != 1 {
+				log.Warn().
+					Str("method", r.Method).
+					Str("path", r.URL.Path).
+					Msg("csrf token did not match")
+				http.Error(w, "csrf token did not match", http.StatusForbidden)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), CSRFTokenContextKey, value)))
+	})
+}
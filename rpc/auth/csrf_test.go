@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSRF(t *testing.T) {
+	var token string
+	handler := (&Auth{}).HTTP().CSRF(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = CSRFToken(r.Context())
+	}), "/hooks/")
+
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve(httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	cookie := cookies[0]
+	assert.Equal(t, CSRFCookieName, cookie.Name)
+	assert.Equal(t, cookie.Value, token)
+
+	post := func(body string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.AddCookie(cookie)
+		return r
+	}
+
+	t.Run("legitimate post with header", func(t *testing.T) {
+		r := post("")
+		r.Header.Set(CSRFHeaderName, cookie.Value)
+		assert.Equal(t, http.StatusOK, serve(r).Code)
+	})
+
+	t.Run("legitimate post with form field", func(t *testing.T) {
+		r := post(url.Values{CSRFFormField: {cookie.Value}}.Encode())
+		assert.Equal(t, http.StatusOK, serve(r).Code)
+	})
+
+	t.Run("forged post", func(t *testing.T) {
+		r := post("")
+		r.Header.Set(CSRFHeaderName, "forged")
+		assert.Equal(t, http.StatusForbidden, serve(r).Code)
+	})
+
+	t.Run("post without cookie", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/items", nil)
+		r.Header.Set(CSRFHeaderName, cookie.Value)
+		assert.Equal(t, http.StatusForbidden, serve(r).Code)
+	})
+
+	t.Run("exempt path", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/hooks/deploy", nil)
+		assert.Equal(t, http.StatusOK, serve(r).Code)
+	})
+}
@@ -2,10 +2,15 @@ package auth
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/asn1"
 	"encoding/json"
+	"maps"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -18,6 +23,41 @@ import (
 	"git.tatikoma.dev/corpix/protoc-gen-grpc-capabilities/capabilities"
 )
 
+const (
+	SPIFFEScheme = "spiffe"
+
+	capabilitiesNotSatisfiedFormat = "required client capability set for %q not satisfied, has: %s, want: %s"
+)
+
+var capabilitiesNotSatisfiedRegexp = regexp.MustCompile(`^required client capability set for ("(?:[^"\\]|\\.)*") not satisfied, has: (.*), want: (.*)$`)
+
+// CapabilitiesNotSatisfied is a hint parsed from the status of the call rejected by ACL.
+type CapabilitiesNotSatisfied struct {
+	Method string
+	Has    string
+	Want   string
+}
+
+// ParseCapabilitiesNotSatisfied extracts capabilities hint from the error returned
+// by server when client capability set does not satisfy method rule.
+func ParseCapabilitiesNotSatisfied(err error) (CapabilitiesNotSatisfied, bool) {
+	var hint CapabilitiesNotSatisfied
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.InvalidArgument {
+		return hint, false
+	}
+	match := capabilitiesNotSatisfiedRegexp.FindStringSubmatch(st.Message())
+	if match == nil {
+		return hint, false
+	}
+	method, unquoteErr := strconv.Unquote(match[1])
+	if unquoteErr != nil {
+		return hint, false
+	}
+	hint.Method, hint.Has, hint.Want = method, match[2], match[3]
+	return hint, true
+}
+
 type GRPC struct {
 	auth *Auth
 }
@@ -36,6 +76,9 @@ func (g *GRPC) ServerOption() grpc.ServerOption {
 
 func (g *GRPC) UnaryInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if g.isPublic(ctx, info.FullMethod) {
+			return handler(ctx, req)
+		}
 		handlerCtx, err := g.authenticateGrpcContext(ctx)
 		if err != nil {
 			return nil, err
@@ -50,6 +93,9 @@ func (g *GRPC) UnaryInterceptor() grpc.UnaryServerInterceptor {
 
 func (g *GRPC) StreamInterceptor() grpc.StreamServerInterceptor {
 	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if g.isPublic(ss.Context(), info.FullMethod) {
+			return handler(srv, ss)
+		}
 		handlerCtx, err := g.authenticateGrpcContext(ss.Context())
 		if err != nil {
 			return err
@@ -58,13 +104,55 @@ func (g *GRPC) StreamInterceptor() grpc.StreamServerInterceptor {
 		if err != nil {
 			return err
 		}
-		return handler(srv, &streamWithCtx{
+		token, ok := handlerCtx.Value(TokenContextKey).(string)
+		if !ok || g.auth.streamTokenInterval <= 0 {
+			return handler(srv, &streamWithCtx{
+				ServerStream: ss,
+				ctx:          handlerCtx,
+			})
+		}
+
+		handlerCtx, cancel := context.WithCancelCause(handlerCtx)
+		defer cancel(nil)
+		go g.reverifyStreamToken(handlerCtx, cancel, token)
+		err = handler(srv, &streamWithCtx{
 			ServerStream: ss,
 			ctx:          handlerCtx,
 		})
+		if cause := context.Cause(handlerCtx); status.Code(cause) == codes.Unauthenticated {
+			return cause
+		}
+		return err
 	}
 }
 
+// reverifyStreamToken verifies token periodically until ctx is done,
+// stream is canceled with Unauthenticated cause once token is rejected.
+func (g *GRPC) reverifyStreamToken(ctx context.Context, cancel context.CancelCauseFunc, token string) {
+	ticker := time.NewTicker(g.auth.streamTokenInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		_, err := g.auth.tokenClaims(ctx, token)
+		if status.Code(err) == codes.Unauthenticated {
+			cancel(status.Errorf(codes.Unauthenticated, "token is no longer valid: %s", status.Convert(err).Message()))
+			return
+		}
+	}
+}
+
+func (g *GRPC) isPublic(ctx context.Context, method string) bool {
+	if _, ok := g.auth.publicMethods[method]; !ok {
+		return false
+	}
+	g.audit(ctx, method, nil, nil)
+	return true
+}
+
 func (g *GRPC) tokenFromGrpcCtx(ctx context.Context) (string, error) {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
@@ -88,6 +176,14 @@ func (g *GRPC) authenticateGrpcContext(ctx context.Context) (context.Context, er
 		}
 	}
 
+	claims, ok, err := g.signedClaimsFromGrpcCtx(ctx, verified)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return context.WithValue(ctx, TokenClaimsContextKey, claims), nil
+	}
+
 	if g.auth.token == nil {
 		// note: client may be verified by client cert only, token may remain unconfigured
 		if verified {
@@ -103,14 +199,43 @@ func (g *GRPC) authenticateGrpcContext(ctx context.Context) (context.Context, er
 		}
 		return nil, err
 	}
-	claims, err := g.auth.tokenClaims(ctx, token)
+	claims, err = g.auth.tokenClaims(ctx, token)
 	if err != nil {
 		return nil, err
 	}
+	ctx = context.WithValue(ctx, TokenContextKey, token)
 	return context.WithValue(ctx, TokenClaimsContextKey, claims), nil
 }
 
 func (g *GRPC) authorizeGrpcContext(ctx context.Context, method string) (context.Context, error) {
+	caps, err := g.authorize(ctx, method)
+	g.audit(ctx, method, caps, err)
+	if err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, capabilities.CapabilitiesContextKey, caps), nil
+}
+
+func (g *GRPC) audit(ctx context.Context, method string, caps capabilities.Capabilities, err error) {
+	if g.auth.config == nil || g.auth.config.Audit == nil {
+		return
+	}
+	ev := AuditEvent{
+		Method:       method,
+		Capabilities: caps,
+		Decision:     AuditDecisionAllow,
+	}
+	if claims, ok := ctx.Value(TokenClaimsContextKey).(*Claims); ok {
+		ev.Email = claims.Email
+	}
+	if err != nil {
+		ev.Decision = AuditDecisionDeny
+		ev.Reason = status.Convert(err).Message()
+	}
+	g.auth.config.Audit(ctx, ev)
+}
+
+func (g *GRPC) authorize(ctx context.Context, method string) (capabilities.Capabilities, error) {
 	var (
 		caps       = capabilities.Capabilities{}
 		err        error
@@ -120,44 +245,67 @@ func (g *GRPC) authorizeGrpcContext(ctx context.Context, method string) (context
 	if ok {
 		tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
 		if ok && len(tlsInfo.State.VerifiedChains) > 0 {
-			caps, err = g.capabilitiesFromCertificate(tlsInfo.State.VerifiedChains[0][0])
+			leaf := tlsInfo.State.VerifiedChains[0][0]
+			caps, err = g.capabilitiesFromCertificate(leaf)
 			if err != nil {
 				return nil, status.Errorf(
 					codes.Internal,
 					"failed to extract capabilities from client certificate: %v", err,
 				)
 			}
+			spiffeCaps, err := g.capabilitiesFromSPIFFE(leaf)
+			if err != nil {
+				return caps, status.Errorf(codes.PermissionDenied, "%v", err)
+			}
+			caps = UnionCapabilities(caps, spiffeCaps)
 			authorized = true
 		}
 	}
 
 	if claims, ok := ctx.Value(TokenClaimsContextKey).(*Claims); ok {
-		claimsCaps := g.parseCapabilities(claims.Groups)
-		for k, v := range claimsCaps {
-			caps[k] = v
-		}
+		caps = UnionCapabilities(caps, g.parseCapabilities(claims.Groups))
 		authorized = true
 	}
 
 	if !authorized {
-		return nil, status.Errorf(codes.Unauthenticated, "no valid authorization sources providen (expected client certificate or token)")
+		return caps, status.Errorf(codes.Unauthenticated, "no valid authorization sources providen (expected client certificate or token)")
 	}
 
 	rule, matched := g.auth.acl.Match(caps, method)
 	if !matched {
-		return nil, status.Errorf(
+		return caps, status.Errorf(
 			codes.InvalidArgument,
-			"required client capability set for %q not satisfied, has: %s, want: %s",
+			capabilitiesNotSatisfiedFormat,
 			method, caps.String(), rule.String(),
 		)
 	}
-	return context.WithValue(ctx, capabilities.CapabilitiesContextKey, caps), nil
+	return caps, nil
 }
 
+// capabilitiesFromCertificate returns capabilities encoded into client certificate,
+// they are cached by certificate fingerprint, so renewed certificate is decoded again.
 func (g *GRPC) capabilitiesFromCertificate(cert *x509.Certificate) (capabilities.Capabilities, error) {
 	if !isClientCertificate(cert) {
 		return nil, errors.New("certificate is not valid for client auth")
 	}
+	cache := g.auth.certCapabilities
+	if cache == nil {
+		return g.decodeCertificateCapabilities(cert)
+	}
+
+	fingerprint := sha256.Sum256(cert.Raw)
+	if caps, ok := cache.Get(fingerprint); ok {
+		return maps.Clone(caps), nil
+	}
+	caps, err := g.decodeCertificateCapabilities(cert)
+	if err != nil {
+		return nil, err
+	}
+	cache.Add(fingerprint, caps)
+	return maps.Clone(caps), nil
+}
+
+func (g *GRPC) decodeCertificateCapabilities(cert *x509.Certificate) (capabilities.Capabilities, error) {
 	for _, ext := range cert.Extensions {
 		if !ext.Id.Equal(CapabilitiesCertificateOID) {
 			continue
@@ -178,6 +326,25 @@ func (g *GRPC) capabilitiesFromCertificate(cert *x509.Certificate) (capabilities
 	return capabilities.Capabilities{}, nil
 }
 
+func (g *GRPC) capabilitiesFromSPIFFE(cert *x509.Certificate) (capabilities.Capabilities, error) {
+	caps := capabilities.Capabilities{}
+	if g.auth.spiffe == nil {
+		return caps, nil
+	}
+	for _, uri := range cert.URIs {
+		if uri.Scheme != SPIFFEScheme {
+			continue
+		}
+		id := uri.String()
+		capSlice, ok := g.auth.spiffe[id]
+		if !ok {
+			return nil, errors.Errorf("unknown spiffe id %q", id)
+		}
+		caps = UnionCapabilities(caps, g.parseCapabilities(capSlice))
+	}
+	return caps, nil
+}
+
 func (g *GRPC) parseCapabilities(capStrs []string) capabilities.Capabilities {
 	caps := make(capabilities.Capabilities, len(capStrs))
 	for _, capStr := range capStrs {
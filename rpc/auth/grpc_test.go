@@ -0,0 +1,296 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"git.tatikoma.dev/corpix/protoc-gen-grpc-capabilities/capabilities"
+)
+
+func TestCapabilitiesFromSPIFFE(t *testing.T) {
+	a := &Auth{}
+	WithSPIFFECapabilities(map[string][]string{
+		"spiffe://atlas.local/ns/default/sa/api": {"read", "write:users"},
+	})(a)
+	g := a.GRPC()
+
+	certWithURI := func(t *testing.T, rawURI string) *x509.Certificate {
+		u, err := url.Parse(rawURI)
+		require.NoError(t, err)
+		return &x509.Certificate{URIs: []*url.URL{u}}
+	}
+
+	t.Run("maps known id to capabilities", func(t *testing.T) {
+		caps, err := g.capabilitiesFromSPIFFE(certWithURI(t, "spiffe://atlas.local/ns/default/sa/api"))
+		require.NoError(t, err)
+		assert.Equal(t, g.parseCapabilities([]string{"read", "write:users"}), caps)
+	})
+
+	t.Run("denies unknown id", func(t *testing.T) {
+		_, err := g.capabilitiesFromSPIFFE(certWithURI(t, "spiffe://atlas.local/ns/default/sa/unknown"))
+		assert.Error(t, err)
+	})
+
+	t.Run("ignores non spiffe uris", func(t *testing.T) {
+		caps, err := g.capabilitiesFromSPIFFE(certWithURI(t, "https://atlas.local"))
+		require.NoError(t, err)
+		assert.Empty(t, caps)
+	})
+
+	t.Run("ignores spiffe ids when mapping is not configured", func(t *testing.T) {
+		caps, err := (&Auth{}).GRPC().capabilitiesFromSPIFFE(certWithURI(t, "spiffe://atlas.local/ns/default/sa/api"))
+		require.NoError(t, err)
+		assert.Empty(t, caps)
+	})
+}
+
+func TestAuditHook(t *testing.T) {
+	var events []AuditEvent
+	a := &Auth{config: &Config{
+		Audit: func(_ context.Context, ev AuditEvent) {
+			events = append(events, ev)
+		},
+	}}
+	g := a.GRPC()
+
+	t.Run("allow", func(t *testing.T) {
+		events = nil
+		ctx := context.WithValue(context.Background(), TokenClaimsContextKey, &Claims{
+			Email:  "user@atlas.local",
+			Groups: []string{"read"},
+		})
+		_, err := g.authorizeGrpcContext(ctx, "/atlas.Test/Get")
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, AuditEvent{
+			Method:       "/atlas.Test/Get",
+			Capabilities: g.parseCapabilities([]string{"read"}),
+			Email:        "user@atlas.local",
+			Decision:     AuditDecisionAllow,
+		}, events[0])
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		events = nil
+		_, err := g.authorizeGrpcContext(context.Background(), "/atlas.Test/Get")
+		require.Error(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, "/atlas.Test/Get", events[0].Method)
+		assert.Equal(t, AuditDecisionDeny, events[0].Decision)
+		assert.Empty(t, events[0].Email)
+		assert.Contains(t, events[0].Reason, "no valid authorization sources")
+	})
+}
+
+func TestPublicMethods(t *testing.T) {
+	a := &Auth{}
+	WithPublicMethods("/grpc.health.v1.Health/Check")(a)
+	interceptor := a.GRPC().UnaryInterceptor()
+
+	call := func(method string) (bool, error) {
+		var called bool
+		_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method},
+			func(ctx context.Context, req any) (any, error) {
+				called = true
+				return nil, nil
+			},
+		)
+		return called, err
+	}
+
+	t.Run("public method allows anonymous access", func(t *testing.T) {
+		called, err := call("/grpc.health.v1.Health/Check")
+		require.NoError(t, err)
+		assert.True(t, called)
+	})
+
+	t.Run("protected method requires auth", func(t *testing.T) {
+		called, err := call("/atlas.Test/Get")
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.False(t, called)
+	})
+}
+
+func TestTokenVerifier(t *testing.T) {
+	a := &Auth{config: &Config{}}
+	WithTokenVerifier(TokenVerifierFunc(func(_ context.Context, token string) (*Claims, error) {
+		switch token {
+		case "reader":
+			return &Claims{Email: "reader@atlas.local", Groups: []string{"read"}}, nil
+		case "writer":
+			return &Claims{Email: "writer@atlas.local", Groups: []string{"read", "write"}}, nil
+		}
+		return nil, errors.New("unknown token")
+	}))(a)
+	interceptor := a.GRPC().UnaryInterceptor()
+
+	call := func(token string) (capabilities.Capabilities, error) {
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(TokenMetadataKey, token))
+		}
+		var caps capabilities.Capabilities
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/atlas.Test/Get"},
+			func(ctx context.Context, req any) (any, error) {
+				caps = ctx.Value(capabilities.CapabilitiesContextKey).(capabilities.Capabilities)
+				return nil, nil
+			},
+		)
+		return caps, err
+	}
+
+	t.Run("allow", func(t *testing.T) {
+		caps, err := call("reader")
+		require.NoError(t, err)
+		assert.Equal(t, a.GRPC().parseCapabilities([]string{"read"}), caps)
+
+		caps, err = call("writer")
+		require.NoError(t, err)
+		assert.Equal(t, a.GRPC().parseCapabilities([]string{"read", "write"}), caps)
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		_, err := call("forged")
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.ErrorContains(t, err, "unknown token")
+
+		_, err = call("")
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamTokenReverification(t *testing.T) {
+	expiry := time.Now().Add(100 * time.Millisecond)
+	a := &Auth{config: &Config{}}
+	WithStreamTokenReverification(10 * time.Millisecond)(a)
+	WithTokenVerifier(TokenVerifierFunc(func(_ context.Context, token string) (*Claims, error) {
+		if token != "short-lived" || time.Now().After(expiry) {
+			return nil, errors.New("token is expired")
+		}
+		return &Claims{Email: "user@atlas.local", Groups: []string{"read"}}, nil
+	}))(a)
+	interceptor := a.GRPC().StreamInterceptor()
+
+	ss := &testServerStream{ctx: metadata.NewIncomingContext(context.Background(), metadata.Pairs(TokenMetadataKey, "short-lived"))}
+	done := make(chan error, 1)
+	go func() {
+		done <- interceptor(nil, ss, &grpc.StreamServerInfo{FullMethod: "/atlas.Test/Watch"},
+			func(srv any, stream grpc.ServerStream) error {
+				<-stream.Context().Done()
+				return stream.Context().Err()
+			},
+		)
+	}()
+
+	select {
+	case err := <-done:
+		assert.False(t, time.Now().Before(expiry), "stream is closed after expiry only")
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.ErrorContains(t, err, "token is expired")
+	case <-time.After(time.Second):
+		t.Fatal("stream was not closed after token expiry")
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		a := &Auth{config: &Config{}}
+		WithTokenVerifier(TokenVerifierFunc(func(context.Context, string) (*Claims, error) {
+			return &Claims{}, nil
+		}))(a)
+		err := a.GRPC().StreamInterceptor()(nil, ss, &grpc.StreamServerInfo{FullMethod: "/atlas.Test/Watch"},
+			func(srv any, stream grpc.ServerStream) error {
+				assert.Equal(t, "short-lived", stream.Context().Value(TokenContextKey))
+				return nil
+			},
+		)
+		require.NoError(t, err)
+	})
+}
+
+func newTestClientCertificate(t testing.TB, serial int64, caps ...string) *x509.Certificate {
+	t.Helper()
+
+	template := &x509.Certificate{}
+	require.NoError(t, NewCertTool(nil).applyCapabilities(template, caps))
+	return &x509.Certificate{
+		Raw:          big.NewInt(serial).Bytes(),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		Extensions:   template.ExtraExtensions,
+		SerialNumber: big.NewInt(serial),
+	}
+}
+
+func TestCertificateCapabilitiesCache(t *testing.T) {
+	a := &Auth{}
+	WithCertificateCapabilitiesCache(16)(a)
+	g := a.GRPC()
+
+	cert := newTestClientCertificate(t, 1, "read", "write:users")
+	caps, err := g.capabilitiesFromCertificate(cert)
+	require.NoError(t, err)
+	assert.Equal(t, g.parseCapabilities([]string{"read", "write:users"}), caps)
+	assert.Equal(t, 1, a.certCapabilities.Len())
+
+	cached, err := g.capabilitiesFromCertificate(cert)
+	require.NoError(t, err)
+	assert.Equal(t, caps, cached)
+	assert.Equal(t, 1, a.certCapabilities.Len())
+
+	delete(cached, "read")
+	cached, err = g.capabilitiesFromCertificate(cert)
+	require.NoError(t, err)
+	assert.Equal(t, caps, cached, "cached capabilities are not shared with callers")
+
+	renewed, err := g.capabilitiesFromCertificate(newTestClientCertificate(t, 2, "read"))
+	require.NoError(t, err)
+	assert.Equal(t, g.parseCapabilities([]string{"read"}), renewed)
+	assert.Equal(t, 2, a.certCapabilities.Len())
+
+	WithCertificateCapabilitiesCache(0)(a)
+	caps, err = g.capabilitiesFromCertificate(cert)
+	require.NoError(t, err)
+	assert.Equal(t, g.parseCapabilities([]string{"read", "write:users"}), caps)
+}
+
+func BenchmarkCertificateCapabilities(b *testing.B) {
+	cert := newTestClientCertificate(b, 1, "read", "write:users", "admin:billing:invoices")
+	for _, size := range []int{0, DefaultCertificateCapabilitiesCacheSize} {
+		b.Run(fmt.Sprintf("cache=%d", size), func(b *testing.B) {
+			a := &Auth{}
+			WithCertificateCapabilitiesCache(size)(a)
+			for n := range size {
+				_, err := a.GRPC().capabilitiesFromCertificate(newTestClientCertificate(b, int64(n+2), "read"))
+				require.NoError(b, err)
+			}
+			g := a.GRPC()
+			b.ResetTimer()
+			for range b.N {
+				_, err := g.capabilitiesFromCertificate(cert)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
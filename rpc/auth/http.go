@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -16,6 +17,9 @@ type HTTP struct {
 
 func (h *HTTP) Middleware(next http.Handler, httpRedirect func(http.ResponseWriter, *http.Request, string, int)) http.Handler {
 	authRedirect := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			h.auth.token.setCookie(w, r, TokenRedirectCookieName, url.QueryEscape(r.URL.RequestURI()), 5*time.Minute)
+		}
 		httpRedirect(w, r, h.auth.config.URL.Path+"/auth/token", http.StatusFound)
 	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -24,21 +28,24 @@ func (h *HTTP) Middleware(next http.Handler, httpRedirect func(http.ResponseWrit
 			return
 		}
 
-		token, err := r.Cookie(TokenCookieName)
+		s, err := h.session(r)
 		if err != nil {
 			authRedirect(w, r)
 			return
 		}
 
 		ctx := r.Context()
-		claims, err := h.auth.tokenClaims(ctx, token.Value)
-		if err != nil {
-			log.Error().Err(err).Msg("failed to verify token")
-			authRedirect(w, r)
-			return
+		claims := s.Claims
+		if claims == nil {
+			claims, err = h.auth.tokenClaims(ctx, s.Token)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to verify token")
+				authRedirect(w, r)
+				return
+			}
 		}
 
-		ctx = context.WithValue(ctx, TokenContextKey, token.Value)
+		ctx = context.WithValue(ctx, TokenContextKey, s.Token)
 		ctx = context.WithValue(ctx, TokenClaimsContextKey, claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -50,6 +57,16 @@ func (h *HTTP) MetadataAnnotator(ctx context.Context, _ *http.Request) metadata.
 	if ok {
 		meta[TokenMetadataKey] = token
 	}
+	claims, ok := ctx.Value(TokenClaimsContextKey).(*Claims)
+	if ok && len(h.auth.claimsSecret) > 0 {
+		payload, signature, err := h.auth.signClaims(claims, time.Now())
+		if err != nil {
+			log.Error().Err(err).Msg("failed to sign claims")
+		} else {
+			meta[SignedClaimsMetadataKey] = payload
+			meta[SignedClaimsSignatureMetadataKey] = signature
+		}
+	}
 
 	return metadata.New(meta)
 }
@@ -61,6 +78,12 @@ func (h *HTTP) Register(mux *http.ServeMux, httpError func(http.ResponseWriter,
 	prefix := h.auth.config.URL.Path
 
 	mux.HandleFunc(prefix+"/auth/token", func(w http.ResponseWriter, r *http.Request) {
+		err := h.auth.token.ready(r.Context())
+		if err != nil {
+			log.Error().Err(err).Msg("token provider unavailable")
+			httpError(w, "token provider unavailable", http.StatusServiceUnavailable)
+			return
+		}
 		state, err := h.auth.token.rand(16)
 		if err != nil {
 			httpError(w, "internal error", http.StatusInternalServerError)
@@ -84,9 +107,13 @@ func (h *HTTP) Register(mux *http.ServeMux, httpError func(http.ResponseWriter,
 			return
 		}
 
-		now := time.Now()
-
 		ctx := r.Context()
+		err = h.auth.token.ready(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("token provider unavailable")
+			httpError(w, "token provider unavailable", http.StatusServiceUnavailable)
+			return
+		}
 		token, err := h.auth.token.OAuth2Config.Exchange(ctx, r.URL.Query().Get("code"))
 		if err != nil {
 			log.Error().Err(err).Msg("failed to exchange code for token")
@@ -94,13 +121,46 @@ func (h *HTTP) Register(mux *http.ServeMux, httpError func(http.ResponseWriter,
 			return
 		}
 
-		_, err = h.auth.tokenClaims(ctx, token.AccessToken)
+		claims, err := h.auth.tokenClaims(ctx, token.AccessToken)
 		if err != nil {
 			log.Error().Err(err).Msg("failed to get token claims")
 			httpError(w, "failed to get token claims", http.StatusUnauthorized)
 			return
 		}
-		h.auth.token.setCookie(w, r, TokenCookieName, token.AccessToken, token.Expiry.Sub(now))
-		http.Redirect(w, r, "/", http.StatusFound)
+		err = h.login(w, r, &Session{
+			Token:  token.AccessToken,
+			Claims: claims,
+			Expiry: token.Expiry,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("failed to login")
+			httpError(w, "failed to login", http.StatusInternalServerError)
+			return
+		}
+		h.loginRedirect(w, r)
 	})
+
+	mux.HandleFunc(prefix+"/auth/logout", h.logout)
+}
+
+// loginRedirect sends user back to the path requested before authentication,
+// only local paths are accepted, "/" is used otherwise.
+func (h *HTTP) loginRedirect(w http.ResponseWriter, r *http.Request) {
+	target := "/"
+	if c, err := r.Cookie(TokenRedirectCookieName); err == nil {
+		value, err := url.QueryUnescape(c.Value)
+		if err == nil && isLocalRedirect(value) {
+			target = value
+		}
+		h.auth.token.setCookie(w, r, TokenRedirectCookieName, "", -time.Second)
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+func isLocalRedirect(target string) bool {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		return false
+	}
+	u, err := url.Parse(target)
+	return err == nil && u.Scheme == "" && u.Host == ""
 }
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginRedirect(t *testing.T) {
+	u, err := url.Parse("https://localhost/app")
+	require.NoError(t, err)
+	h := (&Auth{config: &Config{URL: u}}).HTTP()
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("unauthenticated request should not reach handler")
+	})
+	middleware := h.Middleware(next, http.Redirect)
+
+	login := func(t *testing.T, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, "/app/auth/token/callback", nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		h.loginRedirect(w, r)
+		require.Equal(t, http.StatusFound, w.Code)
+		return w
+	}
+
+	t.Run("returns to original path", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		middleware.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/app/items/1?tab=info", nil))
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "/app/auth/token", w.Header().Get("Location"))
+
+		w = login(t, w.Result().Cookies())
+		assert.Equal(t, "/app/items/1?tab=info", w.Header().Get("Location"))
+
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, TokenRedirectCookieName, cookies[0].Name)
+		assert.Less(t, cookies[0].MaxAge, 0, "redirect cookie should be removed")
+	})
+
+	t.Run("falls back to root", func(t *testing.T) {
+		w := login(t, nil)
+		assert.Equal(t, "/", w.Header().Get("Location"))
+	})
+
+	t.Run("rejects foreign targets", func(t *testing.T) {
+		for _, target := range []string{"https://evil.local/", "//evil.local/", "/\\evil.local/", "items"} {
+			w := login(t, []*http.Cookie{{Name: TokenRedirectCookieName, Value: url.QueryEscape(target)}})
+			assert.Equal(t, "/", w.Header().Get("Location"), target)
+		}
+	})
+}
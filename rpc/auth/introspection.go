@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// IntrospectionConfig configures RFC 7662 token introspection used to verify
+	// opaque tokens, Client and Secret default to the ones of TokenConfig.
+	IntrospectionConfig struct {
+		URL    string
+		Client string
+		Secret string
+	}
+
+	introspectionResponse struct {
+		Email    string   `json:"email"`
+		Username string   `json:"username"`
+		Groups   []string `json:"groups"`
+		Active   bool     `json:"active"`
+	}
+)
+
+var ErrTokenInactive = errors.New("token is not active")
+
+func (t *token) introspect(ctx context.Context, rawToken string) (*Claims, error) {
+	cfg := t.config.Introspection
+	client, secret := cfg.Client, cfg.Secret
+	if client == "" {
+		client, secret = t.config.Client, t.config.Secret
+	}
+
+	form := url.Values{
+		"token":           {rawToken},
+		"token_type_hint": {"access_token"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create introspection request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(client), url.QueryEscape(secret))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to introspect token")
+	}
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read introspection response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to introspect token: %s: %s", resp.Status, buf)
+	}
+
+	var res introspectionResponse
+	err = json.Unmarshal(buf, &res)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse introspection response")
+	}
+	if !res.Active {
+		return nil, ErrTokenInactive
+	}
+
+	claims := &Claims{
+		Email:  res.Email,
+		Groups: res.Groups,
+	}
+	if claims.Email == "" {
+		claims.Email = res.Username
+	}
+	return claims, nil
+}
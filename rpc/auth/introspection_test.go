@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTokenIntrospection(t *testing.T) {
+	issuer, _ := newTestIssuer(t, 0)
+	introspection := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, secret, ok := r.BasicAuth()
+		if !ok || client != "atlas" || secret != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		res := map[string]any{"active": false}
+		if r.PostFormValue("token") == "active" {
+			res = map[string]any{
+				"active": true,
+				"email":  "user@atlas.local",
+				"groups": []string{"read"},
+			}
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+	t.Cleanup(introspection.Close)
+
+	cfg := newTestAuthConfig(t, issuer.URL)
+	cfg.Token.Secret = "secret"
+	cfg.Token.Introspection = &IntrospectionConfig{URL: introspection.URL}
+	a, err := New(cfg)
+	require.NoError(t, err)
+
+	t.Run("active token", func(t *testing.T) {
+		claims, err := a.tokenClaims(t.Context(), "active")
+		require.NoError(t, err)
+		assert.Equal(t, &Claims{Email: "user@atlas.local", Groups: []string{"read"}}, claims)
+	})
+
+	t.Run("inactive token", func(t *testing.T) {
+		_, err := a.tokenClaims(t.Context(), "inactive")
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.ErrorContains(t, err, ErrTokenInactive.Error())
+	})
+
+	t.Run("introspection client credentials", func(t *testing.T) {
+		a.token.config.Introspection = &IntrospectionConfig{URL: introspection.URL, Client: "other", Secret: "other"}
+		defer func() { a.token.config.Introspection = &IntrospectionConfig{URL: introspection.URL} }()
+
+		_, err := a.tokenClaims(t.Context(), "active")
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("without introspection", func(t *testing.T) {
+		a.token.config.Introspection = nil
+		defer func() { a.token.config.Introspection = &IntrospectionConfig{URL: introspection.URL} }()
+
+		_, err := a.tokenClaims(t.Context(), "active")
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
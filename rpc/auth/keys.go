@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultKeySetRefreshInterval is a maximum age of cached keys.
+	DefaultKeySetRefreshInterval = time.Hour
+	// DefaultKeySetMinRefreshInterval limits forced refreshes caused by unknown key ids.
+	DefaultKeySetMinRefreshInterval = 10 * time.Second
+)
+
+var keySetAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.EdDSA,
+}
+
+type (
+	// KeySet caches issuer verification keys, keys are refreshed once they
+	// are older than refresh interval or when token is signed by unknown key,
+	// so key rollover on the issuer side does not fail verification.
+	// Cached keys are still used while issuer is unavailable,
+	// refresh attempts are made no more often than min refresh interval.
+	KeySet struct {
+		client             *http.Client
+		keys               jose.JSONWebKeySet
+		fetched            time.Time
+		attempted          time.Time
+		err                error
+		refreshing         *keySetRefresh
+		url                string
+		stats              KeySetStats
+		refreshInterval    time.Duration
+		minRefreshInterval time.Duration
+		mu                 sync.Mutex
+	}
+
+	keySetRefresh struct {
+		done chan void
+		err  error
+	}
+
+	KeySetStats struct {
+		LastRefresh time.Time
+		Refreshes   int
+		Rotations   int
+		Errors      int
+	}
+)
+
+// WithKeySetRefresh sets maximum age of cached issuer keys and minimal interval
+// between forced refreshes caused by unknown key ids.
+func WithKeySetRefresh(interval, minInterval time.Duration) Option {
+	return func(a *Auth) {
+		a.discovery.keysRefreshInterval = interval
+		a.discovery.keysMinRefreshInterval = minInterval
+	}
+}
+
+func NewKeySet(url string, refreshInterval, minRefreshInterval time.Duration) *KeySet {
+	if refreshInterval <= 0 {
+		refreshInterval = DefaultKeySetRefreshInterval
+	}
+	if minRefreshInterval <= 0 {
+		minRefreshInterval = DefaultKeySetMinRefreshInterval
+	}
+	return &KeySet{
+		client:             http.DefaultClient,
+		url:                url,
+		refreshInterval:    refreshInterval,
+		minRefreshInterval: minRefreshInterval,
+	}
+}
+
+// Stats returns key set refresh statistics.
+func (k *KeySet) Stats() KeySetStats {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.stats
+}
+
+// Refresh fetches keys from issuer regardless of their age.
+func (k *KeySet) Refresh(ctx context.Context) error {
+	return k.refresh(ctx)
+}
+
+// refresh fetches keys from issuer, concurrent callers share single request.
+func (k *KeySet) refresh(ctx context.Context) error {
+	k.mu.Lock()
+	call := k.refreshing
+	if call != nil {
+		k.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-call.done:
+			return call.err
+		}
+	}
+	call = &keySetRefresh{done: make(chan void)}
+	k.refreshing = call
+	k.mu.Unlock()
+
+	call.err = k.update(ctx)
+
+	k.mu.Lock()
+	k.refreshing = nil
+	k.mu.Unlock()
+	close(call.done)
+	return call.err
+}
+
+func (k *KeySet) update(ctx context.Context) error {
+	keys, err := k.fetch(ctx)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.attempted = time.Now()
+	k.err = err
+	if err != nil {
+		k.stats.Errors++
+		return err
+	}
+
+	prev, next := k.keyIDs(k.keys), k.keyIDs(keys)
+	if !k.fetched.IsZero() && !slices.Equal(prev, next) {
+		k.stats.Rotations++
+		log.Info().
+			Str("url", k.url).
+			Strs("previous", prev).
+			Strs("current", next).
+			Msg("issuer keys rotated")
+	}
+
+	k.keys = keys
+	k.fetched = time.Now()
+	k.stats.LastRefresh = k.fetched
+	k.stats.Refreshes++
+	return nil
+}
+
+func (k *KeySet) fetch(ctx context.Context) (jose.JSONWebKeySet, error) {
+	var keys jose.JSONWebKeySet
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.url, nil)
+	if err != nil {
+		return keys, errors.Wrap(err, "failed to create key set request")
+	}
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return keys, errors.Wrap(err, "failed to fetch key set")
+	}
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return keys, errors.Wrap(err, "failed to read key set")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return keys, errors.Errorf("failed to fetch key set: %s: %s", resp.Status, buf)
+	}
+	err = json.Unmarshal(buf, &keys)
+	if err != nil {
+		return keys, errors.Wrap(err, "failed to parse key set")
+	}
+	return keys, nil
+}
+
+func (k *KeySet) keyIDs(keys jose.JSONWebKeySet) []string {
+	ids := make([]string, 0, len(keys.Keys))
+	for _, key := range keys.Keys {
+		ids = append(ids, key.KeyID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// lookup returns keys matching key id, any key matches empty id.
+func (k *KeySet) lookup(kid string) []jose.JSONWebKey {
+	if kid == "" {
+		return k.keys.Keys
+	}
+	return k.keys.Key(kid)
+}
+
+// verificationKeys returns keys matching key id, refreshing key set if required.
+// Stale keys are used if refresh fails, so issuer outage does not reject valid tokens.
+func (k *KeySet) verificationKeys(ctx context.Context, kid string) ([]jose.JSONWebKey, error) {
+	k.mu.Lock()
+	cached := !k.fetched.IsZero()
+	expired := !cached || time.Since(k.fetched) > k.refreshInterval
+	due := time.Since(k.attempted) > k.minRefreshInterval
+	k.mu.Unlock()
+
+	if expired && due {
+		err := k.refresh(ctx)
+		if err != nil {
+			if !cached {
+				return nil, err
+			}
+			log.Warn().
+				Err(err).
+				Str("url", k.url).
+				Msg("failed to refresh issuer keys, using cached keys")
+		}
+	}
+
+	k.mu.Lock()
+	keys := k.lookup(kid)
+	due = time.Since(k.attempted) > k.minRefreshInterval
+	k.mu.Unlock()
+
+	if len(keys) == 0 && due {
+		log.Info().
+			Str("url", k.url).
+			Str("kid", kid).
+			Msg("unknown key id, refreshing issuer keys")
+		err := k.refresh(ctx)
+		if err != nil {
+			return nil, err
+		}
+		k.mu.Lock()
+		keys = k.lookup(kid)
+		k.mu.Unlock()
+	}
+	if len(keys) == 0 {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		if k.fetched.IsZero() && k.err != nil {
+			return nil, k.err
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return keys, nil
+}
+
+// VerifySignature implements oidc.KeySet.
+func (k *KeySet) VerifySignature(ctx context.Context, jwt string) ([]byte, error) {
+	jws, err := jose.ParseSigned(jwt, keySetAlgorithms)
+	if err != nil {
+		return nil, errors.Wrap(err, "malformed jwt")
+	}
+	var kid string
+	if len(jws.Signatures) > 0 {
+		kid = jws.Signatures[0].Header.KeyID
+	}
+
+	keys, err := k.verificationKeys(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		payload, err := jws.Verify(&key)
+		if err == nil {
+			return payload, nil
+		}
+	}
+	return nil, errors.New("failed to verify id token signature")
+}
+
+var _ oidc.KeySet = new(KeySet)
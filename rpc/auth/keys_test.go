@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testKeys struct {
+	signers  map[string]jose.Signer
+	keys     jose.JSONWebKeySet
+	fail     atomic.Bool
+	requests atomic.Int32
+	mu       sync.Mutex
+}
+
+func (k *testKeys) add(t *testing.T, kid string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.ES256,
+		Key:       jose.JSONWebKey{Key: key, KeyID: kid},
+	}, nil)
+	require.NoError(t, err)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.signers[kid] = signer
+	k.keys.Keys = append(k.keys.Keys, jose.JSONWebKey{Key: &key.PublicKey, KeyID: kid, Algorithm: string(jose.ES256)})
+}
+
+func (k *testKeys) sign(t *testing.T, kid string, payload string) string {
+	t.Helper()
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	jws, err := k.signers[kid].Sign([]byte(payload))
+	require.NoError(t, err)
+	jwt, err := jws.CompactSerialize()
+	require.NoError(t, err)
+	return jwt
+}
+
+func (k *testKeys) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	k.requests.Add(1)
+	if k.fail.Load() {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	_ = json.NewEncoder(w).Encode(k.keys)
+}
+
+func TestKeySet(t *testing.T) {
+	keys := &testKeys{signers: map[string]jose.Signer{}}
+	keys.add(t, "k1")
+	srv := httptest.NewServer(keys)
+	t.Cleanup(srv.Close)
+
+	ks := NewKeySet(srv.URL, 0, 1)
+	ctx := t.Context()
+
+	payload, err := ks.VerifySignature(ctx, keys.sign(t, "k1", "first"))
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(payload))
+	assert.Equal(t, 1, ks.Stats().Refreshes)
+
+	t.Run("known key does not trigger refresh", func(t *testing.T) {
+		_, err := ks.VerifySignature(ctx, keys.sign(t, "k1", "again"))
+		require.NoError(t, err)
+		assert.Equal(t, 1, ks.Stats().Refreshes)
+	})
+
+	t.Run("unknown key triggers refresh", func(t *testing.T) {
+		keys.add(t, "k2")
+		payload, err := ks.VerifySignature(ctx, keys.sign(t, "k2", "rotated"))
+		require.NoError(t, err)
+		assert.Equal(t, "rotated", string(payload))
+
+		stats := ks.Stats()
+		assert.Equal(t, 2, stats.Refreshes)
+		assert.Equal(t, 1, stats.Rotations)
+	})
+
+	t.Run("key missing after refresh is rejected", func(t *testing.T) {
+		other := &testKeys{signers: map[string]jose.Signer{}}
+		other.add(t, "k3")
+		_, err := ks.VerifySignature(ctx, other.sign(t, "k3", "forged"))
+		assert.ErrorContains(t, err, `unknown key id "k3"`)
+		assert.Equal(t, 0, ks.Stats().Errors)
+	})
+
+	t.Run("signature of known key id is checked", func(t *testing.T) {
+		other := &testKeys{signers: map[string]jose.Signer{}}
+		other.add(t, "k1")
+		_, err := ks.VerifySignature(ctx, other.sign(t, "k1", "forged"))
+		assert.Error(t, err)
+	})
+}
+
+func TestKeySetIssuerOutage(t *testing.T) {
+	keys := &testKeys{signers: map[string]jose.Signer{}}
+	keys.add(t, "k1")
+	srv := httptest.NewServer(keys)
+	t.Cleanup(srv.Close)
+	ctx := t.Context()
+
+	t.Run("stale keys are used when refresh fails", func(t *testing.T) {
+		ks := NewKeySet(srv.URL, time.Nanosecond, time.Nanosecond)
+		_, err := ks.VerifySignature(ctx, keys.sign(t, "k1", "first"))
+		require.NoError(t, err)
+
+		keys.fail.Store(true)
+		t.Cleanup(func() { keys.fail.Store(false) })
+		time.Sleep(time.Millisecond)
+
+		payload, err := ks.VerifySignature(ctx, keys.sign(t, "k1", "stale"))
+		require.NoError(t, err)
+		assert.Equal(t, "stale", string(payload))
+		assert.Equal(t, 1, ks.Stats().Errors)
+	})
+
+	t.Run("failed refreshes are rate limited", func(t *testing.T) {
+		keys.fail.Store(true)
+		t.Cleanup(func() { keys.fail.Store(false) })
+		keys.requests.Store(0)
+
+		ks := NewKeySet(srv.URL, 0, time.Hour)
+		_, err := ks.VerifySignature(ctx, keys.sign(t, "k1", "first"))
+		assert.ErrorContains(t, err, "failed to fetch key set")
+		_, err = ks.VerifySignature(ctx, keys.sign(t, "k1", "second"))
+		assert.ErrorContains(t, err, "failed to fetch key set")
+		assert.Equal(t, int32(1), keys.requests.Load())
+	})
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/oauth2"
+
+	"git.tatikoma.dev/corpix/atlas/backoff"
+)
+
+type tokenDiscoveryCall struct {
+	done chan void
+	err  error
+}
+
+type tokenDiscovery struct {
+	backoff                backoff.Config
+	attempts               int
+	keysRefreshInterval    time.Duration
+	keysMinRefreshInterval time.Duration
+	lazy                   bool
+}
+
+// WithDiscoveryRetry makes OIDC provider discovery retry up to attempts times
+// with delays computed from cfg, so temporary issuer unavailability does not fail startup.
+func WithDiscoveryRetry(attempts int, cfg backoff.Config) Option {
+	return func(a *Auth) {
+		a.discovery.attempts = max(attempts, 1)
+		a.discovery.backoff = cfg
+	}
+}
+
+// WithLazyDiscovery defers OIDC provider discovery until token is used first time,
+// failed discovery is retried on the next use.
+func WithLazyDiscovery() Option {
+	return func(a *Auth) {
+		a.discovery.lazy = true
+	}
+}
+
+// ready discovers provider if it was not discovered yet.
+// Concurrent callers share single discovery, mutex is never held across network requests.
+func (t *token) ready(ctx context.Context) error {
+	if t.discovered.Load() {
+		return nil
+	}
+
+	t.mu.Lock()
+	if t.discovered.Load() {
+		t.mu.Unlock()
+		return nil
+	}
+	call := t.discovering
+	if call != nil {
+		t.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-call.done:
+			return call.err
+		}
+	}
+	call = &tokenDiscoveryCall{done: make(chan void)}
+	t.discovering = call
+	t.mu.Unlock()
+
+	call.err = t.setup(ctx)
+
+	t.mu.Lock()
+	t.discovering = nil
+	t.mu.Unlock()
+	close(call.done)
+	return call.err
+}
+
+func (t *token) setup(ctx context.Context) error {
+	provider, err := t.discover(ctx)
+	if err != nil {
+		return err
+	}
+	var meta struct {
+		JWKSURL string `json:"jwks_uri"`
+	}
+	err = provider.Claims(&meta)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse provider metadata")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Provider = provider
+	t.Keys = NewKeySet(meta.JWKSURL, t.discovery.keysRefreshInterval, t.discovery.keysMinRefreshInterval)
+	if t.Verifier == nil {
+		t.Verifier = oidcTokenVerifier{oidc.NewVerifier(t.config.Issuer, t.Keys, &oidc.Config{ClientID: t.config.Client})}
+	}
+	t.OAuth2Config = oauth2.Config{
+		ClientID:     t.config.Client,
+		ClientSecret: t.config.Secret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  t.redirectURL,
+		Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+	}
+	t.discovered.Store(true)
+	return nil
+}
+
+func (t *token) discover(ctx context.Context) (*oidc.Provider, error) {
+	b := backoff.New(t.discovery.backoff)
+	for {
+		provider, err := oidc.NewProvider(ctx, t.config.Issuer)
+		if err == nil {
+			return provider, nil
+		}
+		if b.Attempt()+1 >= t.discovery.attempts {
+			return nil, err
+		}
+
+		delay := b.Next()
+		log.Warn().
+			Err(err).
+			Str("issuer", t.config.Issuer).
+			Int("attempt", b.Attempt()).
+			Str("delay", delay.String()).
+			Msg("failed to discover oidc provider, retrying")
+
+		select {
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		case <-time.After(delay):
+		}
+	}
+}
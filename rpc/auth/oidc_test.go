@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.tatikoma.dev/corpix/atlas/backoff"
+)
+
+// newTestIssuer starts OIDC issuer which fails discovery requests fails times.
+func newTestIssuer(t *testing.T, fails int32) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var (
+		srv      *httptest.Server
+		requests atomic.Int32
+	)
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= fails {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                 srv.URL,
+			"authorization_endpoint": srv.URL + "/auth",
+			"token_endpoint":         srv.URL + "/token",
+			"jwks_uri":               srv.URL + "/keys",
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func newTestAuthConfig(t *testing.T, issuer string) Config {
+	t.Helper()
+	certs := newTestCerts(t)
+	u, err := url.Parse("https://localhost")
+	require.NoError(t, err)
+	return Config{
+		URL: u,
+		Certificate: &CertificateConfig{
+			CA:   certs.ca,
+			Cert: certs.cert,
+			Key:  certs.key,
+		},
+		Token: &TokenConfig{
+			Issuer: issuer,
+			Client: "atlas",
+		},
+	}
+}
+
+func TestDiscoveryRetry(t *testing.T) {
+	retry := backoff.Config{BaseDelay: time.Millisecond, Multiplier: 1, MaxDelay: time.Millisecond}
+
+	t.Run("succeeds after failures", func(t *testing.T) {
+		issuer, requests := newTestIssuer(t, 2)
+		a, err := New(newTestAuthConfig(t, issuer.URL), WithDiscoveryRetry(3, retry))
+		require.NoError(t, err)
+		assert.Equal(t, int32(3), requests.Load())
+		assert.Equal(t, issuer.URL+"/token", a.token.OAuth2Config.Endpoint.TokenURL)
+	})
+
+	t.Run("fails when attempts exhausted", func(t *testing.T) {
+		issuer, requests := newTestIssuer(t, 2)
+		_, err := New(newTestAuthConfig(t, issuer.URL), WithDiscoveryRetry(2, retry))
+		assert.Error(t, err)
+		assert.Equal(t, int32(2), requests.Load())
+	})
+
+	t.Run("no retry by default", func(t *testing.T) {
+		issuer, requests := newTestIssuer(t, 1)
+		_, err := New(newTestAuthConfig(t, issuer.URL))
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("lazy discovery", func(t *testing.T) {
+		issuer, requests := newTestIssuer(t, 1)
+		a, err := New(newTestAuthConfig(t, issuer.URL), WithLazyDiscovery())
+		require.NoError(t, err)
+		assert.Equal(t, int32(0), requests.Load())
+
+		ctx := t.Context()
+		assert.Error(t, a.token.ready(ctx))
+		assert.NoError(t, a.token.ready(ctx))
+		assert.NoError(t, a.token.ready(ctx))
+		assert.Equal(t, int32(2), requests.Load())
+	})
+	t.Run("concurrent lazy discovery", func(t *testing.T) {
+		issuer, requests := newTestIssuer(t, 2)
+		a, err := New(newTestAuthConfig(t, issuer.URL),
+			WithLazyDiscovery(),
+			WithDiscoveryRetry(3, backoff.Config{BaseDelay: 50 * time.Millisecond, Multiplier: 1, MaxDelay: 50 * time.Millisecond}),
+		)
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, a.token.ready(t.Context()))
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int32(3), requests.Load())
+	})
+}
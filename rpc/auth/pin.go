@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"strings"
+
+	"git.tatikoma.dev/corpix/atlas/errors"
+)
+
+// CertificateFingerprint returns hex encoded SHA-256 of DER encoded certificate.
+func CertificateFingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.ReplaceAll(fingerprint, ":", ""))
+}
+
+// ApplyFingerprintPinning makes tls config accept peer only if its leaf certificate
+// SHA-256 fingerprint is one of fingerprints (hex, colons are allowed),
+// check is made in addition to the chain verification.
+func ApplyFingerprintPinning(tc *tls.Config, fingerprints ...string) {
+	pinned := make(map[string]void, len(fingerprints))
+	for _, fingerprint := range fingerprints {
+		pinned[normalizeFingerprint(fingerprint)] = void{}
+	}
+
+	prev := tc.VerifyPeerCertificate
+	tc.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		if prev != nil {
+			err := prev(rawCerts, verifiedChains)
+			if err != nil {
+				return err
+			}
+		}
+		if len(rawCerts) == 0 {
+			return errors.New("no peer certificate to check fingerprint of")
+		}
+		fingerprint := CertificateFingerprint(rawCerts[0])
+		if _, ok := pinned[fingerprint]; !ok {
+			return errors.Errorf("peer certificate fingerprint %s is not pinned", fingerprint)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"crypto/tls"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprintPinning(t *testing.T) {
+	certs := newTestCerts(t)
+	server, err := NewTLSConfig("localhost", certs.ca, certs.cert, certs.key)
+	require.NoError(t, err)
+
+	cert, err := tls.LoadX509KeyPair(certs.cert, certs.key)
+	require.NoError(t, err)
+	fingerprint := CertificateFingerprint(cert.Certificate[0])
+
+	client := func(t *testing.T, fingerprints ...string) *tls.Config {
+		t.Helper()
+		certPool, err := NewCertPoolFromFile(certs.ca)
+		require.NoError(t, err)
+		tc := newBaseTLSConfig("localhost", certPool)
+		ApplyFingerprintPinning(tc, fingerprints...)
+		return tc
+	}
+
+	t.Run("matching fingerprint connects", func(t *testing.T) {
+		_, clientErr := testHandshake(t, server, client(t, "0000", fingerprint))
+		assert.NoError(t, clientErr)
+	})
+
+	t.Run("fingerprint with colons and upper case", func(t *testing.T) {
+		var pairs []string
+		for i := 0; i < len(fingerprint); i += 2 {
+			pairs = append(pairs, strings.ToUpper(fingerprint[i:i+2]))
+		}
+		_, clientErr := testHandshake(t, server, client(t, strings.Join(pairs, ":")))
+		assert.NoError(t, clientErr)
+	})
+
+	t.Run("mismatched fingerprint fails", func(t *testing.T) {
+		_, clientErr := testHandshake(t, server, client(t, strings.Repeat("0", len(fingerprint))))
+		assert.ErrorContains(t, clientErr, "is not pinned")
+	})
+}
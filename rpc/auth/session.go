@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type (
+	// Session holds token of the user on the server side,
+	// browser carries only opaque session id.
+	Session struct {
+		Expiry time.Time
+		Claims *Claims
+		Token  string
+	}
+
+	// SessionStore persists sessions by id, Get returns ErrSessionNotFound for unknown ids.
+	SessionStore interface {
+		Get(ctx context.Context, id string) (*Session, error)
+		Set(ctx context.Context, id string, s *Session) error
+		Delete(ctx context.Context, id string) error
+	}
+
+	// MemorySessionStore keeps sessions in process memory.
+	MemorySessionStore struct {
+		sessions map[string]*Session
+		mu       sync.Mutex
+	}
+
+	session struct{}
+)
+
+var (
+	SessionCookieName = "session_" + hex.EncodeToString(fnv.New64a().Sum([]byte(fmt.Sprintf("%T", session{}))))[:8]
+
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// WithSessionStore keeps tokens in store, cookie holds session id only.
+// Token itself is stored in the cookie by default.
+func WithSessionStore(store SessionStore) Option {
+	return func(a *Auth) {
+		a.sessions = store
+	}
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: map[string]*Session{}}
+}
+
+func (m *MemorySessionStore) Get(_ context.Context, id string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	if !s.Expiry.IsZero() && time.Now().After(s.Expiry) {
+		delete(m.sessions, id)
+		return nil, ErrSessionNotFound
+	}
+	return s, nil
+}
+
+func (m *MemorySessionStore) Set(_ context.Context, id string, s *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[id] = s
+	return nil
+}
+
+func (m *MemorySessionStore) Delete(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
+
+// login persists token in the session store or in the cookie if store is not configured.
+func (h *HTTP) login(w http.ResponseWriter, r *http.Request, s *Session) error {
+	age := time.Until(s.Expiry)
+	if h.auth.sessions == nil {
+		h.auth.token.setCookie(w, r, TokenCookieName, s.Token, age)
+		return nil
+	}
+
+	id, err := randString(32)
+	if err != nil {
+		return err
+	}
+	err = h.auth.sessions.Set(r.Context(), id, s)
+	if err != nil {
+		return errors.Wrap(err, "failed to store session")
+	}
+	h.auth.token.setCookie(w, r, SessionCookieName, id, age)
+	return nil
+}
+
+// session loads token of the request, claims are nil if token was not verified yet.
+func (h *HTTP) session(r *http.Request) (*Session, error) {
+	if h.auth.sessions == nil {
+		c, err := r.Cookie(TokenCookieName)
+		if err != nil {
+			return nil, err
+		}
+		return &Session{Token: c.Value}, nil
+	}
+
+	c, err := r.Cookie(SessionCookieName)
+	if err != nil {
+		return nil, err
+	}
+	s, err := h.auth.sessions.Get(r.Context(), c.Value)
+	if err != nil {
+		return nil, err
+	}
+	if !s.Expiry.IsZero() && time.Now().After(s.Expiry) {
+		return nil, ErrSessionNotFound
+	}
+	return s, nil
+}
+
+// logout removes session and cookies of the request.
+func (h *HTTP) logout(w http.ResponseWriter, r *http.Request) {
+	if h.auth.sessions != nil {
+		if c, err := r.Cookie(SessionCookieName); err == nil {
+			err = h.auth.sessions.Delete(r.Context(), c.Value)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to delete session")
+			}
+		}
+		h.auth.token.setCookie(w, r, SessionCookieName, "", -time.Second)
+	}
+	h.auth.token.setCookie(w, r, TokenCookieName, "", -time.Second)
+	http.Redirect(w, r, "/", http.StatusFound)
+}
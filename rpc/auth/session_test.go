@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionStore(t *testing.T) {
+	u, err := url.Parse("https://localhost")
+	require.NoError(t, err)
+	store := NewMemorySessionStore()
+	a := &Auth{config: &Config{URL: u}}
+	WithSessionStore(store)(a)
+	h := a.HTTP()
+
+	var claims *Claims
+	middleware := h.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims = r.Context().Value(TokenClaimsContextKey).(*Claims)
+	}), http.Redirect)
+	request := func(cookies []*http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/items", nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		middleware.ServeHTTP(w, r)
+		return w
+	}
+
+	expected := &Claims{Email: "user@atlas.local"}
+	w := httptest.NewRecorder()
+	require.NoError(t, h.login(w, httptest.NewRequest(http.MethodGet, "/auth/token/callback", nil), &Session{
+		Token:  "access-token",
+		Claims: expected,
+		Expiry: time.Now().Add(time.Hour),
+	}))
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, SessionCookieName, cookies[0].Name)
+	assert.NotContains(t, cookies[0].Value, "access-token")
+
+	t.Run("request", func(t *testing.T) {
+		w := request(cookies)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, expected, claims)
+	})
+
+	t.Run("unknown session", func(t *testing.T) {
+		w := request([]*http.Cookie{{Name: SessionCookieName, Value: "unknown"}})
+		assert.Equal(t, http.StatusFound, w.Code)
+	})
+
+	t.Run("logout", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/auth/logout", nil)
+		r.AddCookie(cookies[0])
+		w := httptest.NewRecorder()
+		h.logout(w, r)
+		assert.Equal(t, http.StatusFound, w.Code)
+
+		_, err := store.Get(t.Context(), cookies[0].Value)
+		assert.ErrorIs(t, err, ErrSessionNotFound)
+		assert.Equal(t, http.StatusFound, request(cookies).Code)
+	})
+
+	t.Run("expired session", func(t *testing.T) {
+		require.NoError(t, store.Set(t.Context(), "expired", &Session{
+			Token:  "access-token",
+			Claims: expected,
+			Expiry: time.Now().Add(-time.Second),
+		}))
+		w := request([]*http.Cookie{{Name: SessionCookieName, Value: "expired"}})
+		assert.Equal(t, http.StatusFound, w.Code)
+	})
+}
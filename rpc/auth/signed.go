@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	SignedClaimsMetadataKey          = "x-atlas-claims"
+	SignedClaimsSignatureMetadataKey = "x-atlas-claims-signature"
+
+	// DefaultSignedClaimsMaxAge limits time window signed claims could be replayed in.
+	DefaultSignedClaimsMaxAge = time.Minute
+)
+
+type signedClaims struct {
+	Claims   *Claims `json:"claims"`
+	IssuedAt int64   `json:"iat"`
+}
+
+// WithSignedClaims makes gateway forward claims of the authenticated request
+// as metadata signed with shared secret, backend trusts such claims
+// instead of verifying the token again. Gateway and backend must share the secret,
+// New fails if secret is empty. Backend accepts signed claims only from peers
+// presenting verified client certificate, so gateway must connect with mTLS.
+func WithSignedClaims(secret []byte) Option {
+	return func(a *Auth) {
+		a.claimsSecret = secret
+		a.signedClaims = true
+	}
+}
+
+func (a *Auth) claimsSignature(payload string) string {
+	mac := hmac.New(sha256.New, a.claimsSecret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (a *Auth) signClaims(claims *Claims, now time.Time) (string, string, error) {
+	buf, err := json.Marshal(signedClaims{Claims: claims, IssuedAt: now.Unix()})
+	if err != nil {
+		return "", "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(buf)
+	return payload, a.claimsSignature(payload), nil
+}
+
+func (a *Auth) verifyClaims(payload string, signature string, now time.Time) (*Claims, error) {
+	if !hmac.Equal([]byte(a.claimsSignature(payload)), []byte(signature)) {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid claims signature")
+	}
+	buf, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "failed to decode signed claims: %v", err)
+	}
+	var signed signedClaims
+	err = json.Unmarshal(buf, &signed)
+	if err != nil || signed.Claims == nil {
+		return nil, status.Errorf(codes.Unauthenticated, "failed to parse signed claims")
+	}
+	age := now.Sub(time.Unix(signed.IssuedAt, 0))
+	if age > DefaultSignedClaimsMaxAge || age < -DefaultSignedClaimsMaxAge {
+		return nil, status.Errorf(codes.Unauthenticated, "signed claims expired")
+	}
+	return signed.Claims, nil
+}
+
+// signedClaimsFromGrpcCtx returns claims forwarded by gateway,
+// ok is false if signed claims are not configured or not present in metadata.
+// Claims are rejected unless peer is verified by client certificate.
+func (g *GRPC) signedClaimsFromGrpcCtx(ctx context.Context, verified bool) (*Claims, bool, error) {
+	if len(g.auth.claimsSecret) == 0 {
+		return nil, false, nil
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, false, nil
+	}
+	payload := md[SignedClaimsMetadataKey]
+	if len(payload) == 0 {
+		return nil, false, nil
+	}
+	if !verified {
+		return nil, true, status.Errorf(codes.Unauthenticated, "signed claims require verified client certificate")
+	}
+	signature := md[SignedClaimsSignatureMetadataKey]
+	if len(signature) == 0 {
+		return nil, true, status.Errorf(codes.Unauthenticated, "missing claims signature")
+	}
+	claims, err := g.auth.verifyClaims(payload[0], signature[0], time.Now())
+	return claims, true, err
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestSignedClaims(t *testing.T) {
+	secret := []byte("secret")
+	gateway := &Auth{}
+	WithSignedClaims(secret)(gateway)
+	backend := &Auth{}
+	WithSignedClaims(secret)(backend)
+
+	claims := &Claims{Email: "user@atlas.local", Groups: []string{"read"}}
+	annotate := func(t *testing.T) metadata.MD {
+		ctx := context.WithValue(context.Background(), TokenClaimsContextKey, claims)
+		md := gateway.HTTP().MetadataAnnotator(ctx, httptest.NewRequest("GET", "/", nil))
+		require.Len(t, md[SignedClaimsMetadataKey], 1)
+		require.Len(t, md[SignedClaimsSignatureMetadataKey], 1)
+		return md
+	}
+	verifiedPeer := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{newTestClientCertificate(t, 1)}},
+		}},
+	})
+	authenticate := func(a *Auth, md metadata.MD) (*Claims, error) {
+		ctx, err := a.GRPC().authenticateGrpcContext(metadata.NewIncomingContext(verifiedPeer, md))
+		if err != nil {
+			return nil, err
+		}
+		return ctx.Value(TokenClaimsContextKey).(*Claims), nil
+	}
+
+	t.Run("signed claims survive", func(t *testing.T) {
+		got, err := authenticate(backend, annotate(t))
+		require.NoError(t, err)
+		assert.Equal(t, claims, got)
+	})
+
+	t.Run("tampered claims are rejected", func(t *testing.T) {
+		md := annotate(t)
+		forged, _, err := gateway.signClaims(&Claims{Email: "admin@atlas.local", Groups: []string{"admin"}}, time.Now())
+		require.NoError(t, err)
+		md.Set(SignedClaimsMetadataKey, forged)
+
+		_, err = authenticate(backend, md)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("claims signed with other secret are rejected", func(t *testing.T) {
+		other := &Auth{}
+		WithSignedClaims([]byte("other"))(other)
+		_, err := authenticate(other, annotate(t))
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("missing signature is rejected", func(t *testing.T) {
+		md := annotate(t)
+		md.Delete(SignedClaimsSignatureMetadataKey)
+		_, err := authenticate(backend, md)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("expired claims are rejected", func(t *testing.T) {
+		payload, signature, err := gateway.signClaims(claims, time.Now().Add(-2*DefaultSignedClaimsMaxAge))
+		require.NoError(t, err)
+		_, err = authenticate(backend, metadata.Pairs(
+			SignedClaimsMetadataKey, payload,
+			SignedClaimsSignatureMetadataKey, signature,
+		))
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("claims from unverified peer are rejected", func(t *testing.T) {
+		_, err := backend.GRPC().authenticateGrpcContext(metadata.NewIncomingContext(context.Background(), annotate(t)))
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("empty secret is rejected", func(t *testing.T) {
+		cfg := newTestAuthConfig(t, "")
+		cfg.Token = nil
+		_, err := New(cfg, WithSignedClaims([]byte{}))
+		assert.Error(t, err)
+		_, err = New(cfg, WithSignedClaims(nil))
+		assert.Error(t, err)
+	})
+
+	t.Run("claims are not forwarded without secret", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), TokenClaimsContextKey, claims)
+		md := (&Auth{}).HTTP().MetadataAnnotator(ctx, httptest.NewRequest("GET", "/", nil))
+		assert.Empty(t, md[SignedClaimsMetadataKey])
+	})
+}
@@ -1,12 +1,20 @@
 package auth
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"os"
 	"sync"
+	"time"
 
 	"git.tatikoma.dev/corpix/atlas/errors"
+	"git.tatikoma.dev/corpix/atlas/log"
+)
+
+const (
+	DefaultCertificateExpiryThreshold     = 30 * 24 * time.Hour // month
+	DefaultCertificateExpiryCheckInterval = time.Hour
 )
 
 type TLSConfigCertificateManager struct {
@@ -53,6 +61,66 @@ func (cm *TLSConfigCertificateManager) LoadClientCertificate(certFile, keyFile s
 	return nil
 }
 
+// CheckExpiry logs a warning for each loaded certificate which expires within threshold.
+// Returns the least time left before one of the certificates expires.
+func (cm *TLSConfigCertificateManager) CheckExpiry(ctx context.Context, threshold time.Duration) (time.Duration, error) {
+	cm.mu.RLock()
+	certs := map[string]*tls.Certificate{
+		"server": cm.cert,
+		"client": cm.clientCert,
+	}
+	cm.mu.RUnlock()
+
+	var (
+		left  time.Duration
+		found bool
+	)
+	for kind, cert := range certs {
+		if cert == nil {
+			continue
+		}
+		notAfter, err := certificateNotAfter(cert)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to read %s certificate expiry", kind)
+		}
+		certLeft := time.Until(notAfter)
+		if certLeft < threshold {
+			log.Ctx(ctx).Warn().
+				Str("certificate", kind).
+				Time("not_after", notAfter).
+				Str("left", certLeft.String()).
+				Msg("certificate is about to expire")
+		}
+		if !found || certLeft < left {
+			left = certLeft
+			found = true
+		}
+	}
+
+	return left, nil
+}
+
+// RunExpiryCheck calls CheckExpiry every interval until ctx is done.
+// Non-positive interval falls back to DefaultCertificateExpiryCheckInterval.
+func (cm *TLSConfigCertificateManager) RunExpiryCheck(ctx context.Context, interval, threshold time.Duration) {
+	if interval <= 0 {
+		interval = DefaultCertificateExpiryCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := cm.CheckExpiry(ctx, threshold)
+		errors.LogCtx(ctx, err, "failed to check certificate expiry")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 func NewTLSConfigCertificateManager() *TLSConfigCertificateManager {
 	return &TLSConfigCertificateManager{}
 }
@@ -95,6 +163,17 @@ func ApplyClientCertPolicy(tc *tls.Config) {
 	tc.ClientCAs = tc.RootCAs
 }
 
+// ApplyRequireClientCertPolicy enforces mutual TLS, clients without
+// a certificate signed by RootCAs are rejected at handshake.
+func ApplyRequireClientCertPolicy(tc *tls.Config) error {
+	if tc.RootCAs == nil {
+		return errors.New("CA pool is required to verify client certificates")
+	}
+	tc.ClientAuth = tls.RequireAndVerifyClientCert
+	tc.ClientCAs = tc.RootCAs
+	return nil
+}
+
 func newBaseTLSConfig(hostname string, certPool *x509.CertPool) *tls.Config {
 	return &tls.Config{
 		ServerName: hostname,
@@ -113,6 +192,21 @@ func newBaseTLSConfig(hostname string, certPool *x509.CertPool) *tls.Config {
 	}
 }
 
+func certificateNotAfter(cert *tls.Certificate) (time.Time, error) {
+	leaf := cert.Leaf
+	if leaf == nil {
+		if len(cert.Certificate) == 0 {
+			return time.Time{}, errors.New("certificate chain is empty")
+		}
+		var err error
+		leaf, err = x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	return leaf.NotAfter, nil
+}
+
 func isClientCertificate(cert *x509.Certificate) bool {
 	if len(cert.ExtKeyUsage) == 0 {
 		return false
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCerts struct {
+	prefix string
+	ca     string
+	cert   string
+	key    string
+}
+
+func newTestCerts(t *testing.T) testCerts {
+	t.Helper()
+
+	registry := NewCertTypeRegistry()
+	require.NoError(t, registry.Register("server", CertType{
+		KeyFile:  "server-key.pem",
+		CertFile: "server-cert.pem",
+	}))
+	tool := NewCertTool(registry)
+
+	prefix := filepath.Join(t.TempDir(), "test")
+	require.NoError(t, tool.Generate(CertToolGenerateOptions{
+		NamePrefix: prefix,
+		CommonName: "atlas-ca",
+		GenerateCA: true,
+	}))
+	require.NoError(t, tool.Generate(CertToolGenerateOptions{
+		NamePrefix:  prefix,
+		Type:        "server",
+		CommonName:  "localhost",
+		IPAddresses: "127.0.0.1",
+		DNSNames:    "localhost",
+	}))
+
+	return testCerts{
+		prefix: prefix,
+		ca:     prefix + "." + CACertFile,
+		cert:   prefix + ".server-cert.pem",
+		key:    prefix + ".server-key.pem",
+	}
+}
+
+func testHandshake(t *testing.T, server, client *tls.Config) (error, error) {
+	t.Helper()
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", server)
+	require.NoError(t, err)
+	defer l.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		err = conn.(*tls.Conn).Handshake()
+		if err == nil {
+			_, err = conn.Read(make([]byte, 1))
+		}
+		serverErr <- err
+	}()
+
+	conn, err := tls.Dial("tcp", l.Addr().String(), client)
+	if err != nil {
+		return <-serverErr, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// tls 1.3 client finishes handshake before server verifies its certificate,
+	// write makes server read and report the verification result
+	_, _ = conn.Write([]byte{0})
+
+	return <-serverErr, nil
+}
+
+func TestRequireClientCert(t *testing.T) {
+	certs := newTestCerts(t)
+
+	t.Run("rejects certless client", func(t *testing.T) {
+		u, err := url.Parse("https://localhost")
+		require.NoError(t, err)
+
+		a, err := New(Config{
+			URL: u,
+			Certificate: &CertificateConfig{
+				CA:   certs.ca,
+				Cert: certs.cert,
+				Key:  certs.key,
+			},
+		}, WithRequireClientCert())
+		require.NoError(t, err)
+
+		server := a.TLSConfig()
+		assert.Equal(t, tls.RequireAndVerifyClientCert, server.ClientAuth)
+
+		certPool, err := NewCertPoolFromFile(certs.ca)
+		require.NoError(t, err)
+		client := newBaseTLSConfig("localhost", certPool)
+
+		serverErr, _ := testHandshake(t, server, client)
+		assert.Error(t, serverErr)
+	})
+
+	t.Run("accepts client with certificate", func(t *testing.T) {
+		server, err := NewTLSConfig("localhost", certs.ca, certs.cert, certs.key)
+		require.NoError(t, err)
+		require.NoError(t, ApplyRequireClientCertPolicy(server))
+
+		client, err := NewTLSConfig("localhost", certs.ca, certs.cert, certs.key)
+		require.NoError(t, err)
+
+		serverErr, clientErr := testHandshake(t, server, client)
+		assert.NoError(t, clientErr)
+		assert.NoError(t, serverErr)
+	})
+
+	t.Run("requires CA pool", func(t *testing.T) {
+		assert.Error(t, ApplyRequireClientCertPolicy(&tls.Config{}))
+	})
+}
+
+func writeTestCertificate(t *testing.T, notAfter time.Time) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certBytes}), 0o600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), 0o600))
+
+	return certPath, keyPath
+}
+
+func TestCertificateManagerCheckExpiry(t *testing.T) {
+	check := func(t *testing.T, notAfter time.Time) (time.Duration, string) {
+		certPath, keyPath := writeTestCertificate(t, notAfter)
+		cm := NewTLSConfigCertificateManager()
+		require.NoError(t, cm.LoadCertificate(certPath, keyPath))
+
+		var buf bytes.Buffer
+		logger := zerolog.New(&buf)
+		ctx := logger.WithContext(context.Background())
+
+		left, err := cm.CheckExpiry(ctx, DefaultCertificateExpiryThreshold)
+		require.NoError(t, err)
+		return left, buf.String()
+	}
+
+	t.Run("warns about near expiry", func(t *testing.T) {
+		left, logs := check(t, time.Now().Add(24*time.Hour))
+		assert.Less(t, left, DefaultCertificateExpiryThreshold)
+		assert.Contains(t, logs, "certificate is about to expire")
+	})
+
+	t.Run("silent for fresh certificate", func(t *testing.T) {
+		left, logs := check(t, time.Now().AddDate(1, 0, 0))
+		assert.Greater(t, left, DefaultCertificateExpiryThreshold)
+		assert.Empty(t, logs)
+	})
+}
@@ -0,0 +1,179 @@
+package rpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	CircuitBreakerClosed CircuitBreakerState = iota
+	CircuitBreakerOpen
+	CircuitBreakerHalfOpen
+)
+
+var (
+	ErrCircuitBreakerOpen = status.Error(codes.Unavailable, "circuit breaker is open")
+
+	DefaultCircuitBreakerConfig = CircuitBreakerConfig{
+		FailureRate: 0.5,
+		MinCalls:    10,
+		Window:      10 * time.Second,
+		OpenTimeout: 5 * time.Second,
+	}
+)
+
+type (
+	CircuitBreakerState int
+
+	CircuitBreakerConfig struct {
+		// FailureRate opens breaker once ratio of failed calls within Window reaches it.
+		FailureRate float64
+		// MinCalls is a number of calls within Window required to evaluate failure rate.
+		MinCalls int
+		Window   time.Duration
+		// OpenTimeout is a time breaker stays open before single probe call is let through.
+		OpenTimeout time.Duration
+		// IsFailure reports whether call error means backend is unhealthy, IsCircuitBreakerFailure by default.
+		IsFailure func(err error) bool
+	}
+
+	// CircuitBreaker fails calls fast while backend is failing, it opens once failure rate
+	// is reached and closes again when probe call succeeds after OpenTimeout.
+	CircuitBreaker struct {
+		cfg         CircuitBreakerConfig
+		state       CircuitBreakerState
+		windowStart time.Time
+		openedAt    time.Time
+		calls       int
+		failures    int
+		probing     bool
+		now         func() time.Time
+		mu          sync.Mutex
+	}
+)
+
+func (s CircuitBreakerState) String() string {
+	switch s {
+	case CircuitBreakerClosed:
+		return "closed"
+	case CircuitBreakerOpen:
+		return "open"
+	case CircuitBreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// IsCircuitBreakerFailure reports whether err means backend is unavailable or broken,
+// errors caused by the request itself or by the caller do not count.
+func IsCircuitBreakerFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown, codes.DataLoss:
+		return true
+	default:
+		return false
+	}
+}
+
+func NewCircuitBreaker(cfg CircuitBreakerConfig) *CircuitBreaker {
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = IsCircuitBreakerFailure
+	}
+	cfg.MinCalls = max(cfg.MinCalls, 1)
+	return &CircuitBreaker{cfg: cfg, now: time.Now}
+}
+
+func (b *CircuitBreaker) State() CircuitBreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// allow reports whether call could be made, probe is true for the call made in half-open state.
+func (b *CircuitBreaker) allow() (allowed bool, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitBreakerOpen:
+		if b.now().Sub(b.openedAt) < b.cfg.OpenTimeout {
+			return false, false
+		}
+		b.state = CircuitBreakerHalfOpen
+		fallthrough
+	case CircuitBreakerHalfOpen:
+		if b.probing {
+			return false, false
+		}
+		b.probing = true
+		return true, true
+	default:
+		return true, false
+	}
+}
+
+func (b *CircuitBreaker) done(err error, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failure := err != nil && b.cfg.IsFailure(err)
+	if probe {
+		b.probing = false
+		switch {
+		case failure:
+			b.open()
+		case status.Code(err) == codes.Canceled:
+			// note: caller gave up, probe tells nothing about backend health
+		default:
+			b.state = CircuitBreakerClosed
+			b.reset(b.now())
+		}
+		return
+	}
+	if b.state != CircuitBreakerClosed {
+		return
+	}
+
+	now := b.now()
+	if now.Sub(b.windowStart) >= b.cfg.Window {
+		b.reset(now)
+	}
+	b.calls++
+	if failure {
+		b.failures++
+	}
+	if b.calls >= b.cfg.MinCalls && float64(b.failures)/float64(b.calls) >= b.cfg.FailureRate {
+		b.open()
+	}
+}
+
+func (b *CircuitBreaker) open() {
+	b.state = CircuitBreakerOpen
+	b.openedAt = b.now()
+}
+
+func (b *CircuitBreaker) reset(now time.Time) {
+	b.windowStart = now
+	b.calls, b.failures = 0, 0
+}
+
+// UnaryClientInterceptorWithCircuitBreaker fails calls with ErrCircuitBreakerOpen while breaker is open.
+// Retrying interceptor should be chained before it, so every attempt is accounted by breaker
+// and retries stop once it opens.
+func UnaryClientInterceptorWithCircuitBreaker(b *CircuitBreaker) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		allowed, probe := b.allow()
+		if !allowed {
+			return ErrCircuitBreakerOpen
+		}
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		b.done(err, probe)
+		return err
+	}
+}
@@ -0,0 +1,78 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	b := NewCircuitBreaker(CircuitBreakerConfig{
+		FailureRate: 0.5,
+		MinCalls:    4,
+		Window:      time.Minute,
+		OpenTimeout: time.Second,
+	})
+	b.now = func() time.Time { return now }
+
+	var invoked int
+	interceptor := UnaryClientInterceptorWithCircuitBreaker(b)
+	call := func(err error) error {
+		return interceptor(context.Background(), "/atlas.Test/Get", nil, nil, nil,
+			func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+				invoked++
+				return err
+			},
+		)
+	}
+	unavailable := status.Error(codes.Unavailable, "backend is down")
+
+	require.NoError(t, call(nil))
+	require.Error(t, call(status.Error(codes.NotFound, "not found")))
+	require.Error(t, call(unavailable))
+	assert.Equal(t, CircuitBreakerClosed, b.State(), "client errors are not failures")
+	require.Error(t, call(unavailable))
+	assert.Equal(t, CircuitBreakerOpen, b.State())
+
+	invoked = 0
+	err := call(nil)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.ErrorIs(t, err, ErrCircuitBreakerOpen)
+	assert.Zero(t, invoked, "open breaker fails fast")
+
+	t.Run("failed probe", func(t *testing.T) {
+		now = now.Add(time.Second)
+		require.ErrorIs(t, call(unavailable), unavailable)
+		assert.Equal(t, 1, invoked)
+		assert.Equal(t, CircuitBreakerOpen, b.State())
+		assert.ErrorIs(t, call(nil), ErrCircuitBreakerOpen)
+	})
+
+	t.Run("single probe at a time", func(t *testing.T) {
+		now = now.Add(time.Second)
+		err := interceptor(context.Background(), "/atlas.Test/Get", nil, nil, nil,
+			func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+				assert.Equal(t, CircuitBreakerHalfOpen, b.State())
+				assert.ErrorIs(t, call(nil), ErrCircuitBreakerOpen)
+				return status.Error(codes.Canceled, "canceled")
+			},
+		)
+		assert.Equal(t, codes.Canceled, status.Code(err))
+		assert.Equal(t, CircuitBreakerHalfOpen, b.State(), "canceled probe does not change state")
+	})
+
+	t.Run("recovery", func(t *testing.T) {
+		require.NoError(t, call(nil))
+		assert.Equal(t, CircuitBreakerClosed, b.State())
+		require.NoError(t, call(nil))
+		require.Error(t, call(unavailable))
+		assert.Equal(t, CircuitBreakerClosed, b.State(), "failure rate is counted from scratch")
+	})
+}
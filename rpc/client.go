@@ -1,35 +1,148 @@
 package rpc
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	grpclog "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials"
 
+	"git.tatikoma.dev/corpix/atlas/backoff"
 	"git.tatikoma.dev/corpix/atlas/log"
 	"git.tatikoma.dev/corpix/atlas/rpc/auth"
 )
 
-func NewClientConn(a *auth.Auth, l log.Logger, host string, port int) (*grpc.ClientConn, error) {
+type clientOptions struct {
+	fingerprints   []string
+	breaker        *CircuitBreaker
+	defaultTimeout time.Duration
+}
+
+type ClientOption func(*clientOptions)
+
+// WithPinnedCertificate makes client accept server only if its certificate
+// SHA-256 fingerprint is one of fingerprints, see auth.ApplyFingerprintPinning.
+func WithPinnedCertificate(fingerprints ...string) ClientOption {
+	return func(opts *clientOptions) {
+		opts.fingerprints = append(opts.fingerprints, fingerprints...)
+	}
+}
+
+// WithCircuitBreaker makes client fail unary calls fast while backend is failing, see CircuitBreaker.
+func WithCircuitBreaker(b *CircuitBreaker) ClientOption {
+	return func(opts *clientOptions) {
+		opts.breaker = b
+	}
+}
+
+// WithDefaultCallTimeout sets deadline of unary calls which context has no deadline,
+// so call waiting for ready connection does not hang forever.
+func WithDefaultCallTimeout(d time.Duration) ClientOption {
+	return func(opts *clientOptions) {
+		opts.defaultTimeout = d
+	}
+}
+
+func NewClientConn(a *auth.Auth, l log.Logger, host string, port int, options ...ClientOption) (*grpc.ClientConn, error) {
+	var opts clientOptions
+	for _, option := range options {
+		option(&opts)
+	}
+
+	creds := a.GRPC().DialOption()
+	if len(opts.fingerprints) > 0 {
+		tc := a.TLSConfig()
+		auth.ApplyFingerprintPinning(tc, opts.fingerprints...)
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(tc))
+	}
+
+	unary := []grpc.UnaryClientInterceptor{
+		grpclog.UnaryClientInterceptor(
+			LoggerInterceptor(l),
+			grpclog.WithLogOnEvents(grpclog.StartCall, grpclog.FinishCall),
+		),
+		UnaryClientInterceptorWithCapabilityHints(l),
+	}
+	if opts.defaultTimeout > 0 {
+		unary = append(unary, UnaryClientInterceptorWithDefaultTimeout(opts.defaultTimeout))
+	}
+	if opts.breaker != nil {
+		unary = append(unary, UnaryClientInterceptorWithCircuitBreaker(opts.breaker))
+	}
+
 	return grpc.NewClient(
 		fmt.Sprintf("%s:%d", host, port),
-		a.GRPC().DialOption(),
+		creds,
 		grpc.WithDisableServiceConfig(),
-		grpc.WithChainUnaryInterceptor(grpclog.UnaryClientInterceptor(
-			LoggerInterceptor(l),
-			grpclog.WithLogOnEvents(grpclog.StartCall, grpclog.FinishCall),
-		)),
+		grpc.WithChainUnaryInterceptor(unary...),
+		grpc.WithChainStreamInterceptor(StreamClientInterceptorWithCapabilityHints(l)),
 		grpc.WithDefaultCallOptions(grpc.WaitForReady(true)),
 		grpc.WithConnectParams(grpc.ConnectParams{
-			Backoff: backoff.Config{
-				BaseDelay:  1 * time.Second,
-				Multiplier: 1.5,
-				Jitter:     0.2,
-				MaxDelay:   10 * time.Second,
-			},
+			Backoff:           backoff.DefaultConfig,
 			MinConnectTimeout: 20 * time.Second,
 		}),
 	)
 }
+
+// UnaryClientInterceptorWithDefaultTimeout applies timeout d to calls which context has no deadline.
+func UnaryClientInterceptorWithDefaultTimeout(d time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, d)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func logCapabilityHint(l log.Logger, err error) {
+	hint, ok := auth.ParseCapabilitiesNotSatisfied(err)
+	if !ok {
+		return
+	}
+	l.Warn().
+		Str("method", hint.Method).
+		Str("has", hint.Has).
+		Str("want", hint.Want).
+		Msg("call rejected, client capability set does not satisfy method rule")
+}
+
+// UnaryClientInterceptorWithCapabilityHints logs capabilities client has and server wants
+// when call is rejected because client capability set is not satisfied.
+func UnaryClientInterceptorWithCapabilityHints(l log.Logger) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err != nil {
+			logCapabilityHint(l, err)
+		}
+		return err
+	}
+}
+
+// StreamClientInterceptorWithCapabilityHints is like UnaryClientInterceptorWithCapabilityHints, but for streams.
+func StreamClientInterceptorWithCapabilityHints(l log.Logger) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			logCapabilityHint(l, err)
+			return nil, err
+		}
+		return &capabilityHintClientStream{ClientStream: stream, logger: l}, nil
+	}
+}
+
+type capabilityHintClientStream struct {
+	grpc.ClientStream
+	logger log.Logger
+}
+
+func (s *capabilityHintClientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		logCapabilityHint(s.logger, err)
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCapabilityHints(t *testing.T) {
+	var buf bytes.Buffer
+	interceptor := UnaryClientInterceptorWithCapabilityHints(zerolog.New(&buf))
+	call := func(err error) error {
+		return interceptor(context.Background(), "/atlas.Test/Get", nil, nil, nil,
+			func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+				return err
+			},
+		)
+	}
+
+	t.Run("logs missing capabilities", func(t *testing.T) {
+		buf.Reset()
+		err := status.Errorf(
+			codes.InvalidArgument,
+			"required client capability set for %q not satisfied, has: %s, want: %s",
+			"/atlas.Test/Get", "[read]", "[read write]",
+		)
+		assert.Equal(t, err, call(err))
+		assert.Contains(t, buf.String(), `"method":"/atlas.Test/Get"`)
+		assert.Contains(t, buf.String(), `"has":"[read]"`)
+		assert.Contains(t, buf.String(), `"want":"[read write]"`)
+	})
+
+	t.Run("ignores other errors", func(t *testing.T) {
+		buf.Reset()
+		assert.Error(t, call(status.Error(codes.InvalidArgument, "invalid request")))
+		assert.Error(t, call(status.Error(codes.Unauthenticated, "missing token")))
+		assert.Empty(t, buf.String())
+	})
+}
+
+func TestDefaultCallTimeout(t *testing.T) {
+	interceptor := UnaryClientInterceptorWithDefaultTimeout(20 * time.Millisecond)
+	call := func(ctx context.Context) (time.Time, bool, error) {
+		var (
+			deadline time.Time
+			ok       bool
+		)
+		err := interceptor(ctx, "/atlas.Test/Get", nil, nil, nil,
+			func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+				deadline, ok = ctx.Deadline()
+				<-ctx.Done()
+				return status.FromContextError(ctx.Err()).Err()
+			},
+		)
+		return deadline, ok, err
+	}
+
+	t.Run("applies default", func(t *testing.T) {
+		started := time.Now()
+		deadline, ok, err := call(context.Background())
+		require.True(t, ok)
+		assert.WithinDuration(t, started.Add(20*time.Millisecond), deadline, 10*time.Millisecond)
+		assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	})
+
+	t.Run("keeps caller deadline", func(t *testing.T) {
+		expected := time.Now().Add(30 * time.Millisecond)
+		ctx, cancel := context.WithDeadline(context.Background(), expected)
+		defer cancel()
+		deadline, ok, err := call(ctx)
+		require.True(t, ok)
+		assert.Equal(t, expected, deadline)
+		assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	})
+}
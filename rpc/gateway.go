@@ -5,6 +5,7 @@ import (
 	"net"
 	"net/http"
 	"net/textproto"
+	"net/url"
 	"strings"
 	"time"
 
@@ -99,19 +100,65 @@ func DefaultGatewayErrorHandler(ctx context.Context, mux *gruntime.ServeMux, mar
 	gruntime.DefaultHTTPErrorHandler(ctx, mux, marshaler, w, r, respErr)
 }
 
+// Register mounts gateway under the prefix, prefix is matched on path segment
+// boundaries with or without trailing slash, so for prefix "/api" (or "/api/")
+// both "/api" and "/api/" are served as "/", while "/apix" is not served.
 func (g *Gateway) Register(mux *http.ServeMux) {
-	prefix := g.prefix
-	mux.Handle(prefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		trimmed := strings.TrimPrefix(r.URL.Path, prefix)
-		r.URL.Path = "/" + strings.TrimPrefix(trimmed, "/")
-		g.mux.ServeHTTP(w, r)
-	}))
+	prefix := gatewayPrefix(g.prefix)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, ok := gatewayTrimPrefix(prefix, r.URL.Path)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		rawPath, _ := gatewayTrimPrefix(prefix, r.URL.RawPath)
+
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = path
+		if r.URL.RawPath != "" {
+			r2.URL.RawPath = rawPath
+		}
+		g.mux.ServeHTTP(w, r2)
+	})
+
+	mux.Handle(prefix+"/", handler)
+	if prefix != "" {
+		mux.Handle(prefix, handler)
+	}
+}
+
+// gatewayPrefix normalizes prefix to the form without trailing slash,
+// root prefix becomes empty string.
+func gatewayPrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return ""
+	}
+	return "/" + prefix
+}
+
+func gatewayTrimPrefix(prefix string, path string) (string, bool) {
+	if path == prefix || path == prefix+"/" {
+		return "/", true
+	}
+	if !strings.HasPrefix(path, prefix+"/") {
+		return "", false
+	}
+	return path[len(prefix):], true
 }
 
 func (g *Gateway) Serve(l net.Listener) error {
 	return g.server.Serve(l)
 }
 
+// Shutdown gracefully stops the gateway, see http.Server.Shutdown.
+func (g *Gateway) Shutdown(ctx context.Context) error {
+	return g.server.Shutdown(ctx)
+}
+
 func (g *Gateway) Close() error {
 	return g.server.Close()
 }
@@ -0,0 +1,58 @@
+package rpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGatewayRegister(t *testing.T) {
+	cases := []struct {
+		prefix string
+		path   string
+		want   string
+		status int
+	}{
+		{prefix: "/", path: "/", want: "/"},
+		{prefix: "/", path: "/v1/items", want: "/v1/items"},
+		{prefix: "/", path: "/v1/items/", want: "/v1/items/"},
+		{prefix: "/api", path: "/api", want: "/"},
+		{prefix: "/api", path: "/api/", want: "/"},
+		{prefix: "/api", path: "/api/v1/items", want: "/v1/items"},
+		{prefix: "/api", path: "/apix/v1/items", status: http.StatusNotFound},
+		{prefix: "/api", path: "/v1/items", status: http.StatusNotFound},
+		{prefix: "/api/", path: "/api", want: "/"},
+		{prefix: "/api/", path: "/api/", want: "/"},
+		{prefix: "/api/", path: "/api/v1/items", want: "/v1/items"},
+		{prefix: "/api/", path: "/apix", status: http.StatusNotFound},
+		{prefix: "api", path: "/api/v1/items", want: "/v1/items"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.prefix+" "+c.path, func(t *testing.T) {
+			var got string
+			g := &Gateway{
+				prefix: c.prefix,
+				mux: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					got = r.URL.Path
+				}),
+			}
+			mux := http.NewServeMux()
+			g.Register(mux)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			mux.ServeHTTP(rec, req)
+
+			status := c.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			assert.Equal(t, status, rec.Code)
+			assert.Equal(t, c.want, got)
+			assert.Equal(t, c.path, req.URL.Path, "original request should not be modified")
+		})
+	}
+}
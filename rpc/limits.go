@@ -0,0 +1,73 @@
+package rpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// RequestSizeLimits defines max size of decoded request message in bytes,
+// zero means no limit. Methods are keyed by full method name (/package.Service/Method).
+type RequestSizeLimits struct {
+	Methods map[string]int
+	Default int
+}
+
+func (l RequestSizeLimits) limit(method string) int {
+	if limit, ok := l.Methods[method]; ok {
+		return limit
+	}
+	return l.Default
+}
+
+func (l RequestSizeLimits) check(method string, req any) error {
+	limit := l.limit(method)
+	if limit <= 0 {
+		return nil
+	}
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return nil
+	}
+	size := proto.Size(msg)
+	if size > limit {
+		return status.Errorf(codes.ResourceExhausted, "request size %d exceeds limit %d for %s", size, limit, method)
+	}
+	return nil
+}
+
+func UnaryServerInterceptorWithRequestSizeLimits(limits RequestSizeLimits) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := limits.check(info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func StreamServerInterceptorWithRequestSizeLimits(limits RequestSizeLimits) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapper := &sizeLimitStreamWrapper{
+			ServerStream: ss,
+			limits:       limits,
+			method:       info.FullMethod,
+		}
+		return handler(srv, wrapper)
+	}
+}
+
+type sizeLimitStreamWrapper struct {
+	grpc.ServerStream
+	limits RequestSizeLimits
+	method string
+}
+
+func (s *sizeLimitStreamWrapper) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.limits.check(s.method, m)
+}
@@ -0,0 +1,49 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestRequestSizeLimits(t *testing.T) {
+	const method = "/atlas.Test/Upload"
+	interceptor := UnaryServerInterceptorWithRequestSizeLimits(RequestSizeLimits{
+		Methods: map[string]int{method: 64},
+		Default: 16,
+	})
+	handler := func(ctx context.Context, req any) (any, error) {
+		return req, nil
+	}
+	call := func(method string, size int) error {
+		_, err := interceptor(
+			context.Background(),
+			wrapperspb.String(strings.Repeat("a", size)),
+			&grpc.UnaryServerInfo{FullMethod: method},
+			handler,
+		)
+		return err
+	}
+
+	t.Run("accepts request under limit", func(t *testing.T) {
+		assert.NoError(t, call(method, 32))
+	})
+
+	t.Run("rejects oversized request", func(t *testing.T) {
+		err := call(method, 128)
+		require.Error(t, err)
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("applies default limit to other methods", func(t *testing.T) {
+		assert.Equal(t, codes.ResourceExhausted, status.Code(call("/atlas.Test/Other", 32)))
+		assert.NoError(t, call("/atlas.Test/Other", 8))
+	})
+}
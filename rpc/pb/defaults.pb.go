@@ -0,0 +1,86 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: defaults.proto
+
+package atlasrpc
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+var file_defaults_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         50700,
+		Name:          "atlas_rpc.default",
+		Tag:           "bytes,50700,opt,name=default",
+		Filename:      "defaults.proto",
+	},
+}
+
+// Extension fields to descriptorpb.FieldOptions.
+var (
+	// Default value of scalar field with presence (proto3 optional) which is applied when field is not set.
+	// Numbers and bools are parsed with Go strconv, e.g. "10", "1.5" or "true",
+	// enums are given by value name or number, e.g. "RED", strings and bytes are taken as is.
+	//
+	// optional string default = 50700;
+	E_Default = &file_defaults_proto_extTypes[0]
+)
+
+var File_defaults_proto protoreflect.FileDescriptor
+
+const file_defaults_proto_rawDesc = "" +
+	"\n" +
+	"\x0edefaults.proto\x12\tatlas_rpc\x1a google/protobuf/descriptor.proto:9\n" +
+	"\adefault\x12\x1d.google.protobuf.FieldOptions\x18\x8c\x8c\x03 \x01(\tR\adefaultB/Z-git.tatikoma.dev/corpix/atlas/rpc/pb;atlasrpcb\x06proto3"
+
+var file_defaults_proto_goTypes = []any{
+	(*descriptorpb.FieldOptions)(nil), // 0: google.protobuf.FieldOptions
+}
+var file_defaults_proto_depIdxs = []int32{
+	0, // 0: atlas_rpc.default:extendee -> google.protobuf.FieldOptions
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	0, // [0:1] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_defaults_proto_init() }
+func file_defaults_proto_init() {
+	if File_defaults_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_defaults_proto_rawDesc), len(file_defaults_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   0,
+			NumExtensions: 1,
+			NumServices:   0,
+		},
+		GoTypes:           file_defaults_proto_goTypes,
+		DependencyIndexes: file_defaults_proto_depIdxs,
+		ExtensionInfos:    file_defaults_proto_extTypes,
+	}.Build()
+	File_defaults_proto = out.File
+	file_defaults_proto_goTypes = nil
+	file_defaults_proto_depIdxs = nil
+}
@@ -0,0 +1,157 @@
+package rpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"git.tatikoma.dev/corpix/atlas/rpc/auth"
+	"git.tatikoma.dev/corpix/atlas/seq"
+	"git.tatikoma.dev/corpix/protoc-gen-grpc-capabilities/capabilities"
+)
+
+// DefaultRateLimiterCallers limits number of callers which buckets are tracked at once.
+const DefaultRateLimiterCallers = 4096
+
+type (
+	// RateLimiter reports whether call to method is allowed,
+	// it receives capabilities of the caller from the context.
+	RateLimiter interface {
+		Allow(ctx context.Context, method string, caps capabilities.Capabilities) bool
+	}
+	RateLimiterFunc func(ctx context.Context, method string, caps capabilities.Capabilities) bool
+
+	// RateLimit is a token bucket configuration, Rate is in requests per second.
+	// Zero Rate means no limit.
+	RateLimit struct {
+		Rate  float64
+		Burst int
+	}
+
+	// CapabilityRateLimiter limits callers according to their capabilities,
+	// most permissive limit of caller capabilities applies, Default is used when none matched.
+	// Buckets are tracked per caller, caller is identified by token email
+	// or by capability set when token is absent.
+	CapabilityRateLimiter struct {
+		Limits  map[capabilities.CapabilityID]RateLimit
+		Default RateLimit
+
+		buckets *seq.LRU[string, *rateLimitBucket]
+		once    sync.Once
+		mu      sync.Mutex
+	}
+
+	rateLimitBucket struct {
+		last   time.Time
+		tokens float64
+		mu     sync.Mutex
+	}
+)
+
+func (f RateLimiterFunc) Allow(ctx context.Context, method string, caps capabilities.Capabilities) bool {
+	return f(ctx, method, caps)
+}
+
+func (l RateLimit) unlimited() bool {
+	return l.Rate <= 0
+}
+
+func (l RateLimit) permissive(other RateLimit) bool {
+	if l.unlimited() || other.unlimited() {
+		return l.unlimited()
+	}
+	if l.Rate != other.Rate {
+		return l.Rate > other.Rate
+	}
+	return l.Burst > other.Burst
+}
+
+func (b *rateLimitBucket) take(limit RateLimit, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	burst := float64(max(limit.Burst, 1))
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (l *CapabilityRateLimiter) limit(caps capabilities.Capabilities) (RateLimit, string) {
+	var (
+		limit   RateLimit
+		id      capabilities.CapabilityID
+		matched bool
+	)
+	for capID := range caps {
+		capLimit, ok := l.Limits[capID]
+		if !ok {
+			continue
+		}
+		if !matched || capLimit.permissive(limit) || (capLimit == limit && capID < id) {
+			limit, id, matched = capLimit, capID, true
+		}
+	}
+	if !matched {
+		return l.Default, ""
+	}
+	return limit, string(id)
+}
+
+func (l *CapabilityRateLimiter) Allow(ctx context.Context, method string, caps capabilities.Capabilities) bool {
+	limit, capID := l.limit(caps)
+	if limit.unlimited() {
+		return true
+	}
+
+	l.once.Do(func() {
+		l.buckets = seq.NewLRU[string, *rateLimitBucket](DefaultRateLimiterCallers, 0)
+	})
+
+	caller := caps.String()
+	if claims, ok := ctx.Value(auth.TokenClaimsContextKey).(*auth.Claims); ok && claims.Email != "" {
+		caller = claims.Email
+	}
+	key := capID + "|" + caller
+
+	l.mu.Lock()
+	bucket, ok := l.buckets.Get(key)
+	if !ok {
+		bucket = &rateLimitBucket{}
+		l.buckets.Add(key, bucket)
+	}
+	l.mu.Unlock()
+
+	return bucket.take(limit, time.Now())
+}
+
+func UnaryServerInterceptorWithRateLimiter(l RateLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !l.Allow(ctx, info.FullMethod, capabilities.CapabilitiesFromContext(ctx)) {
+			return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded for %s", info.FullMethod)
+		}
+		return handler(ctx, req)
+	}
+}
+
+func StreamServerInterceptorWithRateLimiter(l RateLimiter) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		if !l.Allow(ctx, info.FullMethod, capabilities.CapabilitiesFromContext(ctx)) {
+			return status.Errorf(codes.ResourceExhausted, "rate limit exceeded for %s", info.FullMethod)
+		}
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,77 @@
+package rpc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"git.tatikoma.dev/corpix/atlas/rpc/auth"
+	"git.tatikoma.dev/corpix/protoc-gen-grpc-capabilities/capabilities"
+)
+
+func TestCapabilityRateLimiter(t *testing.T) {
+	const method = "/atlas.Test/Get"
+	newCaps := func(ids ...string) capabilities.Capabilities {
+		caps := capabilities.Capabilities{}
+		for _, id := range ids {
+			c := capabilities.NewCapability(capabilities.CapabilityLiteral(id))
+			caps[c.ID] = c
+		}
+		return caps
+	}
+	allowed := func(l RateLimiter, ctx context.Context, caps capabilities.Capabilities, calls int) int {
+		n := 0
+		for range calls {
+			if l.Allow(ctx, method, caps) {
+				n++
+			}
+		}
+		return n
+	}
+	withEmail := func(email string) context.Context {
+		return context.WithValue(context.Background(), auth.TokenClaimsContextKey, &auth.Claims{Email: email})
+	}
+
+	admin := capabilities.NewCapability("admin").ID
+	user := capabilities.NewCapability("user").ID
+	l := &CapabilityRateLimiter{
+		Limits: map[capabilities.CapabilityID]RateLimit{
+			admin: {},
+			user:  {Rate: 0.001, Burst: 3},
+		},
+		Default: RateLimit{Rate: 0.001, Burst: 1},
+	}
+
+	t.Run("different capabilities get different limits", func(t *testing.T) {
+		assert.Equal(t, 10, allowed(l, withEmail("admin@atlas.local"), newCaps("admin", "user"), 10))
+		assert.Equal(t, 3, allowed(l, withEmail("user@atlas.local"), newCaps("user"), 10))
+		assert.Equal(t, 1, allowed(l, withEmail("guest@atlas.local"), newCaps(), 10))
+	})
+
+	t.Run("callers have separate buckets", func(t *testing.T) {
+		assert.Equal(t, 3, allowed(l, withEmail("other@atlas.local"), newCaps("user"), 10))
+	})
+
+	t.Run("concurrent first calls share bucket", func(t *testing.T) {
+		var (
+			n  atomic.Int32
+			wg sync.WaitGroup
+		)
+		ctx := withEmail("concurrent@atlas.local")
+		caps := newCaps("user")
+		for range 32 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if l.Allow(ctx, method, caps) {
+					n.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int32(3), n.Load())
+	})
+}
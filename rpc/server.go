@@ -16,8 +16,10 @@ func NewServer(tlsCfg *tls.Config, a *auth.Auth, l log.Logger) *grpc.Server {
 }
 
 type serverOptions struct {
-	validator   Validator
-	transformer Transformer
+	validator         Validator
+	transformers      []Transformer
+	requestSizeLimits *RequestSizeLimits
+	rateLimiter       RateLimiter
 }
 
 type ServerOption func(*serverOptions)
@@ -28,34 +30,82 @@ func WithValidator(v Validator) ServerOption {
 	}
 }
 
+// WithTransformer replaces DefaultsTransformer applied to requests, e.g. with OptionsDefaultsTransformer,
+// transformers of multiple WithTransformer options are chained in order, see ChainTransformer.
 func WithTransformer(t Transformer) ServerOption {
 	return func(opts *serverOptions) {
-		opts.transformer = t
+		opts.transformers = append(opts.transformers, t)
 	}
 }
 
+// WithRequestSizeLimits rejects requests which exceed size limits with ResourceExhausted.
+func WithRequestSizeLimits(limits RequestSizeLimits) ServerOption {
+	return func(opts *serverOptions) {
+		opts.requestSizeLimits = &limits
+	}
+}
+
+// WithRateLimiter rejects calls which are not allowed by limiter with ResourceExhausted,
+// limiter runs after authorization, so caller capabilities are available.
+func WithRateLimiter(l RateLimiter) ServerOption {
+	return func(opts *serverOptions) {
+		opts.rateLimiter = l
+	}
+}
+
+func (o serverOptions) transformer() Transformer {
+	if len(o.transformers) == 0 {
+		return DefaultsTransformer{}
+	}
+	return ChainTransformer(o.transformers...)
+}
+
+// requestInterceptors returns interceptors transforming and validating requests,
+// transformers run first, so validation sees normalized requests with defaults applied.
+func (o serverOptions) requestInterceptors() ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor) {
+	transformer := o.transformer()
+	unary := []grpc.UnaryServerInterceptor{
+		UnaryServerInterceptorWithTransformer(transformer),
+		UnaryServerInterceptorWithValidator(o.validator),
+	}
+	stream := []grpc.StreamServerInterceptor{
+		StreamServerInterceptorWithTransformer(transformer),
+		StreamServerInterceptorWithValidator(o.validator),
+	}
+	return unary, stream
+}
+
 func NewServerWithOptions(tlsCfg *tls.Config, a *auth.Auth, l log.Logger, options ...ServerOption) *grpc.Server {
 	logger := LoggerInterceptor(l)
 	opts := serverOptions{
-		validator:   validator{},
-		transformer: DefaultsTransformer{},
+		validator: validator{},
 	}
 	for _, option := range options {
 		option(&opts)
 	}
+	unary := []grpc.UnaryServerInterceptor{
+		grpclog.UnaryServerInterceptor(logger),
+		a.GRPC().UnaryInterceptor(),
+	}
+	stream := []grpc.StreamServerInterceptor{
+		grpclog.StreamServerInterceptor(logger),
+		a.GRPC().StreamInterceptor(),
+	}
+	if opts.rateLimiter != nil {
+		unary = append(unary, UnaryServerInterceptorWithRateLimiter(opts.rateLimiter))
+		stream = append(stream, StreamServerInterceptorWithRateLimiter(opts.rateLimiter))
+	}
+	if opts.requestSizeLimits != nil {
+		unary = append(unary, UnaryServerInterceptorWithRequestSizeLimits(*opts.requestSizeLimits))
+		stream = append(stream, StreamServerInterceptorWithRequestSizeLimits(*opts.requestSizeLimits))
+	}
+	requestUnary, requestStream := opts.requestInterceptors()
+	unary = append(unary, requestUnary...)
+	stream = append(stream, requestStream...)
+
 	return grpc.NewServer(
 		grpc.Creds(credentials.NewTLS(tlsCfg)),
-		grpc.ChainUnaryInterceptor(
-			grpclog.UnaryServerInterceptor(logger),
-			a.GRPC().UnaryInterceptor(),
-			UnaryServerInterceptorWithValidator(opts.validator),
-			UnaryServerInterceptorWithTransformer(opts.transformer),
-		),
-		grpc.ChainStreamInterceptor(
-			grpclog.StreamServerInterceptor(logger),
-			a.GRPC().StreamInterceptor(),
-			StreamServerInterceptorWithValidator(opts.validator),
-			StreamServerInterceptorWithTransformer(opts.transformer),
-		),
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
 	)
 }
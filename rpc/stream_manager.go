@@ -0,0 +1,190 @@
+package rpc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+
+	"git.tatikoma.dev/corpix/atlas/backoff"
+)
+
+const DefaultStreamManagerSendQueue = 64
+
+type (
+	StreamManagerConfig[Req, Resp any] struct {
+		Name string
+		// Open opens new stream, it is called on start and on every reconnect.
+		Open func(ctx context.Context) (grpc.BidiStreamingClient[Req, Resp], error)
+		// Key returns handler key for received message.
+		Key func(*Resp) string
+		// OnConnect returns messages sent before queued ones after each (re)connect, e.g. subscriptions.
+		OnConnect func() []*Req
+		// Heartbeat returns message sent every HeartbeatInterval, heartbeat is disabled if nil.
+		Heartbeat         func() *Req
+		HeartbeatInterval time.Duration
+		SendQueue         int
+		Backoff           backoff.Config
+	}
+
+	// StreamManager maintains bidirectional stream, it reconnects with backoff when stream fails,
+	// sends queued messages and dispatches received ones to handlers by key.
+	// Backoff is reset only after stream received a message, so server which accepts
+	// stream and fails right away is reconnected to with growing delays.
+	StreamManager[Req, Resp any] struct {
+		cfg      StreamManagerConfig[Req, Resp]
+		sendCh   chan *Req
+		handlers map[string]func(*Resp)
+		mu       sync.RWMutex
+	}
+)
+
+func NewStreamManager[Req, Resp any](cfg StreamManagerConfig[Req, Resp]) *StreamManager[Req, Resp] {
+	if cfg.SendQueue <= 0 {
+		cfg.SendQueue = DefaultStreamManagerSendQueue
+	}
+	if cfg.Backoff == (backoff.Config{}) {
+		cfg.Backoff = backoff.DefaultConfig
+	}
+	return &StreamManager[Req, Resp]{
+		cfg:      cfg,
+		sendCh:   make(chan *Req, cfg.SendQueue),
+		handlers: map[string]func(*Resp){},
+	}
+}
+
+// Handle registers handler for received messages with key, it replaces previous handler.
+func (m *StreamManager[Req, Resp]) Handle(key string, fn func(*Resp)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers[key] = fn
+}
+
+// Send queues message, it is kept in queue while stream reconnects.
+func (m *StreamManager[Req, Resp]) Send(ctx context.Context, req *Req) error {
+	select {
+	case m.sendCh <- req:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// Run keeps stream connected until ctx is done.
+func (m *StreamManager[Req, Resp]) Run(ctx context.Context) error {
+	var (
+		b       = backoff.New(m.cfg.Backoff)
+		pending *Req
+	)
+	for {
+		var received atomic.Bool
+		pending = m.session(ctx, pending, &received)
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		if received.Load() {
+			b.Reset()
+		}
+
+		delay := b.Next()
+		log.Warn().
+			Str("stream_name", m.cfg.Name).
+			Int("attempt", b.Attempt()).
+			Str("delay", delay.String()).
+			Msg("stream disconnected, reconnecting")
+
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-time.After(delay):
+		}
+	}
+}
+
+// session runs single stream until it fails, message which failed to send is returned to be sent first next time.
+// received is set once first message is received from the stream.
+func (m *StreamManager[Req, Resp]) session(ctx context.Context, pending *Req, received *atomic.Bool) *Req {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	stream, err := m.cfg.Open(ctx)
+	if err != nil {
+		log.Warn().Err(err).Str("stream_name", m.cfg.Name).Msg("failed to open stream")
+		return pending
+	}
+
+	recvDone := make(chan void)
+	go func() {
+		defer close(recvDone)
+		m.recvPump(ctx, cancel, stream, received)
+	}()
+	defer func() { <-recvDone }()
+	defer cancel(nil)
+
+	if m.cfg.OnConnect != nil {
+		for _, req := range m.cfg.OnConnect() {
+			if err := stream.Send(req); err != nil {
+				return pending
+			}
+		}
+	}
+	if pending != nil {
+		if err := stream.Send(pending); err != nil {
+			return pending
+		}
+	}
+
+	var heartbeat <-chan time.Time
+	if m.cfg.Heartbeat != nil && m.cfg.HeartbeatInterval > 0 {
+		ticker := time.NewTicker(m.cfg.HeartbeatInterval)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-heartbeat:
+			if err := stream.Send(m.cfg.Heartbeat()); err != nil {
+				return nil
+			}
+		case req := <-m.sendCh:
+			if err := stream.Send(req); err != nil {
+				return req
+			}
+		}
+	}
+}
+
+func (m *StreamManager[Req, Resp]) recvPump(ctx context.Context, cancel context.CancelCauseFunc, stream grpc.BidiStreamingClient[Req, Resp], received *atomic.Bool) {
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Warn().Err(err).Str("stream_name", m.cfg.Name).Msg("failed to receive from stream")
+			}
+			cancel(err)
+			return
+		}
+		received.Store(true)
+		m.dispatch(resp)
+	}
+}
+
+func (m *StreamManager[Req, Resp]) dispatch(resp *Resp) {
+	key := m.cfg.Key(resp)
+	m.mu.RLock()
+	fn, ok := m.handlers[key]
+	m.mu.RUnlock()
+	if !ok {
+		log.Debug().
+			Str("stream_name", m.cfg.Name).
+			Str("key", key).
+			Msg("no handler for received message, dropping")
+		return
+	}
+	fn(resp)
+}
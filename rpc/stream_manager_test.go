@@ -0,0 +1,176 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"git.tatikoma.dev/corpix/atlas/backoff"
+)
+
+type testBidiStream struct {
+	grpc.ClientStream
+	ctx    context.Context
+	sent   chan string
+	recvCh chan string
+	once   sync.Once
+}
+
+func (s *testBidiStream) Send(req *string) error {
+	select {
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	case s.sent <- *req:
+		return nil
+	}
+}
+
+func (s *testBidiStream) Recv() (*string, error) {
+	select {
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	case resp, ok := <-s.recvCh:
+		if !ok {
+			return nil, io.EOF
+		}
+		return &resp, nil
+	}
+}
+
+func (s *testBidiStream) disconnect() {
+	s.once.Do(func() { close(s.recvCh) })
+}
+
+func TestStreamManager(t *testing.T) {
+	var (
+		sent    = make(chan string, 16)
+		streams = make(chan *testBidiStream, 4)
+	)
+	m := NewStreamManager(StreamManagerConfig[string, string]{
+		Name: "test",
+		Open: func(ctx context.Context) (grpc.BidiStreamingClient[string, string], error) {
+			s := &testBidiStream{ctx: ctx, sent: sent, recvCh: make(chan string)}
+			streams <- s
+			return s, nil
+		},
+		Key: func(resp *string) string { return (*resp)[:1] },
+		OnConnect: func() []*string {
+			sub := "subscribe"
+			return []*string{&sub}
+		},
+		Backoff: backoff.Config{BaseDelay: time.Millisecond, Multiplier: 1, MaxDelay: time.Millisecond},
+	})
+
+	received := make(chan string, 16)
+	m.Handle("a", func(resp *string) { received <- *resp })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- m.Run(ctx) }()
+
+	next := func(ch <-chan string) string {
+		t.Helper()
+		select {
+		case v := <-ch:
+			return v
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+			return ""
+		}
+	}
+	nextStream := func() *testBidiStream {
+		t.Helper()
+		select {
+		case s := <-streams:
+			return s
+		case <-time.After(time.Second):
+			t.Fatal("stream was not opened")
+			return nil
+		}
+	}
+
+	s := nextStream()
+	assert.Equal(t, "subscribe", next(sent))
+
+	t.Run("dispatch", func(t *testing.T) {
+		s.recvCh <- "b1"
+		s.recvCh <- "a1"
+		assert.Equal(t, "a1", next(received), "message without handler is dropped")
+	})
+
+	t.Run("reconnect", func(t *testing.T) {
+		s.disconnect()
+		s = nextStream()
+		assert.Equal(t, "subscribe", next(sent), "subscriptions are restored")
+
+		msg := "hello"
+		require.NoError(t, m.Send(ctx, &msg))
+		assert.Equal(t, "hello", next(sent))
+		s.recvCh <- "a2"
+		assert.Equal(t, "a2", next(received))
+	})
+
+	cancel()
+	select {
+	case err := <-runErr:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("run did not stop")
+	}
+}
+
+func TestStreamManagerBackoff(t *testing.T) {
+	type opened struct {
+		stream *testBidiStream
+		at     time.Time
+	}
+	streams := make(chan opened, 16)
+	m := NewStreamManager(StreamManagerConfig[string, string]{
+		Name: "test",
+		Open: func(ctx context.Context) (grpc.BidiStreamingClient[string, string], error) {
+			s := &testBidiStream{ctx: ctx, sent: make(chan string, 16), recvCh: make(chan string, 1)}
+			streams <- opened{stream: s, at: time.Now()}
+			return s, nil
+		},
+		Key:     func(resp *string) string { return *resp },
+		Backoff: backoff.Config{BaseDelay: 20 * time.Millisecond, Multiplier: 2, MaxDelay: time.Second},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = m.Run(ctx) }()
+
+	next := func() opened {
+		t.Helper()
+		select {
+		case o := <-streams:
+			return o
+		case <-time.After(2 * time.Second):
+			t.Fatal("stream was not opened")
+			return opened{}
+		}
+	}
+
+	// server accepts stream and fails right away, delays grow: 20ms, 40ms, 80ms
+	prev := next()
+	var delays []time.Duration
+	for range 3 {
+		prev.stream.disconnect()
+		o := next()
+		delays = append(delays, o.at.Sub(prev.at))
+		prev = o
+	}
+	assert.GreaterOrEqual(t, delays[2], 80*time.Millisecond, "delays %v", delays)
+
+	// stream which received a message resets backoff
+	prev.stream.recvCh <- "ok"
+	prev.stream.disconnect()
+	o := next()
+	assert.Less(t, o.at.Sub(prev.at), 80*time.Millisecond)
+}
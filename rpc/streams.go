@@ -1,15 +1,20 @@
 package rpc
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type StreamSubscription struct {
 	closeCh      chan void
 	eventsBitmap uint32
+	lastSeq      atomic.Uint64
 }
 
 func NewStreamSubscription(closeCh chan void, eventsBitmap uint32) *StreamSubscription {
@@ -19,8 +24,24 @@ func NewStreamSubscription(closeCh chan void, eventsBitmap uint32) *StreamSubscr
 	}
 }
 
+// LastSeq returns sequence number of the last event queued to the subscriber,
+// zero if nothing was queued yet or stream has no sequence numbers.
+func (s *StreamSubscription) LastSeq() uint64 {
+	return s.lastSeq.Load()
+}
+
 //
 
+// StreamAuthorizer decides whether caller could subscribe to channel, it usually checks
+// capabilities.CapabilitiesFromContext(ctx). Global subscription is authorized with zero channel.
+type StreamAuthorizer[Channel comparable] func(ctx context.Context, channel Channel) error
+
+// StreamSnapshot returns current state of channels as events replayed to new subscriber,
+// all channels are requested if none given. It is called under stream lock, so broadcasting
+// to every subscriber is blocked while it runs: it must be short (e.g. copy in-memory state,
+// no network or database calls) and must not call Stream methods except Seq.
+type StreamSnapshot[Channel comparable, Event any] func(ctx context.Context, channels []Channel) ([]Event, error)
+
 type Stream[Channel comparable, Event any] struct {
 	mu                     *sync.Mutex
 	subscriptionsByChannel map[Channel]map[chan<- Event]*StreamSubscription
@@ -28,9 +49,44 @@ type Stream[Channel comparable, Event any] struct {
 	source                 <-chan Event
 	identify               func(Event) Channel
 	event                  func(Event) uint32
+	stamp                  func(Event, uint64) Event
+	authorize              StreamAuthorizer[Channel]
+	snapshot               StreamSnapshot[Channel, Event]
+	seq                    atomic.Uint64
 	name                   string
 }
 
+// WithSequence enables sequence numbers, every broadcasted event is stamped with
+// monotonic number starting from 1, so client could detect missed events by a gap
+// and resync. Subscriber filtering by channel or events sees gaps as well,
+// it should compare against Seq instead. Stamp must not change the event channel.
+// It should be called before Pump.
+func (s *Stream[Channel, Event]) WithSequence(stamp func(Event, uint64) Event) *Stream[Channel, Event] {
+	s.stamp = stamp
+	return s
+}
+
+// WithAuthorizer enables subscription authorization, errors which are not
+// gRPC statuses are returned as PermissionDenied. Authorizer is enforced only
+// by SubscribeContext and SubscribeSnapshot, Subscribe does not check it.
+// It should be called before subscribing.
+func (s *Stream[Channel, Event]) WithAuthorizer(authorize StreamAuthorizer[Channel]) *Stream[Channel, Event] {
+	s.authorize = authorize
+	return s
+}
+
+// WithSnapshot enables SubscribeSnapshot and ServeSnapshot, see StreamSnapshot
+// for restrictions on snapshot.
+func (s *Stream[Channel, Event]) WithSnapshot(snapshot StreamSnapshot[Channel, Event]) *Stream[Channel, Event] {
+	s.snapshot = snapshot
+	return s
+}
+
+// Seq returns sequence number of the last broadcasted event.
+func (s *Stream[Channel, Event]) Seq() uint64 {
+	return s.seq.Load()
+}
+
 func (s *Stream[Channel, Event]) ClientPump(clientCh chan Event, sub *StreamSubscription, send func(Event) error) error {
 	var err error
 	for {
@@ -51,26 +107,34 @@ func (s *Stream[Channel, Event]) ClientPump(clientCh chan Event, sub *StreamSubs
 
 func (s *Stream[Channel, Event]) broadcast(m Event) {
 	key := s.identify(m)
-	log.Debug().
-		Str("stream_name", s.name).
-		Str("bucket", fmt.Sprintf("%v", key)).
-		Str("payload", fmt.Sprintf("%v", m)).
-		Msg("broadcasting message")
 
+	var seq uint64
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
+	if s.stamp != nil {
+		// note: stamped under lock, so snapshot sees sequence of the last delivered event
+		seq = s.seq.Add(1)
+		m = s.stamp(m, seq)
+	}
 	if bucket, ok := s.subscriptionsByChannel[key]; ok {
 		for clientCh, sub := range bucket {
-			s.send(sub, clientCh, m, key)
+			s.send(sub, clientCh, m, key, seq)
 		}
 	}
 	for clientCh, sub := range s.subscriptionsGlobal {
-		s.send(sub, clientCh, m, key)
+		s.send(sub, clientCh, m, key, seq)
+	}
+	s.mu.Unlock()
+
+	if e := log.Debug(); e.Enabled() {
+		e.
+			Str("stream_name", s.name).
+			Str("bucket", fmt.Sprintf("%v", key)).
+			Str("payload", fmt.Sprintf("%v", m)).
+			Msg("broadcasting message")
 	}
 }
 
-func (s *Stream[Channel, Event]) send(sub *StreamSubscription, clientCh chan<- Event, m Event, channel Channel) {
+func (s *Stream[Channel, Event]) send(sub *StreamSubscription, clientCh chan<- Event, m Event, channel Channel, seq uint64) {
 	eventMatch := sub.eventsBitmap == 0 || (sub.eventsBitmap&s.event(m) != 0)
 	if !eventMatch {
 		return
@@ -78,6 +142,9 @@ func (s *Stream[Channel, Event]) send(sub *StreamSubscription, clientCh chan<- E
 
 	select {
 	case clientCh <- m:
+		if seq != 0 {
+			sub.lastSeq.Store(seq)
+		}
 	default:
 		select {
 		case sub.closeCh <- void{}:
@@ -85,6 +152,7 @@ func (s *Stream[Channel, Event]) send(sub *StreamSubscription, clientCh chan<- E
 				Str("stream_name", s.name).
 				Any("channel", channel).
 				Str("client", fmt.Sprintf("%p", clientCh)).
+				Uint64("last_seq", sub.LastSeq()).
 				Msgf("failed to write %s to client, queue is full, disconnecting client", s.name)
 		default: // already closing
 		}
@@ -97,10 +165,30 @@ func (s *Stream[Channel, Event]) Pump() {
 	}
 }
 
+// Subscribe subscribes client to channels or to all channels if none given,
+// authorizer is not checked, use SubscribeContext for that.
 func (s *Stream[Channel, Event]) Subscribe(clientCh chan<- Event, sub *StreamSubscription, channels ...Channel) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.subscribe(clientCh, sub, channels)
+}
+
+// SubscribeContext is like Subscribe, but checks caller from ctx with authorizer,
+// nothing is subscribed if caller is not authorized for any of channels.
+func (s *Stream[Channel, Event]) SubscribeContext(ctx context.Context, clientCh chan<- Event, sub *StreamSubscription, channels ...Channel) error {
+	if err := s.authorizeChannels(ctx, channels); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subscribe(clientCh, sub, channels)
+	return nil
+}
+
+func (s *Stream[Channel, Event]) subscribe(clientCh chan<- Event, sub *StreamSubscription, channels []Channel) {
 	if len(channels) == 0 {
 		s.subscriptionsGlobal[clientCh] = sub
 		return
@@ -115,6 +203,89 @@ func (s *Stream[Channel, Event]) Subscribe(clientCh chan<- Event, sub *StreamSub
 	}
 }
 
+// SubscribeSnapshot subscribes like SubscribeContext and returns snapshot of current state,
+// which should be sent to client before events from clientCh. Snapshot is taken atomically
+// with subscription, so no event is lost or reordered between snapshot and live events.
+// Subscription LastSeq is set to Seq the snapshot is consistent with.
+// Broadcasting is blocked while snapshot is taken, see StreamSnapshot.
+func (s *Stream[Channel, Event]) SubscribeSnapshot(ctx context.Context, clientCh chan<- Event, sub *StreamSubscription, channels ...Channel) ([]Event, error) {
+	events, _, err := s.subscribeSnapshot(ctx, clientCh, sub, channels)
+	return events, err
+}
+
+func (s *Stream[Channel, Event]) subscribeSnapshot(ctx context.Context, clientCh chan<- Event, sub *StreamSubscription, channels []Channel) ([]Event, uint64, error) {
+	if s.snapshot == nil {
+		return nil, 0, status.Errorf(codes.Unimplemented, "%s stream does not support snapshots", s.name)
+	}
+	if err := s.authorizeChannels(ctx, channels); err != nil {
+		return nil, 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events, err := s.snapshot(ctx, channels)
+	if err != nil {
+		return nil, 0, err
+	}
+	seq := s.seq.Load()
+	sub.lastSeq.Store(seq)
+	s.subscribe(clientCh, sub, channels)
+	return events, seq, nil
+}
+
+// ServeSnapshot is a server side of snapshot protocol, it is called when client requests
+// snapshot on subscribe. It sends snapshot of channels, then marker made by done with Seq
+// the snapshot is consistent with, then live events until subscription is closed or send fails.
+// Client is unsubscribed on return. StreamSnapshotReceiver is a client side of the protocol.
+func (s *Stream[Channel, Event]) ServeSnapshot(
+	ctx context.Context,
+	clientCh chan Event,
+	sub *StreamSubscription,
+	send func(Event) error,
+	done func(seq uint64) Event,
+	channels ...Channel,
+) error {
+	events, seq, err := s.subscribeSnapshot(ctx, clientCh, sub, channels)
+	if err != nil {
+		return err
+	}
+	defer s.Unsubscribe(clientCh, channels...)
+
+	for _, ev := range events {
+		err = send(ev)
+		if err != nil {
+			return err
+		}
+	}
+	err = send(done(seq))
+	if err != nil {
+		return err
+	}
+	return s.ClientPump(clientCh, sub, send)
+}
+
+func (s *Stream[Channel, Event]) authorizeChannels(ctx context.Context, channels []Channel) error {
+	if s.authorize == nil {
+		return nil
+	}
+	if len(channels) == 0 {
+		var global Channel
+		channels = []Channel{global}
+	}
+	for _, channel := range channels {
+		err := s.authorize(ctx, channel)
+		if err == nil {
+			continue
+		}
+		if _, ok := status.FromError(err); !ok {
+			err = status.Errorf(codes.PermissionDenied, "subscription to %s channel %v is not allowed: %v", s.name, channel, err)
+		}
+		return err
+	}
+	return nil
+}
+
 func (s *Stream[Channel, Event]) Unsubscribe(clientCh chan Event, channels ...Channel) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -134,6 +305,74 @@ func (s *Stream[Channel, Event]) Unsubscribe(clientCh chan Event, channels ...Ch
 	}
 }
 
+//
+
+type (
+	StreamSnapshotReceiverConfig[Event any] struct {
+		// Marker reports whether event ends snapshot and returns Seq snapshot is consistent with.
+		Marker func(Event) (uint64, bool)
+		// Seq returns sequence number of live event, live events covered by snapshot are skipped.
+		// Events are never skipped if Seq is nil or returns zero.
+		Seq func(Event) uint64
+		// Reset drops client state, it is called right before snapshot is applied.
+		Reset func()
+		// Apply applies snapshot or live event to client state.
+		Apply func(Event)
+	}
+
+	// StreamSnapshotReceiver is a client side of snapshot protocol, see Stream.ServeSnapshot.
+	// Snapshot events are collected until marker and applied at once, so client state
+	// is never a mix of old and new snapshot. It is not safe for concurrent use.
+	StreamSnapshotReceiver[Event any] struct {
+		cfg     StreamSnapshotReceiverConfig[Event]
+		pending []Event
+		seq     uint64
+		synced  bool
+	}
+)
+
+func NewStreamSnapshotReceiver[Event any](cfg StreamSnapshotReceiverConfig[Event]) *StreamSnapshotReceiver[Event] {
+	return &StreamSnapshotReceiver[Event]{cfg: cfg}
+}
+
+// Restart discards partially received snapshot, it should be called on every (re)connect
+// before snapshot is requested, e.g. from StreamManagerConfig.OnConnect.
+func (r *StreamSnapshotReceiver[Event]) Restart() {
+	r.pending = nil
+	r.seq = 0
+	r.synced = false
+}
+
+// Synced reports whether snapshot was applied and live events are applied as they come.
+func (r *StreamSnapshotReceiver[Event]) Synced() bool {
+	return r.synced
+}
+
+// Receive handles event received from stream.
+func (r *StreamSnapshotReceiver[Event]) Receive(ev Event) {
+	if seq, ok := r.cfg.Marker(ev); ok {
+		r.cfg.Reset()
+		for _, pending := range r.pending {
+			r.cfg.Apply(pending)
+		}
+		r.pending = nil
+		r.seq = seq
+		r.synced = true
+		return
+	}
+	if !r.synced {
+		r.pending = append(r.pending, ev)
+		return
+	}
+	if r.cfg.Seq != nil {
+		seq := r.cfg.Seq(ev)
+		if seq != 0 && seq <= r.seq {
+			return
+		}
+	}
+	r.cfg.Apply(ev)
+}
+
 // NewStream creates a gRPC stream wrapper for server which introduces pubsub semantics to the stream.
 func NewStream[Channel comparable, Event any](
 	name string,
@@ -0,0 +1,325 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"git.tatikoma.dev/corpix/protoc-gen-grpc-capabilities/capabilities"
+)
+
+type testStreamEvent struct {
+	channel string
+	seq     uint64
+	marker  bool
+}
+
+func TestStreamSequence(t *testing.T) {
+	source := make(chan testStreamEvent)
+	defer close(source)
+	s := NewStream(
+		"test",
+		source,
+		func(ev testStreamEvent) string { return ev.channel },
+		func(testStreamEvent) uint32 { return 1 },
+	).WithSequence(func(ev testStreamEvent, seq uint64) testStreamEvent {
+		ev.seq = seq
+		return ev
+	})
+	go s.Pump()
+
+	var (
+		fastCh  = make(chan testStreamEvent, 8)
+		fastSub = NewStreamSubscription(make(chan void, 1), 0)
+		slowCh  = make(chan testStreamEvent, 1)
+		slowSub = NewStreamSubscription(make(chan void, 1), 0)
+	)
+	s.Subscribe(fastCh, fastSub)
+	s.Subscribe(slowCh, slowSub, "a")
+
+	recv := func(ch chan testStreamEvent) testStreamEvent {
+		t.Helper()
+		select {
+		case ev := <-ch:
+			return ev
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for event")
+			return testStreamEvent{}
+		}
+	}
+
+	source <- testStreamEvent{channel: "a"}
+	assert.Equal(t, uint64(1), recv(slowCh).seq)
+	for _, channel := range []string{"b", "a", "a", "a"} {
+		source <- testStreamEvent{channel: channel}
+	}
+	for seq := uint64(1); seq <= 5; seq++ {
+		assert.Equal(t, seq, recv(fastCh).seq, "sequence numbers increase monotonically")
+	}
+	assert.Equal(t, uint64(5), fastSub.LastSeq())
+	assert.Equal(t, uint64(5), s.Seq())
+
+	t.Run("gap after drop", func(t *testing.T) {
+		select {
+		case <-slowSub.closeCh:
+		default:
+			t.Fatal("slow client was not disconnected")
+		}
+		assert.Equal(t, uint64(3), slowSub.LastSeq())
+		assert.Equal(t, uint64(3), recv(slowCh).seq)
+		require.Less(t, slowSub.LastSeq(), s.Seq(), "missed events are detectable")
+
+		s.Unsubscribe(slowCh, "a")
+		resubCh := make(chan testStreamEvent, 1)
+		s.Subscribe(resubCh, NewStreamSubscription(make(chan void, 1), 0), "a")
+		source <- testStreamEvent{channel: "a"}
+		assert.Equal(t, uint64(6), recv(resubCh).seq, "gap of %d events", 6-slowSub.LastSeq()-1)
+	})
+}
+
+func TestStreamAuthorizer(t *testing.T) {
+	tenantCapability := func(channel string) capabilities.CapabilityID {
+		return capabilities.NewCapability(capabilities.CapabilityLiteral("tenant." + channel)).ID
+	}
+	s := NewStream(
+		"test",
+		make(chan testStreamEvent),
+		func(ev testStreamEvent) string { return ev.channel },
+		func(testStreamEvent) uint32 { return 1 },
+	).WithAuthorizer(func(ctx context.Context, channel string) error {
+		caps, _ := ctx.Value(capabilities.CapabilitiesContextKey).(capabilities.Capabilities)
+		if _, ok := caps[tenantCapability(channel)]; !ok {
+			return errors.New("missing tenant capability")
+		}
+		return nil
+	})
+
+	caps := capabilities.Capabilities{}
+	capability := capabilities.NewCapability("tenant.a")
+	caps[capability.ID] = capability
+	ctx := context.WithValue(context.Background(), capabilities.CapabilitiesContextKey, caps)
+	subscribe := func(ctx context.Context, channels ...string) (chan testStreamEvent, error) {
+		clientCh := make(chan testStreamEvent, 1)
+		return clientCh, s.SubscribeContext(ctx, clientCh, NewStreamSubscription(make(chan void, 1), 0), channels...)
+	}
+
+	clientCh, err := subscribe(ctx, "a")
+	require.NoError(t, err)
+	s.broadcast(testStreamEvent{channel: "a"})
+	assert.Len(t, clientCh, 1)
+
+	for name, channels := range map[string][]string{
+		"other tenant":   {"b"},
+		"mixed channels": {"a", "b"},
+		"global":         nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			clientCh, err := subscribe(ctx, channels...)
+			assert.Equal(t, codes.PermissionDenied, status.Code(err))
+			s.broadcast(testStreamEvent{channel: "a"})
+			s.broadcast(testStreamEvent{channel: "b"})
+			assert.Empty(t, clientCh, "rejected subscription receives nothing")
+		})
+	}
+
+	_, err = subscribe(context.Background(), "a")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "caller without capabilities")
+
+	t.Run("subscribe does not authorize", func(t *testing.T) {
+		clientCh := make(chan testStreamEvent, 1)
+		s.Subscribe(clientCh, NewStreamSubscription(make(chan void, 1), 0), "b")
+		s.broadcast(testStreamEvent{channel: "b"})
+		assert.Len(t, clientCh, 1)
+	})
+}
+
+func TestStreamSubscribeSnapshot(t *testing.T) {
+	const total = 200
+	source := make(chan testStreamEvent)
+	s := NewStream(
+		"test",
+		source,
+		func(ev testStreamEvent) string { return ev.channel },
+		func(testStreamEvent) uint32 { return 1 },
+	).WithSequence(func(ev testStreamEvent, seq uint64) testStreamEvent {
+		ev.seq = seq
+		return ev
+	})
+	// note: state is replayed from sequence numbers, every broadcasted event is an entity
+	s.WithSnapshot(func(ctx context.Context, channels []string) ([]testStreamEvent, error) {
+		events := make([]testStreamEvent, 0, s.Seq())
+		for seq := uint64(1); seq <= s.Seq(); seq++ {
+			events = append(events, testStreamEvent{channel: "a", seq: seq})
+		}
+		return events, nil
+	})
+	go s.Pump()
+
+	var (
+		pumpCh  = make(chan testStreamEvent, total)
+		pumpSub = NewStreamSubscription(make(chan void, 1), 0)
+	)
+	s.Subscribe(pumpCh, pumpSub)
+	go func() {
+		defer close(source)
+		for range total {
+			source <- testStreamEvent{channel: "a"}
+		}
+	}()
+	for len(pumpCh) < total/2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	var (
+		clientCh = make(chan testStreamEvent, total)
+		sub      = NewStreamSubscription(make(chan void, 1), 0)
+	)
+	snapshot, err := s.SubscribeSnapshot(context.Background(), clientCh, sub, "a")
+	require.NoError(t, err)
+	require.NotEmpty(t, snapshot)
+	assert.Equal(t, uint64(len(snapshot)), sub.LastSeq())
+
+	received := snapshot
+	for received[len(received)-1].seq < total {
+		select {
+		case ev := <-clientCh:
+			received = append(received, ev)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for live events, got %d", len(received))
+		}
+	}
+	require.Len(t, received, total)
+	for n, ev := range received {
+		assert.Equal(t, uint64(n+1), ev.seq, "snapshot is followed by live events without gaps and duplicates")
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		s := NewStream(
+			"test",
+			make(chan testStreamEvent),
+			func(ev testStreamEvent) string { return ev.channel },
+			func(testStreamEvent) uint32 { return 1 },
+		)
+		_, err := s.SubscribeSnapshot(context.Background(), make(chan testStreamEvent), NewStreamSubscription(make(chan void, 1), 0))
+		assert.Equal(t, codes.Unimplemented, status.Code(err))
+	})
+}
+
+func TestStreamSnapshotProtocol(t *testing.T) {
+	const total = 200
+	source := make(chan testStreamEvent)
+	s := NewStream(
+		"test",
+		source,
+		func(ev testStreamEvent) string { return ev.channel },
+		func(testStreamEvent) uint32 { return 1 },
+	).WithSequence(func(ev testStreamEvent, seq uint64) testStreamEvent {
+		ev.seq = seq
+		return ev
+	}).WithSnapshot(func(ctx context.Context, channels []string) ([]testStreamEvent, error) {
+		return []testStreamEvent{{channel: "a", seq: 0}}, nil
+	})
+	go s.Pump()
+
+	var (
+		pumpCh  = make(chan testStreamEvent, total)
+		pumpSub = NewStreamSubscription(make(chan void, 1), 0)
+	)
+	s.Subscribe(pumpCh, pumpSub)
+	go func() {
+		defer close(source)
+		for range total {
+			source <- testStreamEvent{channel: "a"}
+		}
+	}()
+	for len(pumpCh) < total/2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	var (
+		wire     = make(chan testStreamEvent, total+2)
+		clientCh = make(chan testStreamEvent, total)
+		sub      = NewStreamSubscription(make(chan void, 1), 0)
+		serveErr = make(chan error, 1)
+	)
+	go func() {
+		serveErr <- s.ServeSnapshot(context.Background(), clientCh, sub,
+			func(ev testStreamEvent) error {
+				wire <- ev
+				return nil
+			},
+			func(seq uint64) testStreamEvent { return testStreamEvent{seq: seq, marker: true} },
+			"a",
+		)
+	}()
+
+	var (
+		state  []testStreamEvent
+		resets int
+	)
+	r := NewStreamSnapshotReceiver(StreamSnapshotReceiverConfig[testStreamEvent]{
+		Marker: func(ev testStreamEvent) (uint64, bool) { return ev.seq, ev.marker },
+		Seq:    func(ev testStreamEvent) uint64 { return ev.seq },
+		Reset: func() {
+			resets++
+			state = nil
+		},
+		Apply: func(ev testStreamEvent) { state = append(state, ev) },
+	})
+	r.Restart()
+	var snapshotSeq uint64
+	for !r.Synced() || state[len(state)-1].seq < total {
+		select {
+		case ev := <-wire:
+			if ev.marker {
+				snapshotSeq = ev.seq
+			}
+			r.Receive(ev)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for events, got %d", len(state))
+		}
+	}
+
+	assert.Equal(t, 1, resets)
+	require.NotZero(t, snapshotSeq)
+	require.Len(t, state, 1+total-int(snapshotSeq))
+	assert.Equal(t, uint64(0), state[0].seq, "snapshot goes first")
+	for n, ev := range state[1:] {
+		assert.Equal(t, snapshotSeq+uint64(n+1), ev.seq, "snapshot is followed by live events without gaps and duplicates")
+	}
+
+	sub.closeCh <- void{}
+	select {
+	case err := <-serveErr:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("serve did not stop")
+	}
+
+	t.Run("live events covered by snapshot are skipped", func(t *testing.T) {
+		var applied []uint64
+		r := NewStreamSnapshotReceiver(StreamSnapshotReceiverConfig[testStreamEvent]{
+			Marker: func(ev testStreamEvent) (uint64, bool) { return ev.seq, ev.marker },
+			Seq:    func(ev testStreamEvent) uint64 { return ev.seq },
+			Reset:  func() { applied = nil },
+			Apply:  func(ev testStreamEvent) { applied = append(applied, ev.seq) },
+		})
+		r.Receive(testStreamEvent{seq: 1})
+		assert.Empty(t, applied, "snapshot is applied on marker")
+		r.Receive(testStreamEvent{seq: 5, marker: true})
+		r.Receive(testStreamEvent{seq: 4})
+		r.Receive(testStreamEvent{seq: 6})
+		assert.Equal(t, []uint64{1, 6}, applied)
+
+		r.Restart()
+		r.Receive(testStreamEvent{seq: 7})
+		assert.False(t, r.Synced())
+		assert.Equal(t, []uint64{1, 6}, applied, "state is kept until next snapshot is complete")
+	})
+}
@@ -2,8 +2,17 @@ package rpc
 
 import (
 	"context"
+	"slices"
+	"strconv"
+	"strings"
 
+	"golang.org/x/text/unicode/norm"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	atlasrpc "git.tatikoma.dev/corpix/atlas/rpc/pb"
 )
 
 type Transformer interface {
@@ -16,6 +25,15 @@ func (f TransformerFunc) Transform(req any) {
 	f(req)
 }
 
+// ChainTransformer returns Transformer applying transformers in order.
+func ChainTransformer(transformers ...Transformer) Transformer {
+	return TransformerFunc(func(req any) {
+		for _, t := range transformers {
+			t.Transform(req)
+		}
+	})
+}
+
 type DefaultsTransformer struct{}
 
 func (DefaultsTransformer) Transform(req any) {
@@ -24,6 +42,199 @@ func (DefaultsTransformer) Transform(req any) {
 	}
 }
 
+// OptionsDefaultsTransformer sets unset scalar fields of proto.Message (including nested messages)
+// to values declared with atlas_rpc.default field option, it is an alternative to DefaultsTransformer
+// which does not require messages to implement Default() method.
+// Only fields with presence (proto3 optional, proto2) are defaulted, because zero value
+// of proto3 implicit presence field could not be told apart from unset one.
+// Fields of oneof and defaults which could not be parsed for the field kind are skipped.
+type OptionsDefaultsTransformer struct{}
+
+func (OptionsDefaultsTransformer) Transform(req any) {
+	if msg, ok := req.(proto.Message); ok {
+		applyOptionsDefaults(msg.ProtoReflect())
+	}
+}
+
+func applyOptionsDefaults(m protoreflect.Message) {
+	fields := m.Descriptor().Fields()
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() == nil || !m.Has(fd) {
+				continue
+			}
+			m.Get(fd).Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+				applyOptionsDefaults(v.Message())
+				return true
+			})
+		case fd.IsList():
+			if fd.Message() == nil || !m.Has(fd) {
+				continue
+			}
+			list := m.Get(fd).List()
+			for j := range list.Len() {
+				applyOptionsDefaults(list.Get(j).Message())
+			}
+		case fd.Message() != nil:
+			if m.Has(fd) {
+				applyOptionsDefaults(m.Mutable(fd).Message())
+			}
+		default:
+			if !fd.HasPresence() || m.Has(fd) {
+				continue
+			}
+			if oneof := fd.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+				continue
+			}
+			if value, ok := optionsDefault(fd); ok {
+				m.Set(fd, value)
+			}
+		}
+	}
+}
+
+func optionsDefault(fd protoreflect.FieldDescriptor) (protoreflect.Value, bool) {
+	opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+	if !ok || !proto.HasExtension(opts, atlasrpc.E_Default) {
+		return protoreflect.Value{}, false
+	}
+	raw := proto.GetExtension(opts, atlasrpc.E_Default).(string)
+
+	var (
+		value protoreflect.Value
+		err   error
+	)
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		var v bool
+		v, err = strconv.ParseBool(raw)
+		value = protoreflect.ValueOfBool(v)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		var v int64
+		v, err = strconv.ParseInt(raw, 10, 32)
+		value = protoreflect.ValueOfInt32(int32(v))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		var v int64
+		v, err = strconv.ParseInt(raw, 10, 64)
+		value = protoreflect.ValueOfInt64(v)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		var v uint64
+		v, err = strconv.ParseUint(raw, 10, 32)
+		value = protoreflect.ValueOfUint32(uint32(v))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		var v uint64
+		v, err = strconv.ParseUint(raw, 10, 64)
+		value = protoreflect.ValueOfUint64(v)
+	case protoreflect.FloatKind:
+		var v float64
+		v, err = strconv.ParseFloat(raw, 32)
+		value = protoreflect.ValueOfFloat32(float32(v))
+	case protoreflect.DoubleKind:
+		var v float64
+		v, err = strconv.ParseFloat(raw, 64)
+		value = protoreflect.ValueOfFloat64(v)
+	case protoreflect.StringKind:
+		value = protoreflect.ValueOfString(raw)
+	case protoreflect.BytesKind:
+		value = protoreflect.ValueOfBytes([]byte(raw))
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(raw)); ev != nil {
+			value = protoreflect.ValueOfEnum(ev.Number())
+			break
+		}
+		var v int64
+		v, err = strconv.ParseInt(raw, 10, 32)
+		value = protoreflect.ValueOfEnum(protoreflect.EnumNumber(v))
+	default:
+		return protoreflect.Value{}, false
+	}
+	if err != nil {
+		return protoreflect.Value{}, false
+	}
+	return value, true
+}
+
+// StringNormalizeTransformer normalizes string fields of proto.Message including
+// repeated, map values and nested messages.
+type StringNormalizeTransformer struct {
+	// Trim removes leading and trailing white space.
+	Trim bool
+	// NFC converts strings to unicode normalization form C.
+	NFC bool
+	// Lowercase is a list of full field names, e.g. "atlas.User.email", which values are lowercased.
+	Lowercase []protoreflect.FullName
+}
+
+func (t StringNormalizeTransformer) Transform(req any) {
+	if msg, ok := req.(proto.Message); ok {
+		t.normalizeMessage(msg.ProtoReflect())
+	}
+}
+
+func (t StringNormalizeTransformer) normalize(fd protoreflect.FieldDescriptor, s string) string {
+	if t.Trim {
+		s = strings.TrimSpace(s)
+	}
+	if t.NFC {
+		s = norm.NFC.String(s)
+	}
+	if slices.Contains(t.Lowercase, fd.FullName()) {
+		s = strings.ToLower(s)
+	}
+	return s
+}
+
+func (t StringNormalizeTransformer) normalizeMessage(m protoreflect.Message) {
+	// fields are collected first, message should not be mutated during Range
+	var fields []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		fields = append(fields, fd)
+		return true
+	})
+
+	for _, fd := range fields {
+		switch {
+		case fd.IsMap():
+			t.normalizeMap(fd, m.Mutable(fd).Map())
+		case fd.IsList():
+			list := m.Mutable(fd).List()
+			for i := range list.Len() {
+				switch {
+				case fd.Message() != nil:
+					t.normalizeMessage(list.Get(i).Message())
+				case fd.Kind() == protoreflect.StringKind:
+					list.Set(i, protoreflect.ValueOfString(t.normalize(fd, list.Get(i).String())))
+				}
+			}
+		case fd.Message() != nil:
+			t.normalizeMessage(m.Mutable(fd).Message())
+		case fd.Kind() == protoreflect.StringKind:
+			m.Set(fd, protoreflect.ValueOfString(t.normalize(fd, m.Get(fd).String())))
+		}
+	}
+}
+
+func (t StringNormalizeTransformer) normalizeMap(fd protoreflect.FieldDescriptor, mv protoreflect.Map) {
+	value := fd.MapValue()
+	if value.Message() == nil && value.Kind() != protoreflect.StringKind {
+		return
+	}
+	var keys []protoreflect.MapKey
+	mv.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys = append(keys, k)
+		return true
+	})
+	for _, k := range keys {
+		if value.Message() != nil {
+			t.normalizeMessage(mv.Get(k).Message())
+			continue
+		}
+		mv.Set(k, protoreflect.ValueOfString(t.normalize(fd, mv.Get(k).String())))
+	}
+}
+
 func TransformUnaryServerInterceptor() grpc.UnaryServerInterceptor {
 	return UnaryServerInterceptorWithTransformer(DefaultsTransformer{})
 }
@@ -0,0 +1,223 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	atlasrpc "git.tatikoma.dev/corpix/atlas/rpc/pb"
+)
+
+func newTestField(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, def string) *descriptorpb.FieldDescriptorProto {
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(name),
+		Number:   proto.Int32(number),
+		Type:     typ.Enum(),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+	}
+	if def != "" {
+		field.Options = &descriptorpb.FieldOptions{}
+		proto.SetExtension(field.Options, atlasrpc.E_Default, def)
+	}
+	return field
+}
+
+// withTestPresence makes fields proto3 optional, each gets its own synthetic oneof.
+func withTestPresence(msg *descriptorpb.DescriptorProto, names ...string) *descriptorpb.DescriptorProto {
+	for _, field := range msg.Field {
+		for _, name := range names {
+			if field.GetName() != name {
+				continue
+			}
+			field.Proto3Optional = proto.Bool(true)
+			field.OneofIndex = proto.Int32(int32(len(msg.OneofDecl)))
+			msg.OneofDecl = append(msg.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: proto.String("_" + name)})
+		}
+	}
+	return msg
+}
+
+// newTestSettingsDescriptor builds messages
+//
+//	enum Color { COLOR_UNSPECIFIED = 0; RED = 1; }
+//	message Nested { optional string name = 1 [(atlas_rpc.default) = "nested"]; }
+//	message Settings {
+//	  optional int32 limit = 1 [(atlas_rpc.default) = "10"];
+//	  optional string mode = 2 [(atlas_rpc.default) = "fast"];
+//	  optional bool enabled = 3 [(atlas_rpc.default) = "true"];
+//	  optional Color color = 4 [(atlas_rpc.default) = "RED"];
+//	  optional double ratio = 5 [(atlas_rpc.default) = "not a number"];
+//	  string comment = 6;
+//	  Nested nested = 7;
+//	  repeated Nested list = 8;
+//	  int32 implicit = 9 [(atlas_rpc.default) = "5"];
+//	}
+func newTestSettingsDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	nested := newTestField("nested", 7, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, "")
+	nested.TypeName = proto.String(".atlas_rpc_test.Nested")
+	list := newTestField("list", 8, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, "")
+	list.TypeName = proto.String(".atlas_rpc_test.Nested")
+	list.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	color := newTestField("color", 4, descriptorpb.FieldDescriptorProto_TYPE_ENUM, "RED")
+	color.TypeName = proto.String(".atlas_rpc_test.Color")
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("atlas_rpc_test_defaults.proto"),
+		Package:    proto.String("atlas_rpc_test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"defaults.proto"},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Color"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("COLOR_UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String("RED"), Number: proto.Int32(1)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{
+			withTestPresence(&descriptorpb.DescriptorProto{
+				Name:  proto.String("Nested"),
+				Field: []*descriptorpb.FieldDescriptorProto{newTestField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, "nested")},
+			}, "name"),
+			withTestPresence(&descriptorpb.DescriptorProto{
+				Name: proto.String("Settings"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					newTestField("limit", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32, "10"),
+					newTestField("mode", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, "fast"),
+					newTestField("enabled", 3, descriptorpb.FieldDescriptorProto_TYPE_BOOL, "true"),
+					color,
+					newTestField("ratio", 5, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, "not a number"),
+					newTestField("comment", 6, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					nested,
+					list,
+					newTestField("implicit", 9, descriptorpb.FieldDescriptorProto_TYPE_INT32, "5"),
+				},
+			}, "limit", "mode", "enabled", "color", "ratio"),
+		},
+	}
+	fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	return fd.Messages().ByName("Settings")
+}
+
+func TestOptionsDefaultsTransformer(t *testing.T) {
+	desc := newTestSettingsDescriptor(t)
+	fields := desc.Fields()
+	nestedDesc := fields.ByName("nested").Message()
+
+	msg := dynamicpb.NewMessage(desc)
+	msg.Set(fields.ByName("mode"), protoreflect.ValueOfString("slow"))
+	msg.Set(fields.ByName("nested"), protoreflect.ValueOfMessage(dynamicpb.NewMessage(nestedDesc)))
+	list := msg.Mutable(fields.ByName("list")).List()
+	list.Append(protoreflect.ValueOfMessage(dynamicpb.NewMessage(nestedDesc)))
+
+	OptionsDefaultsTransformer{}.Transform(msg)
+
+	assert.Equal(t, int64(10), msg.Get(fields.ByName("limit")).Int())
+	assert.Equal(t, "slow", msg.Get(fields.ByName("mode")).String(), "set fields are kept")
+	assert.True(t, msg.Get(fields.ByName("enabled")).Bool())
+	assert.Equal(t, protoreflect.EnumNumber(1), msg.Get(fields.ByName("color")).Enum())
+	assert.False(t, msg.Has(fields.ByName("ratio")), "invalid default is skipped")
+	assert.False(t, msg.Has(fields.ByName("comment")))
+	assert.False(t, msg.Has(fields.ByName("implicit")), "implicit presence field is not defaulted")
+
+	nestedName := nestedDesc.Fields().ByName("name")
+	assert.Equal(t, "nested", msg.Get(fields.ByName("nested")).Message().Get(nestedName).String())
+	assert.Equal(t, "nested", msg.Get(fields.ByName("list")).List().Get(0).Message().Get(nestedName).String())
+
+	assert.NotPanics(t, func() { OptionsDefaultsTransformer{}.Transform(struct{}{}) })
+
+	t.Run("explicit zero values are kept", func(t *testing.T) {
+		msg := dynamicpb.NewMessage(desc)
+		msg.Set(fields.ByName("enabled"), protoreflect.ValueOfBool(false))
+		msg.Set(fields.ByName("limit"), protoreflect.ValueOfInt32(0))
+
+		OptionsDefaultsTransformer{}.Transform(msg)
+
+		assert.False(t, msg.Get(fields.ByName("enabled")).Bool())
+		assert.Equal(t, int64(0), msg.Get(fields.ByName("limit")).Int())
+	})
+}
+
+func TestChainTransformer(t *testing.T) {
+	var calls []string
+	transformer := func(name string) Transformer {
+		return TransformerFunc(func(req any) {
+			calls = append(calls, name+":"+req.(string))
+		})
+	}
+
+	ChainTransformer(transformer("a"), transformer("b"), transformer("c")).Transform("req")
+	assert.Equal(t, []string{"a:req", "b:req", "c:req"}, calls)
+
+	t.Run("server options accumulate", func(t *testing.T) {
+		calls = nil
+		var opts serverOptions
+		for _, option := range []ServerOption{
+			WithTransformer(transformer("first")),
+			WithTransformer(transformer("second")),
+		} {
+			option(&opts)
+		}
+		opts.transformer().Transform("req")
+		assert.Equal(t, []string{"first:req", "second:req"}, calls)
+
+		assert.Equal(t, DefaultsTransformer{}, serverOptions{}.transformer())
+	})
+
+	assert.NotPanics(t, func() { ChainTransformer().Transform("req") })
+}
+
+func TestStringNormalizeTransformer(t *testing.T) {
+	transformer := StringNormalizeTransformer{
+		Trim:      true,
+		NFC:       true,
+		Lowercase: []protoreflect.FullName{"atlas_rpc_test.Order.id"},
+	}
+
+	order := newTestOrder(t, "  ORDER-1\t", " Cafe\u0301 ", "NAME")
+	transformer.Transform(order)
+
+	fields := order.Descriptor().Fields()
+	assert.Equal(t, "order-1", order.Get(fields.ByName("id")).String())
+	items := order.Get(fields.ByName("items")).List()
+	name := fields.ByName("items").Message().Fields().ByName("name")
+	assert.Equal(t, "Caf\u00e9", items.Get(0).Message().Get(name).String())
+	assert.Equal(t, "NAME", items.Get(1).Message().Get(name).String(), "only listed fields are lowercased")
+
+	t.Run("runs before validation", func(t *testing.T) {
+		opts := serverOptions{validator: validator{}}
+		WithTransformer(transformer)(&opts)
+		unary, _ := opts.requestInterceptors()
+
+		call := func(req any) error {
+			handler := func(ctx context.Context, req any) (any, error) { return req, nil }
+			for i := len(unary) - 1; i >= 0; i-- {
+				interceptor, next := unary[i], handler
+				handler = func(ctx context.Context, req any) (any, error) {
+					return interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/atlas_rpc_test.Test/Order"}, next)
+				}
+			}
+			_, err := handler(context.Background(), req)
+			return err
+		}
+
+		require.NoError(t, call(newTestOrder(t, " 1 ", "a")))
+		err := call(newTestOrder(t, "   ", "a"))
+		require.Error(t, err)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
@@ -3,12 +3,14 @@ package rpc
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 
 	protovalidate "github.com/bufbuild/protovalidate-go"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 
 	"git.tatikoma.dev/corpix/atlas/errors"
 	atlasrpc "git.tatikoma.dev/corpix/atlas/rpc/pb"
@@ -29,9 +31,18 @@ type ValidatorMethod interface {
 	Validate() error
 }
 
-type validator struct{}
+type validator struct {
+	masked []protoreflect.FullName
+}
+
+// NewMaskingValidator returns default validator which reports violations of masked fields
+// (full field names, e.g. "atlas.Login.password") with generic message, so messages of
+// custom constraints which may include field value never leak secrets into responses.
+func NewMaskingValidator(masked ...protoreflect.FullName) Validator {
+	return validator{masked: masked}
+}
 
-func (validator) Validate(req any) error {
+func (v validator) Validate(req any) error {
 	if v, ok := req.(ValidatorMethod); ok {
 		return v.Validate()
 	}
@@ -39,13 +50,18 @@ func (validator) Validate(req any) error {
 	if !ok {
 		return nil
 	}
-	return ValidateProtoMessage(msg)
+	return validateProtoMessage(msg, v.masked)
 }
 
+const maskedViolationMessage = "invalid value"
+
 type ValidationError struct {
 	Field   string
 	Rule    string
 	Message string
+
+	// Violations holds all violations of the message, first of them is described by the fields above.
+	Violations []ValidationError
 }
 
 type ErrValidation = ValidationError
@@ -55,16 +71,31 @@ func (e *ValidationError) Error() string {
 }
 
 func (e *ValidationError) ErrorDetails() []proto.Message {
-	return []proto.Message{
-		&atlasrpc.ValidationError{
-			Field:   e.Field,
-			Rule:    e.Rule,
-			Message: e.Message,
-		},
+	if len(e.Violations) == 0 {
+		return []proto.Message{
+			&atlasrpc.ValidationError{
+				Field:   e.Field,
+				Rule:    e.Rule,
+				Message: e.Message,
+			},
+		}
+	}
+	details := make([]proto.Message, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		details = append(details, &atlasrpc.ValidationError{
+			Field:   v.Field,
+			Rule:    v.Rule,
+			Message: v.Message,
+		})
 	}
+	return details
 }
 
 func ValidateProtoMessage(msg proto.Message) error {
+	return validateProtoMessage(msg, nil)
+}
+
+func validateProtoMessage(msg proto.Message, masked []protoreflect.FullName) error {
 	err := protovalidate.Validate(msg)
 	if err == nil {
 		return nil
@@ -72,11 +103,17 @@ func ValidateProtoMessage(msg proto.Message) error {
 
 	var validationErr *protovalidate.ValidationError
 	if errors.As(err, &validationErr) {
-		field, rule, message := FormatValidationError(validationErr)
+		violations := formatValidationViolations(validationErr, masked)
+		if len(violations) == 0 {
+			return errors.RpcCode(&ValidationError{
+				Message: strings.TrimPrefix(err.Error(), "validation error: "),
+			}, codes.InvalidArgument, "validation error")
+		}
 		return errors.RpcCode(&ValidationError{
-			Field:   field,
-			Rule:    rule,
-			Message: message,
+			Field:      violations[0].Field,
+			Rule:       violations[0].Rule,
+			Message:    violations[0].Message,
+			Violations: violations,
 		}, codes.InvalidArgument, "validation error")
 	}
 
@@ -88,6 +125,27 @@ func FormatValidationError(err *protovalidate.ValidationError) (string, string,
 		return "", "", ""
 	}
 
+	violations := FormatValidationViolations(err)
+	if len(violations) > 0 {
+		return violations[0].Field, violations[0].Rule, violations[0].Message
+	}
+	return "", "", strings.TrimPrefix(err.Error(), "validation error: ")
+}
+
+// FormatValidationViolations formats each violation of err, field paths of
+// repeated and map fields include indices and keys, e.g. items[2].name.
+// Messages never include field values except for custom constraints which put them into message,
+// use NewMaskingValidator for sensitive fields.
+func FormatValidationViolations(err *protovalidate.ValidationError) []ValidationError {
+	return formatValidationViolations(err, nil)
+}
+
+func formatValidationViolations(err *protovalidate.ValidationError, masked []protoreflect.FullName) []ValidationError {
+	if err == nil {
+		return nil
+	}
+
+	violations := make([]ValidationError, 0, len(err.Violations))
 	for _, violation := range err.Violations {
 		if violation == nil || violation.Proto == nil {
 			continue
@@ -95,12 +153,19 @@ func FormatValidationError(err *protovalidate.ValidationError) (string, string,
 		field := protovalidate.FieldPathString(violation.Proto.GetField())
 		rule := protovalidate.FieldPathString(violation.Proto.GetRule())
 		message := violation.Proto.GetMessage()
+		if violation.FieldDescriptor != nil && slices.Contains(masked, violation.FieldDescriptor.FullName()) {
+			rule, message = "", maskedViolationMessage
+		}
 		if field != "" && message != "" {
-			return field, rule, fmt.Sprintf("%s: %s", field, message)
+			message = fmt.Sprintf("%s: %s", field, message)
 		}
-		return field, rule, message
+		violations = append(violations, ValidationError{
+			Field:   field,
+			Rule:    rule,
+			Message: message,
+		})
 	}
-	return "", "", strings.TrimPrefix(err.Error(), "validation error: ")
+	return violations
 }
 
 func ValidateRequest(req any) error {
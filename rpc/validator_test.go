@@ -0,0 +1,176 @@
+package rpc
+
+import (
+	"testing"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	atlasrpc "git.tatikoma.dev/corpix/atlas/rpc/pb"
+)
+
+// newTestMessageDescriptors builds messages
+//
+//	message Item { string name = 1 [(buf.validate.field).string.min_len = 1]; }
+//	message Order {
+//	  repeated Item items = 1;
+//	  string id = 2 [(buf.validate.field).string.min_len = 1];
+//	  string password = 3 [(buf.validate.field).cel = {id: "password.len", expression: "..."}];
+//	}
+func newTestMessageDescriptors(t *testing.T) (order, item protoreflect.MessageDescriptor) {
+	t.Helper()
+
+	minLen := func() *descriptorpb.FieldOptions {
+		opts := &descriptorpb.FieldOptions{}
+		proto.SetExtension(opts, validate.E_Field, &validate.FieldConstraints{
+			Type: &validate.FieldConstraints_String_{String_: &validate.StringRules{MinLen: proto.Uint64(1)}},
+		})
+		return opts
+	}
+	passwordLen := &descriptorpb.FieldOptions{}
+	proto.SetExtension(passwordLen, validate.E_Field, &validate.FieldConstraints{
+		Cel: []*validate.Constraint{{
+			Id:         proto.String("password.len"),
+			Expression: proto.String("this.size() < 8 ? 'password ' + this + ' is too short' : ''"),
+		}},
+		IgnoreEmpty: proto.Bool(true),
+	})
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("atlas_rpc_test.proto"),
+		Package:    proto.String("atlas_rpc_test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"buf/validate/validate.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Item"),
+				Field: []*descriptorpb.FieldDescriptorProto{{
+					Name:     proto.String("name"),
+					JsonName: proto.String("name"),
+					Number:   proto.Int32(1),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Options:  minLen(),
+				}},
+			},
+			{
+				Name: proto.String("Order"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name:     proto.String("items"),
+						JsonName: proto.String("items"),
+						Number:   proto.Int32(1),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+						TypeName: proto.String(".atlas_rpc_test.Item"),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+					},
+					{
+						Name:     proto.String("id"),
+						JsonName: proto.String("id"),
+						Number:   proto.Int32(2),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Options:  minLen(),
+					},
+					{
+						Name:     proto.String("password"),
+						JsonName: proto.String("password"),
+						Number:   proto.Int32(3),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Options:  passwordLen,
+					},
+				},
+			},
+		},
+	}
+	fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	return fd.Messages().ByName("Order"), fd.Messages().ByName("Item")
+}
+
+func newTestOrder(t *testing.T, id string, names ...string) *dynamicpb.Message {
+	t.Helper()
+
+	orderDesc, itemDesc := newTestMessageDescriptors(t)
+	order := dynamicpb.NewMessage(orderDesc)
+	order.Set(orderDesc.Fields().ByName("id"), protoreflect.ValueOfString(id))
+	items := order.Mutable(orderDesc.Fields().ByName("items")).List()
+	for _, name := range names {
+		item := dynamicpb.NewMessage(itemDesc)
+		item.Set(itemDesc.Fields().ByName("name"), protoreflect.ValueOfString(name))
+		items.Append(protoreflect.ValueOfMessage(item))
+	}
+	return order
+}
+
+func TestValidateProtoMessage(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		assert.NoError(t, ValidateProtoMessage(newTestOrder(t, "1", "a", "b")))
+	})
+
+	t.Run("repeated field violations", func(t *testing.T) {
+		err := ValidateProtoMessage(newTestOrder(t, "", "a", "b", ""))
+		require.Error(t, err)
+		st, ok := status.FromError(err)
+		require.True(t, ok)
+		assert.Equal(t, codes.InvalidArgument, st.Code())
+
+		var fields []string
+		for _, detail := range st.Details() {
+			v, ok := detail.(*atlasrpc.ValidationError)
+			require.True(t, ok)
+			fields = append(fields, v.Field)
+			assert.Contains(t, v.Message, v.Field+": ")
+			assert.Equal(t, "string.min_len", v.Rule)
+		}
+		assert.ElementsMatch(t, []string{"items[2].name", "id"}, fields)
+	})
+}
+
+func TestMaskingValidator(t *testing.T) {
+	order := newTestOrder(t, "1", "a")
+	order.Set(order.Descriptor().Fields().ByName("password"), protoreflect.ValueOfString("hunter2"))
+
+	details := func(err error) []*atlasrpc.ValidationError {
+		require.Error(t, err)
+		var res []*atlasrpc.ValidationError
+		for _, detail := range status.Convert(err).Details() {
+			res = append(res, detail.(*atlasrpc.ValidationError))
+		}
+		return res
+	}
+
+	t.Run("unmasked custom constraint echoes value", func(t *testing.T) {
+		violations := details(ValidateRequest(order))
+		require.Len(t, violations, 1)
+		assert.Contains(t, violations[0].Message, "hunter2")
+	})
+
+	t.Run("masked", func(t *testing.T) {
+		v := NewMaskingValidator("atlas_rpc_test.Order.password")
+		err := ValidateRequestWithValidator(v, order)
+		violations := details(err)
+		require.Len(t, violations, 1)
+		assert.Equal(t, "password", violations[0].Field)
+		assert.Empty(t, violations[0].Rule)
+		assert.Equal(t, "password: invalid value", violations[0].Message)
+		assert.NotContains(t, err.Error(), "hunter2")
+		for _, violation := range violations {
+			assert.NotContains(t, violation.String(), "hunter2")
+		}
+
+		err = ValidateRequestWithValidator(v, newTestOrder(t, "", "a"))
+		violations = details(err)
+		require.Len(t, violations, 1)
+		assert.Equal(t, "string.min_len", violations[0].Rule, "other fields are not masked")
+	})
+}
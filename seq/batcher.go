@@ -1,5 +1,9 @@
 package seq
 
+import (
+	"context"
+)
+
 type Batcher[T any] struct {
 	items []T
 	size  int
@@ -25,6 +29,22 @@ func (b *Batcher[T]) Iter() func(yield func([]T) bool) {
 	}
 }
 
+// EachContext calls fn for each batch, it stops on first error returned by fn
+// or when ctx is canceled, which is checked before each batch.
+func (b *Batcher[T]) EachContext(ctx context.Context, fn func(ctx context.Context, batch []T) error) error {
+	for batch := range b.Iter() {
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+		err = fn(ctx, batch)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (b *Batcher[T]) Len() int {
 	if b.size <= 0 {
 		return 0
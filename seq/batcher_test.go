@@ -1,6 +1,8 @@
 package seq
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -78,3 +80,45 @@ func TestBatcher(t *testing.T) {
 		})
 	}
 }
+
+func TestBatcherEachContext(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	t.Run("processes all batches", func(t *testing.T) {
+		var result [][]int
+		err := NewBatcher(items, 2).EachContext(context.Background(), func(_ context.Context, batch []int) error {
+			result = append(result, append([]int(nil), batch...))
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, [][]int{{1, 2}, {3, 4}, {5}}, result)
+	})
+
+	t.Run("stops on cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		calls := 0
+		err := NewBatcher(items, 2).EachContext(ctx, func(context.Context, []int) error {
+			calls++
+			cancel()
+			return nil
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("propagates error", func(t *testing.T) {
+		expected := errors.New("failed")
+		calls := 0
+		err := NewBatcher(items, 2).EachContext(context.Background(), func(context.Context, []int) error {
+			calls++
+			if calls == 2 {
+				return expected
+			}
+			return nil
+		})
+		assert.ErrorIs(t, err, expected)
+		assert.Equal(t, 2, calls)
+	})
+}
@@ -0,0 +1,130 @@
+package seq
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type lruEntry[K comparable, V any] struct {
+	expires time.Time
+	key     K
+	value   V
+}
+
+// LRU is a concurrency-safe cache which evicts least recently used entries
+// once size is exceeded, entries may also expire after TTL.
+type LRU[K comparable, V any] struct {
+	entries map[K]*list.Element
+	order   *list.List // front is most recently used
+	size    int
+	ttl     time.Duration
+	mu      sync.Mutex
+}
+
+// Add puts value into the cache with default TTL, it reports whether
+// some entry was evicted to free space.
+func (c *LRU[K, V]) Add(k K, v V) bool {
+	return c.AddWithTTL(k, v, c.ttl)
+}
+
+// AddWithTTL puts value into the cache which expires after ttl,
+// zero ttl means entry never expires.
+func (c *LRU[K, V]) AddWithTTL(k K, v V, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &lruEntry[K, V]{key: k, value: v}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	if el, ok := c.entries[k]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return false
+	}
+	c.entries[k] = c.order.PushFront(entry)
+
+	if c.size <= 0 || c.order.Len() <= c.size {
+		return false
+	}
+	c.remove(c.order.Back())
+	return true
+}
+
+// Get returns value and marks it as recently used.
+func (c *LRU[K, V]) Get(k K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var empty V
+	el, ok := c.entries[k]
+	if !ok {
+		return empty, false
+	}
+	entry := el.Value.(*lruEntry[K, V])
+	if c.expired(entry, time.Now()) {
+		c.remove(el)
+		return empty, false
+	}
+	c.order.MoveToFront(el)
+
+	return entry.value, true
+}
+
+// Remove deletes value from the cache.
+func (c *LRU[K, V]) Remove(k K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[k]
+	if !ok {
+		return false
+	}
+	c.remove(el)
+	return true
+}
+
+// Len returns number of entries which are not expired.
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		if c.expired(el.Value.(*lruEntry[K, V]), now) {
+			c.remove(el)
+		}
+		el = next
+	}
+	return c.order.Len()
+}
+
+// Purge removes all entries.
+func (c *LRU[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[K]*list.Element{}
+	c.order.Init()
+}
+
+func (c *LRU[K, V]) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*lruEntry[K, V]).key)
+}
+
+func (c *LRU[K, V]) expired(entry *lruEntry[K, V], now time.Time) bool {
+	return !entry.expires.IsZero() && !now.Before(entry.expires)
+}
+
+// NewLRU creates cache holding at most size entries, size <= 0 disables
+// eviction by size. Entries added with Add expire after ttl, zero ttl disables expiration.
+func NewLRU[K comparable, V any](size int, ttl time.Duration) *LRU[K, V] {
+	return &LRU[K, V]{
+		entries: map[K]*list.Element{},
+		order:   list.New(),
+		size:    size,
+		ttl:     ttl,
+	}
+}
@@ -0,0 +1,73 @@
+package seq
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	t.Run("evicts least recently used", func(t *testing.T) {
+		c := NewLRU[string, int](2, 0)
+		assert.False(t, c.Add("a", 1))
+		assert.False(t, c.Add("b", 2))
+
+		v, ok := c.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, 1, v)
+
+		assert.True(t, c.Add("c", 3))
+		_, ok = c.Get("b")
+		assert.False(t, ok)
+		_, ok = c.Get("a")
+		assert.True(t, ok)
+		_, ok = c.Get("c")
+		assert.True(t, ok)
+		assert.Equal(t, 2, c.Len())
+	})
+
+	t.Run("expires entries", func(t *testing.T) {
+		c := NewLRU[string, int](10, 20*time.Millisecond)
+		c.Add("a", 1)
+		c.AddWithTTL("b", 2, 0)
+		assert.Equal(t, 2, c.Len())
+
+		time.Sleep(30 * time.Millisecond)
+		_, ok := c.Get("a")
+		assert.False(t, ok)
+		_, ok = c.Get("b")
+		assert.True(t, ok)
+		assert.Equal(t, 1, c.Len())
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		c := NewLRU[string, int](10, 0)
+		c.Add("a", 1)
+		c.Purge()
+		assert.Equal(t, 0, c.Len())
+		c.Add("b", 2)
+		assert.Equal(t, 1, c.Len())
+	})
+
+	t.Run("concurrent access", func(t *testing.T) {
+		c := NewLRU[string, int](16, time.Minute)
+		var wg sync.WaitGroup
+		for n := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range 100 {
+					k := strconv.Itoa((n * i) % 32)
+					c.Add(k, i)
+					c.Get(k)
+					c.Len()
+				}
+			}()
+		}
+		wg.Wait()
+		assert.LessOrEqual(t, c.Len(), 16)
+	})
+}
@@ -0,0 +1,74 @@
+package seq
+
+import (
+	"iter"
+)
+
+// OrderedMap is a map which preserves insertion order of keys.
+// Updating existing key keeps its position.
+type OrderedMap[K comparable, V any] struct {
+	kv map[K]V
+	kn map[K]int
+	k  []K
+}
+
+func (m *OrderedMap[K, V]) Set(k K, v V) {
+	if _, exists := m.kn[k]; !exists {
+		m.k = append(m.k, k)
+		m.kn[k] = len(m.k) - 1
+	}
+	m.kv[k] = v
+}
+
+func (m *OrderedMap[K, V]) Get(k K) (V, bool) {
+	v, ok := m.kv[k]
+	return v, ok
+}
+
+func (m *OrderedMap[K, V]) Has(k K) bool {
+	_, ok := m.kn[k]
+	return ok
+}
+
+func (m *OrderedMap[K, V]) Delete(k K) bool {
+	n, exists := m.kn[k]
+	if !exists {
+		return false
+	}
+
+	m.k = append(m.k[:n], m.k[n+1:]...)
+	delete(m.kv, k)
+	delete(m.kn, k)
+	for i := n; i < len(m.k); i++ {
+		m.kn[m.k[i]] = i
+	}
+
+	return true
+}
+
+func (m *OrderedMap[K, V]) Len() int {
+	return len(m.k)
+}
+
+func (m *OrderedMap[K, V]) iter(yield func(K, V) bool) {
+	for _, k := range m.k {
+		if !yield(k, m.kv[k]) {
+			break
+		}
+	}
+}
+
+func (m *OrderedMap[K, V]) Iter() iter.Seq2[K, V] {
+	return m.iter
+}
+
+func (m *OrderedMap[K, V]) Keys() []K {
+	return append([]K(nil), m.k...)
+}
+
+func NewOrderedMap[K comparable, V any]() *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{
+		kv: make(map[K]V),
+		kn: make(map[K]int),
+	}
+}
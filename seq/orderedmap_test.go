@@ -0,0 +1,81 @@
+package seq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getAllOrderedMapItems(m *OrderedMap[string, int]) ([]string, []int) {
+	var (
+		keys   []string
+		values []int
+	)
+	for k, v := range m.Iter() {
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+	return keys, values
+}
+
+func TestOrderedMap(t *testing.T) {
+	t.Run("SetGet", func(t *testing.T) {
+		m := NewOrderedMap[string, int]()
+		m.Set("c", 3)
+		m.Set("a", 1)
+		m.Set("b", 2)
+
+		v, ok := m.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, 1, v)
+		_, ok = m.Get("z")
+		assert.False(t, ok)
+
+		keys, values := getAllOrderedMapItems(m)
+		assert.Equal(t, []string{"c", "a", "b"}, keys)
+		assert.Equal(t, []int{3, 1, 2}, values)
+		assert.Equal(t, 3, m.Len())
+	})
+
+	t.Run("Update keeps position", func(t *testing.T) {
+		m := NewOrderedMap[string, int]()
+		m.Set("a", 1)
+		m.Set("b", 2)
+		m.Set("a", 10)
+
+		keys, values := getAllOrderedMapItems(m)
+		assert.Equal(t, []string{"a", "b"}, keys)
+		assert.Equal(t, []int{10, 2}, values)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		m := NewOrderedMap[string, int]()
+		for n, k := range []string{"a", "b", "c", "d"} {
+			m.Set(k, n)
+		}
+
+		assert.True(t, m.Delete("b"))
+		assert.False(t, m.Delete("b"))
+		assert.False(t, m.Has("b"))
+		assert.True(t, m.Delete("d"))
+		m.Set("b", 5)
+
+		keys, values := getAllOrderedMapItems(m)
+		assert.Equal(t, []string{"a", "c", "b"}, keys)
+		assert.Equal(t, []int{0, 2, 5}, values)
+		assert.Equal(t, keys, m.Keys())
+	})
+
+	t.Run("Iter stops", func(t *testing.T) {
+		m := NewOrderedMap[string, int]()
+		m.Set("a", 1)
+		m.Set("b", 2)
+
+		var keys []string
+		for k := range m.Iter() {
+			keys = append(keys, k)
+			break
+		}
+		assert.Equal(t, []string{"a"}, keys)
+	})
+}
@@ -35,13 +35,31 @@ func (st *Set[K, V]) Del(id K) bool {
 	st.v = append(st.v[:n], st.v[n+1:]...)
 	delete(st.kv, id)
 	delete(st.vn, id)
-	for i := n; i < len(st.v[n:]); i++ {
-		st.vn[st.id(st.v[i])] = n + i
+	for i := n; i < len(st.v); i++ {
+		st.vn[st.id(st.v[i])] = i
 	}
 
 	return true
 }
 
+// Pop deletes element identified by id and returns it.
+func (st *Set[K, V]) Pop(id K) (V, bool) {
+	t, exists := st.kv[id]
+	if !exists {
+		return t, false
+	}
+	st.Del(id)
+	return t, true
+}
+
+// Clear removes all elements, keeping allocated capacity.
+func (st *Set[K, V]) Clear() {
+	clear(st.kv)
+	clear(st.vn)
+	clear(st.v)
+	st.v = st.v[:0]
+}
+
 func (st *Set[K, V]) Has(id K) bool {
 	_, ok := st.vn[id]
 	return ok
@@ -67,6 +85,12 @@ func (st *Set[K, V]) Iter() iter.Seq[V] {
 	return st.iter
 }
 
+// Batches iterates over set elements in batches of size, preserving order.
+// Batches share memory with the set, so they should not be retained or modified.
+func (st *Set[K, V]) Batches(size int) iter.Seq[[]V] {
+	return NewBatcher(st.v, size).Iter()
+}
+
 func (st *Set[K, V]) Copy() *Set[K, V] {
 	return NewSet(st.v, st.id)
 }
@@ -89,12 +113,33 @@ func (st *Set[K, V]) Difference(s *Set[K, V]) *Set[K, V] {
 	return r
 }
 
-func (st *Set[K, V]) DifferenceSynchronized(s *Set[K, V]) *Set[K, V] {
+// SymmetricDifference returns elements which are present only in one of the sets.
+func (st *Set[K, V]) SymmetricDifference(s *Set[K, V]) *Set[K, V] {
 	lrDiff := st.Difference(s)
 	rlDiff := s.Difference(st)
 	return lrDiff.Merge(rlDiff)
 }
 
+// DifferenceSynchronized is an alias for SymmetricDifference kept for compatibility.
+func (st *Set[K, V]) DifferenceSynchronized(s *Set[K, V]) *Set[K, V] {
+	return st.SymmetricDifference(s)
+}
+
+// Equal reports whether sets have the same keys and values are equal according to eq.
+// Element order is not taken into account.
+func (st *Set[K, V]) Equal(s *Set[K, V], eq func(V, V) bool) bool {
+	if st.Len() != s.Len() {
+		return false
+	}
+	for id, t := range st.kv {
+		other, exists := s.kv[id]
+		if !exists || !eq(t, other) {
+			return false
+		}
+	}
+	return true
+}
+
 func NewSet[K comparable, V any](ts []V, id func(V) K) *Set[K, V] {
 	st := &Set[K, V]{
 		id: id,
@@ -69,6 +69,39 @@ func TestSet(t *testing.T) {
 		assert.False(t, st.Del(666))
 	})
 
+	t.Run("Pop", func(t *testing.T) {
+		i1, i2, i3 := mkSetItem(1, "a"), mkSetItem(2, "b"), mkSetItem(3, "c")
+		st := NewSet([]setTestItem{i1, i2, i3}, setTestItemID)
+
+		v, ok := st.Pop(i2.ID)
+		assert.True(t, ok)
+		assert.Equal(t, i2, v)
+		assert.False(t, st.Has(i2.ID))
+		assert.Equal(t, []setTestItem{i1, i3}, getAllSetItems(st))
+
+		v, ok = st.Pop(i3.ID)
+		assert.True(t, ok)
+		assert.Equal(t, i3, v)
+		assert.Equal(t, []setTestItem{i1}, getAllSetItems(st))
+
+		_, ok = st.Pop(666)
+		assert.False(t, ok)
+	})
+
+	t.Run("Clear", func(t *testing.T) {
+		i1, i2 := mkSetItem(1, "a"), mkSetItem(2, "b")
+		st := NewSet([]setTestItem{i1, i2}, setTestItemID)
+
+		st.Clear()
+		assert.Equal(t, 0, st.Len())
+		assert.False(t, st.Has(i1.ID))
+		assert.Empty(t, getAllSetItems(st))
+
+		st.Add(i2)
+		assert.Equal(t, 1, st.Len())
+		assert.Equal(t, i2, st.Get(i2.ID))
+	})
+
 	t.Run("Get", func(t *testing.T) {
 		i1 := mkSetItem(10, "z")
 		st := NewSet([]setTestItem{i1}, setTestItemID)
@@ -206,3 +239,51 @@ func TestSet(t *testing.T) {
 		assert.Equal(t, getAllSetItems(st1), getAllSetItems(diffNoOp))
 	})
 }
+
+func TestSetEqual(t *testing.T) {
+	eq := func(a, b setTestItem) bool { return a == b }
+	i1, i2, i3 := mkSetItem(1, "a"), mkSetItem(2, "b"), mkSetItem(3, "c")
+
+	t.Run("equal", func(t *testing.T) {
+		st1 := NewSet([]setTestItem{i1, i2}, setTestItemID)
+		st2 := NewSet([]setTestItem{i2, i1}, setTestItemID)
+		assert.True(t, st1.Equal(st2, eq))
+		assert.True(t, st2.Equal(st1, eq))
+		assert.Empty(t, getAllSetItems(st1.SymmetricDifference(st2)))
+	})
+
+	t.Run("different values same keys", func(t *testing.T) {
+		st1 := NewSet([]setTestItem{i1, i2}, setTestItemID)
+		st2 := NewSet([]setTestItem{i1, mkSetItem(2, "b_updated")}, setTestItemID)
+		assert.False(t, st1.Equal(st2, eq))
+		assert.True(t, st1.Equal(st2, func(a, b setTestItem) bool { return a.ID == b.ID }))
+	})
+
+	t.Run("different keys", func(t *testing.T) {
+		st1 := NewSet([]setTestItem{i1, i2}, setTestItemID)
+		st2 := NewSet([]setTestItem{i1, i3}, setTestItemID)
+		assert.False(t, st1.Equal(st2, eq))
+		assert.False(t, st1.Equal(NewSet([]setTestItem{i1}, setTestItemID), eq))
+		assert.ElementsMatch(t, []setTestItem{i2, i3}, getAllSetItems(st1.SymmetricDifference(st2)))
+	})
+}
+
+func TestSetBatches(t *testing.T) {
+	items := []setTestItem{
+		mkSetItem(1, "a"), mkSetItem(2, "b"), mkSetItem(3, "c"),
+		mkSetItem(4, "d"), mkSetItem(5, "e"),
+	}
+	st := NewSet(items, setTestItemID)
+
+	var batches [][]setTestItem
+	for batch := range st.Batches(2) {
+		batches = append(batches, append([]setTestItem(nil), batch...))
+	}
+	assert.Equal(t, [][]setTestItem{items[0:2], items[2:4], items[4:5]}, batches)
+
+	cnt := 0
+	for range NewSet(nil, setTestItemID).Batches(2) {
+		cnt++
+	}
+	assert.Equal(t, 0, cnt)
+}
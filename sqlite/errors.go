@@ -9,7 +9,8 @@ import (
 )
 
 var (
-	ErrNoRows = sql.ErrNoRows
+	ErrNoRows       = sql.ErrNoRows
+	ErrUnknownQuery = errors.New("unknown query")
 )
 
 func ErrIsNoRows(err error) bool {
@@ -0,0 +1,116 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"git.tatikoma.dev/corpix/atlas/errors"
+)
+
+// Queries is a registry of named SQL queries, statements are prepared
+// on first use and cached until Close.
+type Queries struct {
+	db      *DB
+	queries map[string]string
+	stmts   map[string]*sql.Stmt
+	mu      sync.Mutex
+}
+
+// Register adds named query, name should be unique.
+func (q *Queries) Register(name, query string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.queries[name]; ok {
+		return errors.Errorf("query %q is already registered", name)
+	}
+	q.queries[name] = query
+	return nil
+}
+
+// SQL returns query registered with name.
+func (q *Queries) SQL(name string) (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	query, ok := q.queries[name]
+	return query, ok
+}
+
+// Stmt returns prepared statement for named query.
+func (q *Queries) Stmt(ctx context.Context, name string) (*sql.Stmt, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stmt, ok := q.stmts[name]
+	if ok {
+		return stmt, nil
+	}
+
+	query, ok := q.queries[name]
+	if !ok {
+		return nil, errors.Errorf("%w: %q", ErrUnknownQuery, name)
+	}
+	stmt, err := q.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to prepare query %q", name)
+	}
+	q.stmts[name] = stmt
+
+	return stmt, nil
+}
+
+func (q *Queries) ExecContext(ctx context.Context, name string, args ...any) (sql.Result, error) {
+	stmt, err := q.Stmt(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.ExecContext(ctx, args...)
+}
+
+func (q *Queries) QueryContext(ctx context.Context, name string, args ...any) (*sql.Rows, error) {
+	stmt, err := q.Stmt(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.QueryContext(ctx, args...)
+}
+
+// TxStmt returns transaction-specific prepared statement for named query.
+func (q *Queries) TxStmt(ctx context.Context, tx *Tx, name string) (*sql.Stmt, error) {
+	stmt, err := q.Stmt(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return tx.StmtContext(ctx, stmt), nil
+}
+
+// Close closes prepared statements, queries are prepared again on next use.
+func (q *Queries) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var closeErr error
+	for name, stmt := range q.stmts {
+		err := stmt.Close()
+		if err != nil && closeErr == nil {
+			closeErr = errors.Wrapf(err, "failed to close query %q", name)
+		}
+	}
+	clear(q.stmts)
+
+	return closeErr
+}
+
+func NewQueries(db *DB, queries map[string]string) *Queries {
+	q := &Queries{
+		db:      db,
+		queries: make(map[string]string, len(queries)),
+		stmts:   make(map[string]*sql.Stmt, len(queries)),
+	}
+	for name, query := range queries {
+		q.queries[name] = query
+	}
+	return q
+}
@@ -0,0 +1,63 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueries(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, err := NewClient(":memory:", 5*time.Second)
+	require.NoError(err)
+	defer func() {
+		assert.NoError(db.Close())
+	}()
+
+	_, err = db.ExecContext(ctx, `CREATE TABLE query_items (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL)`)
+	require.NoError(err)
+
+	queries := NewQueries(db, map[string]string{
+		"insert": `INSERT INTO query_items (name) VALUES (?)`,
+	})
+	defer func() {
+		assert.NoError(queries.Close())
+	}()
+	require.NoError(queries.Register("list", `SELECT name FROM query_items ORDER BY id`))
+	assert.Error(queries.Register("list", `SELECT 1`), "duplicate name should be rejected")
+
+	for _, name := range []string{"a", "b"} {
+		_, err = queries.ExecContext(ctx, "insert", name)
+		require.NoError(err)
+	}
+
+	_, err = WithTxContext(ctx, db, func(tx *Tx) (any, error) {
+		stmt, err := queries.TxStmt(ctx, tx, "insert")
+		if err != nil {
+			return nil, err
+		}
+		return stmt.ExecContext(ctx, "c")
+	})
+	require.NoError(err)
+
+	rows, err := queries.QueryContext(ctx, "list")
+	require.NoError(err)
+	var names []string
+	for rows.Next() {
+		var name string
+		require.NoError(rows.Scan(&name))
+		names = append(names, name)
+	}
+	require.NoError(rows.Err())
+	require.NoError(rows.Close())
+	assert.Equal([]string{"a", "b", "c"}, names)
+
+	_, err = queries.ExecContext(ctx, "unknown")
+	assert.ErrorIs(err, ErrUnknownQuery)
+}
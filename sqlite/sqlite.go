@@ -3,6 +3,7 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"time"
 
@@ -53,10 +54,57 @@ func NewClient(dsn string, timeout time.Duration) (*DB, error) {
 }
 
 func WithTxContext[T any](ctx context.Context, db *DB, fn func(tx *Tx) (T, error)) (T, error) {
-	var result T
-	tx, err := db.BeginTx(ctx, nil)
-	if err != nil {
-		return result, errors.Wrap(err, errors.ErrBeginTx)
+	return WithTxOptionsContext(ctx, db, nil, fn)
+}
+
+// WithTxOptionsContext is like WithTxContext, but starts transaction with opts.
+// Driver ignores options, so read-only mode is enforced with query_only pragma
+// on a dedicated connection, isolation levels other than serializable are rejected.
+func WithTxOptionsContext[T any](ctx context.Context, db *DB, opts *sql.TxOptions, fn func(tx *Tx) (T, error)) (T, error) {
+	var (
+		result T
+		tx     *Tx
+		err    error
+	)
+	if opts != nil {
+		switch opts.Isolation {
+		case sql.LevelDefault, sql.LevelSerializable:
+		default:
+			return result, errors.Errorf("unsupported isolation level: %s", opts.Isolation)
+		}
+	}
+
+	if opts != nil && opts.ReadOnly {
+		var conn *sql.Conn
+		conn, err = db.Conn(ctx)
+		if err != nil {
+			return result, errors.Wrap(err, "failed to acquire connection")
+		}
+		defer conn.Close()
+
+		_, err = conn.ExecContext(ctx, "PRAGMA query_only = 1;")
+		if err != nil {
+			return result, errors.Wrap(err, "failed to enable read-only mode")
+		}
+		defer func() {
+			// pragma is connection-wide, it should be reset before connection returns to the pool
+			_, resetErr := conn.ExecContext(context.WithoutCancel(ctx), "PRAGMA query_only = 0;")
+			if resetErr != nil {
+				errors.Log(resetErr, "failed to disable read-only mode, discarding connection")
+				// driver.ErrBadConn makes pool close connection instead of reusing it
+				_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+			}
+		}()
+
+		tx, err = conn.BeginTx(ctx, opts)
+		if err != nil {
+			return result, errors.Wrap(err, errors.ErrBeginTx)
+		}
+	} else {
+		tx, err = db.BeginTx(ctx, opts)
+		if err != nil {
+			return result, errors.Wrap(err, errors.ErrBeginTx)
+		}
 	}
 
 	defer func() {
@@ -2,6 +2,7 @@ package sqlite
 
 import (
 	"context"
+	"database/sql"
 	"testing"
 	"time"
 
@@ -120,3 +121,51 @@ func TestClientTxRollback(t *testing.T) {
 		assert.Zero(count, "Data should not be committed after function panics")
 	})
 }
+
+func TestClientTxOptions(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, err := NewClient(":memory:", 5*time.Second)
+	require.NoError(err)
+	defer func() {
+		assert.NoError(db.Close())
+	}()
+
+	_, err = db.ExecContext(ctx, `CREATE TABLE options_items (id INTEGER PRIMARY KEY AUTOINCREMENT, value TEXT NOT NULL)`)
+	require.NoError(err)
+	_, err = db.ExecContext(ctx, `INSERT INTO options_items (value) VALUES (?)`, "a")
+	require.NoError(err)
+
+	t.Run("read-only tx rejects write", func(t *testing.T) {
+		_, err := WithTxOptionsContext(ctx, db, &sql.TxOptions{ReadOnly: true}, func(tx *Tx) (any, error) {
+			return tx.ExecContext(ctx, `INSERT INTO options_items (value) VALUES (?)`, "b")
+		})
+		assert.Error(err)
+	})
+
+	t.Run("read-only tx allows read", func(t *testing.T) {
+		count, err := WithTxOptionsContext(ctx, db, &sql.TxOptions{ReadOnly: true}, func(tx *Tx) (int, error) {
+			var count int
+			err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM options_items`).Scan(&count)
+			return count, err
+		})
+		require.NoError(err)
+		assert.Equal(1, count)
+	})
+
+	t.Run("connection is writable after read-only tx", func(t *testing.T) {
+		_, err := WithTxContext(ctx, db, func(tx *Tx) (any, error) {
+			return tx.ExecContext(ctx, `INSERT INTO options_items (value) VALUES (?)`, "c")
+		})
+		assert.NoError(err)
+	})
+
+	t.Run("rejects unsupported isolation", func(t *testing.T) {
+		_, err := WithTxOptionsContext(ctx, db, &sql.TxOptions{Isolation: sql.LevelReadCommitted}, func(tx *Tx) (any, error) {
+			return nil, nil
+		})
+		assert.Error(err)
+	})
+}
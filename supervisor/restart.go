@@ -0,0 +1,63 @@
+package supervisor
+
+import (
+	"time"
+
+	"git.tatikoma.dev/corpix/atlas/backoff"
+	"git.tatikoma.dev/corpix/atlas/log"
+)
+
+// RestartPolicy describes how failed task is restarted before its error is propagated to runner.
+type RestartPolicy struct {
+	// MaxRetries limits number of restarts, negative means restart indefinitely.
+	MaxRetries int
+	// Backoff is used to delay restarts, backoff.DefaultConfig if zero.
+	Backoff backoff.Config
+	// Retryable reports whether task should be restarted after err, all errors are retryable if nil.
+	Retryable func(err error) bool
+}
+
+// RunRestart runs job which is restarted on retryable failures without canceling other tasks,
+// error is propagated once retries are exhausted. Job is not restarted once runner is done.
+func (r *Runner) RunRestart(j Job, policy RestartPolicy) {
+	r.Lock()
+	defer r.Unlock()
+
+	if policy.Backoff == (backoff.Config{}) {
+		policy.Backoff = backoff.DefaultConfig
+	}
+	r.run("", j, &policy)
+}
+
+func (t *Task) exec() error {
+	if t.restart == nil {
+		return t.fn(t.ctx)
+	}
+
+	b := backoff.New(t.restart.Backoff)
+	for {
+		err := t.fn(t.ctx)
+		switch {
+		case err == nil, t.ctx.Err() != nil:
+			return err
+		case t.restart.Retryable != nil && !t.restart.Retryable(err):
+			return err
+		case t.restart.MaxRetries >= 0 && b.Attempt() >= t.restart.MaxRetries:
+			return err
+		}
+
+		delay := b.Next()
+		log.Ctx(t.ctx).Warn().
+			Err(err).
+			Str("task", t.String()).
+			Int("attempt", b.Attempt()).
+			Str("delay", delay.String()).
+			Msg("task failed, restarting")
+
+		select {
+		case <-t.ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
@@ -4,25 +4,36 @@ import (
 	"context"
 	"slices"
 	"sync"
+	"time"
 
 	"git.tatikoma.dev/corpix/atlas/errors"
+	"git.tatikoma.dev/corpix/atlas/log"
 )
 
 type Runner struct {
 	Context
-	cancel ContextCancel
-	tasks  Tasks
-	childs []Super
-	wg     sync.WaitGroup
+	cancel       ContextCancel
+	tasks        Tasks
+	childs       map[Super]context.CancelFunc
+	drainTimeout time.Duration
+	wg           sync.WaitGroup
 	sync.Mutex
 }
 
+// WithDrainTimeout limits time Wait spends waiting for tasks to exit
+// after runner is done, zero means wait indefinitely.
+func WithDrainTimeout(d time.Duration) Option {
+	return func(r *Runner) {
+		r.drainTimeout = d
+	}
+}
+
 func (r *Runner) Cancel(cause Cause) {
 	r.Lock()
 	defer r.Unlock()
 	r.cancel(cause)
 
-	for _, child := range r.childs {
+	for child := range r.childs {
 		child.Cancel(cause)
 	}
 }
@@ -30,31 +41,65 @@ func (r *Runner) Cancel(cause Cause) {
 func (r *Runner) Attach(child Super) {
 	r.Lock()
 	defer r.Unlock()
-	n := len(r.childs)
-	r.childs = append(r.childs, child)
 
-	r.run(func(ctx Context) error {
+	ctx, cancel := context.WithCancel(r.Context)
+	if r.childs == nil {
+		r.childs = map[Super]context.CancelFunc{}
+	}
+	r.childs[child] = cancel
+
+	r.run("", func(Context) error {
+		defer cancel()
 		err := child.Wait(ctx)
 
 		r.Lock()
 		defer r.Unlock()
-		r.childs = slices.Delete(r.childs, n, n)
+		if _, attached := r.childs[child]; !attached {
+			return nil // detached
+		}
+		delete(r.childs, child)
 
 		if errors.Is(err, context.Canceled) {
 			return nil
 		}
 		return err
-	})
+	}, nil)
+}
+
+// Detach stops supervising child, parent cancellation is no longer propagated
+// to it and its errors are no longer propagated to parent.
+// It reports whether child was attached.
+func (r *Runner) Detach(child Super) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	cancel, attached := r.childs[child]
+	if !attached {
+		return false
+	}
+	delete(r.childs, child)
+	cancel()
+
+	return true
 }
 
 func (r *Runner) Run(j Job) {
 	r.Lock()
 	defer r.Unlock()
 
-	r.run(j)
+	r.run("", j, nil)
 }
 
-func (r *Runner) run(j Job) {
+// RunNamed runs job labeled with name, label is attached to the logger
+// available from job context and to the error returned by job.
+func (r *Runner) RunNamed(name string, j Job) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.run(name, j, nil)
+}
+
+func (r *Runner) run(name string, j Job, restart *RestartPolicy) {
 	select {
 	case <-r.Done():
 		// skip new tasks if we are done
@@ -62,26 +107,37 @@ func (r *Runner) run(j Job) {
 	default:
 	}
 
+	ctx := r.Context
+	if name != "" {
+		ctx = log.Ctx(ctx).
+			With().
+			Str("task", name).
+			Logger().
+			WithContext(ctx)
+	}
+
 	task := &Task{
-		ctx:  r.Context,
-		fn:   j,
-		done: make(chan void),
+		ctx:     ctx,
+		fn:      j,
+		name:    name,
+		done:    make(chan void),
+		started: time.Now(),
+		restart: restart,
 	}
-	n := len(r.tasks)
 	r.tasks = append(r.tasks, task)
 
 	r.wg.Add(1)
-	go r.runTask(n, task)
+	go r.runTask(task)
 }
 
-func (r *Runner) runTask(n int, task *Task) {
+func (r *Runner) runTask(task *Task) {
 	defer r.wg.Add(-1)
 	defer close(task.done)
 
-	err := task.fn(task.ctx)
+	err := task.exec()
 	r.Lock()
 	defer r.Unlock()
-	r.tasks = slices.Delete(r.tasks, n, n)
+	r.tasks = slices.DeleteFunc(r.tasks, func(t *Task) bool { return t == task })
 
 	if err != nil {
 		r.cancel(&Error{
@@ -91,14 +147,54 @@ func (r *Runner) runTask(n int, task *Task) {
 	}
 }
 
+// Tasks returns snapshot of tasks which are still running, including tasks waiting for attached childs.
+func (r *Runner) Tasks() []TaskInfo {
+	r.Lock()
+	defer r.Unlock()
+
+	infos := make([]TaskInfo, 0, len(r.tasks))
+	for _, task := range r.tasks {
+		infos = append(infos, task.Info())
+	}
+	return infos
+}
+
 func (r *Runner) Wait(ctx Context) error {
 	select {
 	case <-ctx.Done():
 		return context.Cause(ctx)
 	case <-r.Done():
 		err := context.Cause(r)
-		r.wg.Wait() // wait for runner to drain
-		return err
+		return r.drain(err)
+	}
+}
+
+// drain waits for runner tasks to exit, no longer than drain timeout if it is set.
+func (r *Runner) drain(cause error) error {
+	if r.drainTimeout <= 0 {
+		r.wg.Wait()
+		return cause
+	}
+
+	drained := make(chan void)
+	go func() {
+		r.wg.Wait()
+		close(drained)
+	}()
+
+	timer := time.NewTimer(r.drainTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-drained:
+		return cause
+	case <-timer.C:
+		r.Lock()
+		defer r.Unlock()
+		return &DrainError{
+			Err:   cause,
+			Tasks: slices.Clone(r.tasks),
+		}
 	}
 }
 
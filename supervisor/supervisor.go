@@ -18,12 +18,18 @@ type (
 		Attach(child Super)
 		Wait(ctx Context) error
 	}
+
+	Option func(*Runner)
 )
 
-func New(ctx context.Context) *Runner {
+func New(ctx context.Context, opts ...Option) *Runner {
 	innerCtx, cancel := context.WithCancelCause(ctx)
-	return &Runner{
+	r := &Runner{
 		Context: innerCtx,
 		cancel:  cancel,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
@@ -1,15 +1,21 @@
 package supervisor
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/goleak"
+
+	"git.tatikoma.dev/corpix/atlas/backoff"
+	"git.tatikoma.dev/corpix/atlas/log"
 )
 
 type testCanceled struct{}
@@ -108,6 +114,184 @@ func TestRunner(t *testing.T) {
 	})
 }
 
+func TestRunnerRunNamed(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+	sup := New(logger.WithContext(context.Background()))
+	expectedErr := errors.New("task failed")
+
+	sup.RunNamed("worker", func(ctx Context) error {
+		log.Ctx(ctx).Info().Msg("working")
+		return expectedErr
+	})
+
+	err := sup.Wait(context.Background())
+	assert.ErrorIs(t, err, expectedErr)
+	assert.Contains(t, err.Error(), `task "worker"`)
+	assert.Contains(t, buf.String(), `"task":"worker"`)
+	assert.Contains(t, buf.String(), `"message":"working"`)
+}
+
+func TestRunnerDrainTimeout(t *testing.T) {
+	sup := New(context.Background(), WithDrainTimeout(100*time.Millisecond))
+	release := make(chan void)
+	exited := make(chan void)
+
+	sup.RunNamed("stuck", func(ctx Context) error {
+		defer close(exited)
+		<-release // ignores cancellation
+		return nil
+	})
+	sup.Run(func(ctx Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	sup.Cancel(testCanceled{})
+
+	started := time.Now()
+	err := sup.Wait(context.Background())
+	assert.Less(t, time.Since(started), time.Second)
+	assert.ErrorIs(t, err, ErrDrainTimeout)
+	assert.ErrorIs(t, err, testCanceled{})
+
+	var drainErr *DrainError
+	if assert.ErrorAs(t, err, &drainErr) && assert.Len(t, drainErr.Tasks, 1) {
+		assert.Equal(t, "stuck", drainErr.Tasks[0].Name())
+	}
+
+	close(release)
+	<-exited
+}
+
+func TestRunnerTasks(t *testing.T) {
+	sup := New(context.Background())
+	release := make(chan void)
+	started := time.Now()
+
+	sup.RunNamed("blocking", func(ctx Context) error {
+		<-release
+		return nil
+	})
+	sup.Run(func(ctx Context) error {
+		<-release
+		return nil
+	})
+
+	tasks := sup.Tasks()
+	if assert.Len(t, tasks, 2) {
+		assert.Equal(t, "blocking", tasks[0].Name)
+		assert.Empty(t, tasks[1].Name)
+		for _, task := range tasks {
+			assert.True(t, strings.HasSuffix(task.Loc.Package, ".TestRunnerTasks"), task.Loc.Package)
+			assert.True(t, strings.HasSuffix(task.Loc.File, "supervisor_test.go"), task.Loc.File)
+			assert.False(t, task.Started.Before(started))
+		}
+		assert.NotEqual(t, tasks[0].Loc, tasks[1].Loc)
+	}
+
+	close(release)
+	sup.Cancel(testCanceled{})
+	assert.ErrorIs(t, sup.Wait(context.Background()), testCanceled{})
+	assert.Empty(t, sup.Tasks())
+}
+
+func TestRunnerRunRestart(t *testing.T) {
+	var (
+		transientErr = errors.New("transient failure")
+		fatalErr     = errors.New("fatal failure")
+		policy       = RestartPolicy{
+			MaxRetries: 2,
+			Backoff:    backoff.Config{BaseDelay: time.Millisecond, Multiplier: 1, MaxDelay: time.Millisecond},
+			Retryable:  func(err error) bool { return !errors.Is(err, fatalErr) },
+		}
+	)
+
+	t.Run("fails twice then succeeds", func(t *testing.T) {
+		sup := New(context.Background())
+		siblingDone := make(chan void)
+		sup.Run(func(ctx Context) error {
+			<-ctx.Done()
+			close(siblingDone)
+			return nil
+		})
+
+		var runs atomic.Int32
+		succeeded := make(chan void)
+		sup.RunRestart(func(ctx Context) error {
+			if runs.Add(1) <= 2 {
+				return transientErr
+			}
+			close(succeeded)
+			return nil
+		}, policy)
+
+		select {
+		case <-succeeded:
+		case <-time.After(time.Second):
+			t.Fatal("task was not restarted")
+		}
+		assert.Equal(t, int32(3), runs.Load())
+		assert.NoError(t, sup.Err(), "siblings are not canceled")
+		select {
+		case <-siblingDone:
+			t.Fatal("sibling task exited")
+		default:
+		}
+
+		sup.Cancel(testCanceled{})
+		assert.ErrorIs(t, sup.Wait(context.Background()), testCanceled{})
+		<-siblingDone
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		sup := New(context.Background())
+		var runs atomic.Int32
+		sup.RunRestart(func(ctx Context) error {
+			runs.Add(1)
+			return transientErr
+		}, policy)
+
+		err := sup.Wait(context.Background())
+		var supErr *Error
+		if assert.ErrorAs(t, err, &supErr) {
+			assert.ErrorIs(t, supErr.Err, transientErr)
+		}
+		assert.Equal(t, int32(3), runs.Load())
+	})
+
+	t.Run("not retryable", func(t *testing.T) {
+		sup := New(context.Background())
+		var runs atomic.Int32
+		sup.RunRestart(func(ctx Context) error {
+			runs.Add(1)
+			return fatalErr
+		}, policy)
+
+		assert.ErrorIs(t, sup.Wait(context.Background()), fatalErr)
+		assert.Equal(t, int32(1), runs.Load())
+	})
+
+	t.Run("cancellation takes precedence", func(t *testing.T) {
+		sup := New(context.Background())
+		var runs atomic.Int32
+		failed := make(chan void)
+		sup.RunRestart(func(ctx Context) error {
+			if runs.Add(1) == 1 {
+				close(failed)
+			}
+			return transientErr
+		}, RestartPolicy{
+			MaxRetries: -1,
+			Backoff:    backoff.Config{BaseDelay: time.Hour, Multiplier: 1, MaxDelay: time.Hour},
+		})
+
+		<-failed
+		sup.Cancel(testCanceled{})
+		assert.ErrorIs(t, sup.Wait(context.Background()), testCanceled{})
+		assert.Equal(t, int32(1), runs.Load())
+	})
+}
+
 func TestRunnerAttach(t *testing.T) {
 	t.Run("child supervisor error propagation", func(t *testing.T) {
 		ctx := context.Background()
@@ -227,6 +411,59 @@ func TestRunnerAttach(t *testing.T) {
 	})
 }
 
+func TestRunnerDetach(t *testing.T) {
+	ctx := context.Background()
+	parent := New(ctx)
+	child := New(ctx)
+
+	fail := make(chan void)
+	expectedErr := errors.New("child task failed")
+	child.Run(func(ctx Context) error {
+		select {
+		case <-fail:
+			return expectedErr
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	})
+
+	parent.Attach(child)
+	assert.True(t, parent.Detach(child))
+	assert.False(t, parent.Detach(child))
+
+	parent.Cancel(testCanceled{})
+	assert.NoError(t, child.Err(), "parent cancellation should not propagate to detached child")
+
+	close(fail)
+	assert.ErrorIs(t, child.Wait(ctx), expectedErr)
+
+	err := parent.Wait(ctx)
+	assert.ErrorIs(t, err, testCanceled{})
+	assert.NotErrorIs(t, err, expectedErr)
+}
+
+func TestRunnerDetachKeepsParentRunning(t *testing.T) {
+	ctx := context.Background()
+	parent := New(ctx)
+	child := New(ctx)
+
+	fail := make(chan void)
+	child.Run(func(ctx Context) error {
+		<-fail
+		return errors.New("child task failed")
+	})
+	parent.Attach(child)
+	parent.Detach(child)
+
+	close(fail)
+	assert.Error(t, child.Wait(ctx))
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, parent.Err())
+
+	parent.Cancel(nil)
+	assert.ErrorIs(t, parent.Wait(ctx), context.Canceled)
+}
+
 func TestMain(m *testing.M) {
 	goleak.VerifyTestMain(m)
 }
@@ -5,18 +5,29 @@ import (
 	"reflect"
 	"runtime"
 	"strings"
+	"time"
 
 	"git.tatikoma.dev/corpix/atlas/errors"
 )
 
 type (
 	Task struct {
-		ctx  Context
-		fn   Job
-		done chan void
+		ctx     Context
+		fn      Job
+		done    chan void
+		name    string
+		started time.Time
+		restart *RestartPolicy
 	}
 	Tasks []*Task
 
+	// TaskInfo describes running task, Loc is zero if it could not be resolved.
+	TaskInfo struct {
+		Name    string
+		Loc     Loc
+		Started time.Time
+	}
+
 	Job func(ctx Context) error
 	Loc struct {
 		Package  string
@@ -28,8 +39,20 @@ type (
 		Err  error
 		task *Task
 	}
+	// DrainError is returned by Wait when tasks did not exit within drain timeout.
+	DrainError struct {
+		Err   error
+		Tasks Tasks
+	}
 )
 
+var ErrDrainTimeout = errors.New("supervisor drain timed out")
+
+// Name returns task label, it is empty for tasks started with Run.
+func (t *Task) Name() string {
+	return t.name
+}
+
 func (t *Task) Loc() (Loc, error) {
 	v := reflect.ValueOf(t.fn)
 	if v.Kind() != reflect.Func {
@@ -61,6 +84,26 @@ func (t *Task) Loc() (Loc, error) {
 	}, nil
 }
 
+func (t *Task) Info() TaskInfo {
+	loc, _ := t.Loc()
+	return TaskInfo{
+		Name:    t.name,
+		Loc:     loc,
+		Started: t.started,
+	}
+}
+
+func (t *Task) String() string {
+	if t.name != "" {
+		return t.name
+	}
+	loc, err := t.Loc()
+	if err != nil {
+		return err.Error()
+	}
+	return loc.String()
+}
+
 func (l Loc) String() string {
 	return fmt.Sprintf("%s.%s.%s:%d", l.File, l.Package, l.FuncName, l.Line)
 }
@@ -77,5 +120,24 @@ func (e Error) Error() string {
 	} else {
 		locStr = err.Error()
 	}
+	if name := e.task.Name(); name != "" {
+		locStr = fmt.Sprintf("%q (%s)", name, locStr)
+	}
 	return fmt.Sprintf("task %s failed: %s", locStr, e.Err)
 }
+
+func (e DrainError) Is(target error) bool {
+	return target == ErrDrainTimeout || errors.Is(e.Err, target)
+}
+
+func (e DrainError) Unwrap() error {
+	return e.Err
+}
+
+func (e DrainError) Error() string {
+	running := make([]string, 0, len(e.Tasks))
+	for _, task := range e.Tasks {
+		running = append(running, task.String())
+	}
+	return fmt.Sprintf("%s: %s, still running: %s", ErrDrainTimeout, e.Err, strings.Join(running, ", "))
+}
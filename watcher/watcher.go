@@ -1,8 +1,14 @@
 package watcher
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"io"
+	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 	"unsafe"
@@ -21,6 +27,33 @@ func WithWatcherModifyFilter() WatcherFilter {
 	}
 }
 
+// WithContentChangeFilter passes events only when file content differs from
+// the content seen on previous event, first event for each file always passes.
+// Events for files which could not be read (removed, for example) always pass.
+func WithContentChangeFilter() WatcherFilter {
+	var (
+		hashes = map[string][sha256.Size]byte{}
+		mu     sync.Mutex
+	)
+	return func(ev *fsnotify.Event) bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		buf, err := os.ReadFile(ev.Name)
+		if err != nil {
+			delete(hashes, ev.Name)
+			return true
+		}
+
+		sum := sha256.Sum256(buf)
+		if prev, ok := hashes[ev.Name]; ok && prev == sum {
+			return false
+		}
+		hashes[ev.Name] = sum
+		return true
+	}
+}
+
 func WithWatcherCallbackDebounce(dur time.Duration) WatcherCallbackWrapper {
 	return func(next WatcherCallback) WatcherCallback {
 		contexts := map[string]struct {
@@ -51,11 +84,15 @@ type watcherWatch struct {
 	callback WatcherCallback
 	filters  []WatcherFilter
 }
+
+// Watcher watches directories of the watched files, so file replaced with rename
+// (reported as Remove or Rename followed by Create) keeps firing callbacks,
+// watches are keyed by absolute file name.
 type Watcher struct {
-	notify  *fsnotify.Watcher
-	names   map[string][]watcherWatch
-	watches map[string][]watcherWatch
-	mu      sync.Mutex
+	notify *fsnotify.Watcher
+	names  map[string][]watcherWatch
+	dirs   map[string]int
+	mu     sync.Mutex
 }
 
 func (w *Watcher) Watch(name string, cb WatcherCallback, filters ...WatcherFilter) error {
@@ -68,18 +105,20 @@ func (w *Watcher) Watch(name string, cb WatcherCallback, filters ...WatcherFilte
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	if _, ok := w.watches[absDir]; !ok {
-		err := w.notify.Add(absDir)
-		if err != nil {
-			return err
+	if _, ok := w.names[absName]; !ok {
+		if w.dirs[absDir] == 0 {
+			err := w.notify.Add(absDir)
+			if err != nil {
+				return err
+			}
 		}
+		w.dirs[absDir]++
 	}
 
-	w.watches[absDir] = append(w.watches[absDir], watcherWatch{
+	w.names[absName] = append(w.names[absName], watcherWatch{
 		callback: cb,
 		filters:  filters,
 	})
-	w.names[absName] = w.watches[absDir]
 	return nil
 }
 
@@ -94,36 +133,54 @@ func (w *Watcher) Unwatch(name string, cb WatcherCallback) error {
 
 	w.mu.Lock()
 	defer w.mu.Unlock()
-	bucket, ok := w.watches[absDir]
-	if ok {
-		for n, w := range bucket {
-			if *(*unsafe.Pointer)(unsafe.Pointer(&w.callback)) == cbptr {
-				bucket = append(bucket[:n], bucket[n+1:]...)
-				break
-			}
-		}
-		if len(bucket) == 0 {
-			err := w.notify.Remove(absDir)
-			if err != nil {
-				return err
-			}
-			delete(w.watches, absDir)
-			names := []string{}
-			bucketptr := *(*unsafe.Pointer)(unsafe.Pointer(&bucket))
-			for name, nameBucket := range w.names {
-				if *(*unsafe.Pointer)(unsafe.Pointer(&nameBucket)) == bucketptr {
-					names = append(names, name)
-				}
-			}
-			for _, name := range names {
-				delete(w.names, name)
-			}
-		} else {
-			delete(w.names, absName)
+	bucket, ok := w.names[absName]
+	if !ok {
+		return nil
+	}
+	for n, w := range bucket {
+		if *(*unsafe.Pointer)(unsafe.Pointer(&w.callback)) == cbptr {
+			bucket = append(bucket[:n], bucket[n+1:]...)
+			break
 		}
 	}
+	if len(bucket) > 0 {
+		w.names[absName] = bucket
+		return nil
+	}
 
-	return nil
+	delete(w.names, absName)
+	w.dirs[absDir]--
+	if w.dirs[absDir] > 0 {
+		return nil
+	}
+	delete(w.dirs, absDir)
+	return w.notify.Remove(absDir)
+}
+
+// WatcherEntry describes watched file.
+type WatcherEntry struct {
+	Name      string
+	Dir       string
+	Callbacks int
+}
+
+// List returns currently watched files sorted by name.
+func (w *Watcher) List() []WatcherEntry {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	entries := make([]WatcherEntry, 0, len(w.names))
+	for name, bucket := range w.names {
+		entries = append(entries, WatcherEntry{
+			Name:      name,
+			Dir:       filepath.Dir(name),
+			Callbacks: len(bucket),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
 }
 
 func (w *Watcher) emit(ev *fsnotify.Event) {
@@ -160,6 +217,70 @@ func (w *Watcher) Run(ctx context.Context) {
 	}
 }
 
+// Close releases underlying fsnotify watcher, Run returns once it is closed.
+func (w *Watcher) Close() error {
+	return w.notify.Close()
+}
+
+type watcherTail struct {
+	callback func(lines []string)
+	info     os.FileInfo
+	offset   int64
+	partial  []byte
+}
+
+func (t *watcherTail) read(ev *fsnotify.Event) {
+	f, err := os.Open(ev.Name)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return
+	}
+	if t.info == nil || !os.SameFile(t.info, info) || info.Size() < t.offset {
+		t.offset = 0
+		t.partial = nil
+	}
+	t.info = info
+
+	_, err = f.Seek(t.offset, io.SeekStart)
+	if err != nil {
+		return
+	}
+	buf, err := io.ReadAll(f)
+	if err != nil {
+		return
+	}
+	t.offset += int64(len(buf))
+
+	buf = append(t.partial, buf...)
+	n := bytes.LastIndexByte(buf, '\n')
+	if n < 0 {
+		t.partial = buf
+		return
+	}
+	t.partial = bytes.Clone(buf[n+1:])
+	t.callback(strings.Split(string(buf[:n]), "\n"))
+}
+
+// Tail watches content appended to file, cb receives complete lines (without trailing newline)
+// appended since previous call. Reading starts from the current end of file, offset is reset
+// when file shrinks (truncation) or is replaced by another file (rotation).
+// Returned callback could be passed to Unwatch to stop tailing.
+func (w *Watcher) Tail(name string, cb func(lines []string)) (WatcherCallback, error) {
+	t := &watcherTail{callback: cb}
+	if info, err := os.Stat(name); err == nil {
+		t.info = info
+		t.offset = info.Size()
+	}
+
+	callback := t.read
+	return callback, w.Watch(name, callback, WithWatcherModifyFilter())
+}
+
 func New() (*Watcher, error) {
 	w, err := fsnotify.NewWatcher()
 	if err != nil {
@@ -167,9 +288,9 @@ func New() (*Watcher, error) {
 	}
 
 	return &Watcher{
-		notify:  w,
-		watches: map[string][]watcherWatch{},
-		names:   map[string][]watcherWatch{},
+		notify: w,
+		names:  map[string][]watcherWatch{},
+		dirs:   map[string]int{},
 	}, nil
 }
 
@@ -179,13 +300,35 @@ type MultiWatcher struct {
 	watcher         *Watcher
 	names           map[string]bool
 	callback        func()
+	changedCallback MultiWatcherChangedCallback
 	watcherCallback WatcherCallback
 	filters         []WatcherFilter
+	mode            MultiWatcherMode
+	coalesce        time.Duration
+	timer           *time.Timer
 	mu              sync.Mutex
 }
 
 type MultiWatcherOption any
 
+// MultiWatcherChangedCallback receives absolute names of changed files,
+// when passed as option it is called instead of the callback given to NewMulti.
+type MultiWatcherChangedCallback func(changed []string)
+
+// MultiWatcherMode defines when MultiWatcher fires its callback.
+type MultiWatcherMode int
+
+// MultiWatcherCoalesce batches changes of watched files made within the window
+// started by first change and fires callback once for the batch, regardless of the mode.
+type MultiWatcherCoalesce time.Duration
+
+const (
+	// MultiWatcherModeAll fires callback once all watched files have changed.
+	MultiWatcherModeAll MultiWatcherMode = iota
+	// MultiWatcherModeAny fires callback on change of any watched file.
+	MultiWatcherModeAny
+)
+
 func NewMulti(w *Watcher, names []string, callback func(), opts ...MultiWatcherOption) (*MultiWatcher, error) {
 	mw := &MultiWatcher{
 		watcher:  w,
@@ -200,6 +343,12 @@ func NewMulti(w *Watcher, names []string, callback func(), opts ...MultiWatcherO
 			mw.filters = append(mw.filters, v)
 		case WatcherCallbackWrapper:
 			mw.watcherCallback = v(mw.watcherCallback)
+		case MultiWatcherMode:
+			mw.mode = v
+		case MultiWatcherChangedCallback:
+			mw.changedCallback = v
+		case MultiWatcherCoalesce:
+			mw.coalesce = time.Duration(v)
 		default:
 			return nil, errors.Errorf("unsupported option type %T", opt)
 		}
@@ -220,11 +369,49 @@ func (m *MultiWatcher) eventCallback(event *fsnotify.Event) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if _, ok := m.names[event.Name]; !ok {
+		return
+	}
 	m.names[event.Name] = true
-	if m.all() {
+	if m.coalesce > 0 {
+		if m.timer == nil {
+			m.timer = time.AfterFunc(m.coalesce, m.flush)
+		}
+		return
+	}
+	if m.mode == MultiWatcherModeAny || m.all() {
+		m.fire()
+	}
+}
+
+func (m *MultiWatcher) flush() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.timer = nil
+	if len(m.changed()) > 0 {
+		m.fire()
+	}
+}
+
+func (m *MultiWatcher) fire() {
+	if m.changedCallback != nil {
+		m.changedCallback(m.changed())
+	} else {
 		m.callback()
-		m.reset()
 	}
+	m.reset()
+}
+
+func (m *MultiWatcher) changed() []string {
+	changed := make([]string, 0, len(m.names))
+	for file, modified := range m.names {
+		if modified {
+			changed = append(changed, file)
+		}
+	}
+	sort.Strings(changed)
+	return changed
 }
 
 func (m *MultiWatcher) all() bool {
@@ -253,6 +440,13 @@ func (m *MultiWatcher) Watch() error {
 }
 
 func (m *MultiWatcher) Unwatch() error {
+	m.mu.Lock()
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+	m.mu.Unlock()
+
 	for file := range m.names {
 		err := m.watcher.Unwatch(file, m.watcherCallback)
 		if err != nil {
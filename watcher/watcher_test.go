@@ -0,0 +1,260 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestWatcher(t *testing.T) *Watcher {
+	t.Helper()
+
+	w, err := New()
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, w.Close())
+	})
+	go w.Run(ctx)
+
+	return w
+}
+
+// writeTestFile replaces file atomically, so watcher never observes partial content.
+func writeTestFile(t *testing.T, name, content string) {
+	t.Helper()
+
+	tmp := filepath.Join(filepath.Dir(name), "."+filepath.Base(name)+".tmp")
+	require.NoError(t, os.WriteFile(tmp, []byte(content), 0o600))
+	require.NoError(t, os.Rename(tmp, name))
+}
+
+func TestContentChangeFilter(t *testing.T) {
+	w := newTestWatcher(t)
+	name := filepath.Join(t.TempDir(), "config.yaml")
+
+	var calls atomic.Int32
+	require.NoError(t, w.Watch(name, func(*fsnotify.Event) {
+		calls.Add(1)
+	}, WithWatcherModifyFilter(), WithContentChangeFilter()))
+
+	writeTestFile(t, name, "a: 1")
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, 10*time.Millisecond)
+
+	writeTestFile(t, name, "a: 1")
+	time.Sleep(100 * time.Millisecond)
+	require.EqualValues(t, 1, calls.Load())
+
+	writeTestFile(t, name, "a: 2")
+	require.Eventually(t, func() bool { return calls.Load() == 2 }, time.Second, 10*time.Millisecond)
+}
+
+func TestMultiWatcherMode(t *testing.T) {
+	test := func(t *testing.T, mode MultiWatcherMode) int32 {
+		w := newTestWatcher(t)
+		dir := t.TempDir()
+		names := []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")}
+
+		var calls atomic.Int32
+		mw, err := NewMulti(w, names, func() {
+			calls.Add(1)
+		}, WithWatcherModifyFilter(), mode)
+		require.NoError(t, err)
+		require.NoError(t, mw.Watch())
+
+		writeTestFile(t, names[0], "a: 1")
+		time.Sleep(100 * time.Millisecond)
+		return calls.Load()
+	}
+
+	t.Run("all waits for every file", func(t *testing.T) {
+		require.EqualValues(t, 0, test(t, MultiWatcherModeAll))
+	})
+
+	t.Run("any fires on single file", func(t *testing.T) {
+		require.EqualValues(t, 1, test(t, MultiWatcherModeAny))
+	})
+}
+
+func TestMultiWatcherChangedCallback(t *testing.T) {
+	w := newTestWatcher(t)
+	dir := t.TempDir()
+	names := []string{
+		filepath.Join(dir, "a.yaml"),
+		filepath.Join(dir, "b.yaml"),
+		filepath.Join(dir, "c.yaml"),
+	}
+
+	changed := make(chan []string, 1)
+	mw, err := NewMulti(w, names, nil,
+		WithWatcherModifyFilter(),
+		MultiWatcherChangedCallback(func(names []string) { changed <- names }),
+	)
+	require.NoError(t, err)
+	require.NoError(t, mw.Watch())
+
+	for _, name := range names {
+		writeTestFile(t, name, "a: 1")
+	}
+
+	select {
+	case got := <-changed:
+		require.Equal(t, names, got)
+	case <-time.After(time.Second):
+		t.Fatal("callback was not called")
+	}
+}
+
+func TestTail(t *testing.T) {
+	w := newTestWatcher(t)
+	name := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(name, []byte("old\n"), 0o600))
+
+	received := make(chan []string, 16)
+	_, err := w.Tail(name, func(lines []string) { received <- lines })
+	require.NoError(t, err)
+
+	appendLines := func(t *testing.T, name, content string) {
+		f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		require.NoError(t, err)
+		_, err = f.WriteString(content)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+	await := func(t *testing.T, expected ...string) {
+		t.Helper()
+		var got []string
+		for len(got) < len(expected) {
+			select {
+			case lines := <-received:
+				got = append(got, lines...)
+			case <-time.After(time.Second):
+				t.Fatalf("lines were not delivered, got %q, expected %q", got, expected)
+			}
+		}
+		require.Equal(t, expected, got)
+	}
+
+	appendLines(t, name, "one\ntwo\n")
+	await(t, "one", "two")
+
+	appendLines(t, name, "thr")
+	appendLines(t, name, "ee\n")
+	await(t, "three")
+
+	t.Run("truncation", func(t *testing.T) {
+		require.NoError(t, os.Truncate(name, 0))
+		appendLines(t, name, "four\n")
+		await(t, "four")
+	})
+
+	t.Run("rotation", func(t *testing.T) {
+		require.NoError(t, os.Rename(name, name+".1"))
+		appendLines(t, name, "five\n")
+		await(t, "five")
+		appendLines(t, name, "six\n")
+		await(t, "six")
+	})
+}
+
+func TestMultiWatcherCoalesce(t *testing.T) {
+	w := newTestWatcher(t)
+	dir := t.TempDir()
+	names := []string{
+		filepath.Join(dir, "a.yaml"),
+		filepath.Join(dir, "b.yaml"),
+		filepath.Join(dir, "c.yaml"),
+	}
+
+	changed := make(chan []string, 4)
+	mw, err := NewMulti(w, names, nil,
+		WithWatcherModifyFilter(),
+		MultiWatcherModeAny,
+		MultiWatcherCoalesce(200*time.Millisecond),
+		MultiWatcherChangedCallback(func(names []string) { changed <- names }),
+	)
+	require.NoError(t, err)
+	require.NoError(t, mw.Watch())
+
+	for _, name := range names[:2] {
+		writeTestFile(t, name, "a: 1")
+	}
+
+	select {
+	case got := <-changed:
+		require.Equal(t, names[:2], got)
+	case <-time.After(time.Second):
+		t.Fatal("callback was not called")
+	}
+	select {
+	case got := <-changed:
+		t.Fatalf("unexpected callback with %q", got)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	writeTestFile(t, names[2], "a: 1")
+	select {
+	case got := <-changed:
+		require.Equal(t, names[2:], got)
+	case <-time.After(time.Second):
+		t.Fatal("callback was not called")
+	}
+	require.NoError(t, mw.Unwatch())
+}
+
+func TestWatchAtomicRename(t *testing.T) {
+	w := newTestWatcher(t)
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")
+
+	var callsA, callsB atomic.Int32
+	require.NoError(t, w.Watch(a, func(*fsnotify.Event) { callsA.Add(1) }, WithWatcherModifyFilter()))
+	require.NoError(t, w.Watch(b, func(*fsnotify.Event) { callsB.Add(1) }, WithWatcherModifyFilter()))
+
+	for n := int32(1); n <= 3; n++ {
+		writeTestFile(t, a, "a: 1")
+		require.Eventually(t, func() bool { return callsA.Load() == n }, time.Second, 10*time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	require.EqualValues(t, 3, callsA.Load())
+	require.EqualValues(t, 0, callsB.Load(), "callbacks fire only for their files")
+
+	require.NoError(t, os.Remove(a))
+	require.NoError(t, os.WriteFile(a, []byte("a: 2"), 0o600))
+	require.Eventually(t, func() bool { return callsA.Load() >= 4 }, time.Second, 10*time.Millisecond)
+}
+
+func TestWatcherList(t *testing.T) {
+	w := newTestWatcher(t)
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")
+	cb := func(*fsnotify.Event) {}
+	cb2 := func(*fsnotify.Event) {}
+
+	require.Empty(t, w.List())
+
+	require.NoError(t, w.Watch(b, cb))
+	require.NoError(t, w.Watch(a, cb))
+	require.NoError(t, w.Watch(a, cb2))
+	require.Equal(t, []WatcherEntry{
+		{Name: a, Dir: dir, Callbacks: 2},
+		{Name: b, Dir: dir, Callbacks: 1},
+	}, w.List())
+
+	require.NoError(t, w.Unwatch(a, cb))
+	require.Equal(t, []WatcherEntry{
+		{Name: a, Dir: dir, Callbacks: 1},
+		{Name: b, Dir: dir, Callbacks: 1},
+	}, w.List())
+
+	require.NoError(t, w.Unwatch(a, cb2))
+	require.NoError(t, w.Unwatch(b, cb))
+	require.Empty(t, w.List())
+}